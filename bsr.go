@@ -0,0 +1,319 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// BSR is a sparse matrix in the Block Compressed Sparse Row format. The matrix
+// is partitioned into square blocks of equal size and only non-zero blocks are
+// stored, each as a dense row-major array. A single column index is stored
+// per block instead of per entry.
+//
+// Products with vectors whose increment is not one use buffers stored in the
+// matrix, so they must not be computed concurrently with the same matrix.
+type BSR struct {
+	rows, cols int
+	block      int
+
+	values   []float64 // Dense blocks stored consecutively, block*block values each.
+	columns  []int     // Block column index of each block.
+	rowIndex []int     // Start of each block row in columns.
+
+	props MatrixProperties
+}
+
+// NewBSR returns a new BSR matrix with blocks of size block×block that holds
+// the entries of dok. Both dimensions of dok must be multiples of block,
+// otherwise NewBSR will panic.
+func NewBSR(dok *DOK, block int) *BSR {
+	r, c := dok.Dims()
+	b := NewBSRBuilder(r, c, block)
+	for ij, v := range dok.data {
+		b.InsertEntry(ij[0], ij[1], v)
+	}
+	m := b.BSR()
+	m.props = dok.props
+	return m
+}
+
+// NewBSRFromCSR returns a new BSR matrix with blocks of size block×block that
+// holds the entries of a. Both dimensions of a must be multiples of block,
+// otherwise NewBSRFromCSR will panic.
+func NewBSRFromCSR(a *CSR, block int) *BSR {
+	r, c := a.Dims()
+	b := NewBSRBuilder(r, c, block)
+	a.DoNonZero(b.InsertEntry)
+	m := b.BSR()
+	m.props = a.props
+	return m
+}
+
+func (m *BSR) Dims() (r, c int) {
+	return m.rows, m.cols
+}
+
+func (m *BSR) MulVecTo(dst []float64, trans bool, x []float64) {
+	mulVecTo(dst, trans, m, x)
+}
+
+// BlockSize returns the size of the blocks.
+func (m *BSR) BlockSize() int {
+	return m.block
+}
+
+// NNZBlocks returns the number of stored blocks.
+func (m *BSR) NNZBlocks() int {
+	return len(m.columns)
+}
+
+func (m *BSR) At(r, c int) float64 {
+	if r >= m.rows || r < 0 {
+		panic("sparse: row index out of range")
+	}
+	if c >= m.cols || c < 0 {
+		panic("sparse: column index out of range")
+	}
+
+	bi, bj := r/m.block, c/m.block
+	for k := m.rowIndex[bi]; k < m.rowIndex[bi+1]; k++ {
+		if m.columns[k] == bj {
+			return m.values[k*m.block*m.block+(r%m.block)*m.block+c%m.block]
+		}
+	}
+	return 0
+}
+
+func (m *BSR) Properties() MatrixProperties {
+	return m.props
+}
+
+// SetProperties sets the properties of the matrix. The properties are not
+// verified, DetectProperties can be used to determine them from the entries.
+func (m *BSR) SetProperties(props MatrixProperties) {
+	m.props = props
+}
+
+// DoNonZero calls fn for each entry of the stored blocks, including the zero
+// entries within the blocks.
+func (m *BSR) DoNonZero(fn func(r, c int, v float64)) {
+	bs := m.block
+	for bi := 0; bi < m.rows/bs; bi++ {
+		for k := m.rowIndex[bi]; k < m.rowIndex[bi+1]; k++ {
+			blk := m.values[k*bs*bs : (k+1)*bs*bs]
+			for i := 0; i < bs; i++ {
+				for j := 0; j < bs; j++ {
+					fn(bi*bs+i, m.columns[k]*bs+j, blk[i*bs+j])
+				}
+			}
+		}
+	}
+}
+
+func bsrMulMatVec(y *mat64.Vector, alpha float64, transA bool, a *BSR, x *mat64.Vector) {
+	r, c := a.Dims()
+	if transA {
+		if r != x.Len() || c != y.Len() {
+			panic("sparse: dimension mismatch")
+		}
+	} else {
+		if r != y.Len() || c != x.Len() {
+			panic("sparse: dimension mismatch")
+		}
+	}
+
+	if alpha == 0 {
+		return
+	}
+
+	xRaw := x.RawVector()
+	yRaw := y.RawVector()
+	// The block kernels need unit stride. Vectors with a different
+	// increment are copied to buffers taken from workPool.
+	xb := xRaw.Data[:x.Len()]
+	if xRaw.Inc != 1 {
+		p := getWork(x.Len())
+		defer putWork(p)
+		xb = *p
+		for i := range xb {
+			xb[i] = xRaw.Data[i*xRaw.Inc]
+		}
+	}
+	yb := yRaw.Data[:y.Len()]
+	if yRaw.Inc != 1 {
+		p := getWork(y.Len())
+		defer putWork(p)
+		yb = *p
+		for i := range yb {
+			yb[i] = yRaw.Data[i*yRaw.Inc]
+		}
+	}
+
+	bs := a.block
+	for bi := 0; bi < r/bs; bi++ {
+		for k := a.rowIndex[bi]; k < a.rowIndex[bi+1]; k++ {
+			blk := a.values[k*bs*bs : (k+1)*bs*bs]
+			bj := a.columns[k]
+			if transA {
+				blockMulTransVec(yb[bj*bs:(bj+1)*bs], alpha, blk, xb[bi*bs:(bi+1)*bs])
+			} else {
+				blockMulVec(yb[bi*bs:(bi+1)*bs], alpha, blk, xb[bj*bs:(bj+1)*bs])
+			}
+		}
+	}
+
+	if yRaw.Inc != 1 {
+		for i, v := range yb {
+			yRaw.Data[i*yRaw.Inc] = v
+		}
+	}
+}
+
+// blockMulVec computes y += alpha * B * x for a dense row-major square
+// block B.
+func blockMulVec(y []float64, alpha float64, b, x []float64) {
+	if len(x) == 3 {
+		y[0] += alpha * (b[0]*x[0] + b[1]*x[1] + b[2]*x[2])
+		y[1] += alpha * (b[3]*x[0] + b[4]*x[1] + b[5]*x[2])
+		y[2] += alpha * (b[6]*x[0] + b[7]*x[1] + b[8]*x[2])
+		return
+	}
+	n := len(x)
+	for i := range y {
+		var sum float64
+		for j, bij := range b[i*n : (i+1)*n] {
+			sum += bij * x[j]
+		}
+		y[i] += alpha * sum
+	}
+}
+
+// blockMulTransVec computes y += alpha * Bᵀ * x for a dense row-major square
+// block B.
+func blockMulTransVec(y []float64, alpha float64, b, x []float64) {
+	if len(x) == 3 {
+		x0, x1, x2 := alpha*x[0], alpha*x[1], alpha*x[2]
+		y[0] += b[0]*x0 + b[3]*x1 + b[6]*x2
+		y[1] += b[1]*x0 + b[4]*x1 + b[7]*x2
+		y[2] += b[2]*x0 + b[5]*x1 + b[8]*x2
+		return
+	}
+	n := len(x)
+	for i, xi := range x {
+		axi := alpha * xi
+		for j, bij := range b[i*n : (i+1)*n] {
+			y[j] += bij * axi
+		}
+	}
+}
+
+// BSRBuilder assembles a BSR matrix entry by entry or block by block.
+type BSRBuilder struct {
+	rows, cols int
+	block      int
+	blocks     map[Index][]float64
+}
+
+// NewBSRBuilder returns a new builder of an r×c BSR matrix with blocks of size
+// block×block. Both r and c must be multiples of block, otherwise
+// NewBSRBuilder will panic.
+func NewBSRBuilder(r, c, block int) *BSRBuilder {
+	if block <= 0 {
+		panic("sparse: non-positive block size")
+	}
+	if r%block != 0 || c%block != 0 {
+		panic("sparse: dimensions not multiple of the block size")
+	}
+	return &BSRBuilder{
+		rows:   r,
+		cols:   c,
+		block:  block,
+		blocks: make(map[Index][]float64),
+	}
+}
+
+func (b *BSRBuilder) blockAt(bi, bj int) []float64 {
+	blk, ok := b.blocks[Index{bi, bj}]
+	if !ok {
+		blk = make([]float64, b.block*b.block)
+		b.blocks[Index{bi, bj}] = blk
+	}
+	return blk
+}
+
+// InsertEntry sets the entry at (r, c) to v, creating the block that contains
+// it if it does not exist.
+func (b *BSRBuilder) InsertEntry(r, c int, v float64) {
+	if r >= b.rows || r < 0 {
+		panic("sparse: row index out of range")
+	}
+	if c >= b.cols || c < 0 {
+		panic("sparse: column index out of range")
+	}
+
+	b.blockAt(r/b.block, c/b.block)[(r%b.block)*b.block+c%b.block] = v
+}
+
+// InsertBlock sets the block at block row bi and block column bj to the
+// values in blk stored in row-major order.
+func (b *BSRBuilder) InsertBlock(bi, bj int, blk []float64) {
+	b.checkBlock(bi, bj, blk)
+	copy(b.blockAt(bi, bj), blk)
+}
+
+// AddBlock adds the values in blk stored in row-major order to the block at
+// block row bi and block column bj. It is useful for assembly of finite
+// element matrices.
+func (b *BSRBuilder) AddBlock(bi, bj int, blk []float64) {
+	b.checkBlock(bi, bj, blk)
+	dst := b.blockAt(bi, bj)
+	for k, v := range blk {
+		dst[k] += v
+	}
+}
+
+func (b *BSRBuilder) checkBlock(bi, bj int, blk []float64) {
+	if bi >= b.rows/b.block || bi < 0 {
+		panic("sparse: block row index out of range")
+	}
+	if bj >= b.cols/b.block || bj < 0 {
+		panic("sparse: block column index out of range")
+	}
+	if len(blk) != b.block*b.block {
+		panic("sparse: bad block length")
+	}
+}
+
+// BSR returns the assembled matrix. The builder can be used further, the
+// returned matrix does not share data with it.
+func (b *BSRBuilder) BSR() *BSR {
+	keys := make([]Index, 0, len(b.blocks))
+	for ij := range b.blocks {
+		keys = append(keys, ij)
+	}
+	sort.Sort(indexRowWise(keys))
+
+	bs := b.block
+	m := &BSR{
+		rows:     b.rows,
+		cols:     b.cols,
+		block:    bs,
+		values:   make([]float64, len(keys)*bs*bs),
+		columns:  make([]int, len(keys)),
+		rowIndex: make([]int, b.rows/bs+1),
+	}
+	for k, ij := range keys {
+		m.rowIndex[ij[0]+1]++
+		m.columns[k] = ij[1]
+		copy(m.values[k*bs*bs:], b.blocks[ij])
+	}
+	for i := 1; i < len(m.rowIndex); i++ {
+		m.rowIndex[i] += m.rowIndex[i-1]
+	}
+	return m
+}
@@ -0,0 +1,174 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestBSR(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, test := range []struct {
+		r, c, block, nnz int
+	}{
+		{4, 4, 1, 6},
+		{4, 6, 2, 8},
+		{9, 6, 3, 12},
+		{30, 30, 3, 100},
+		{12, 8, 4, 20},
+	} {
+		dok := NewDOK(test.r, test.c)
+		for k := 0; k < test.nnz; k++ {
+			dok.InsertEntry(rnd.Intn(test.r), rnd.Intn(test.c), rnd.NormFloat64())
+		}
+		csr := NewCSR(dok)
+
+		for _, bsr := range []*BSR{NewBSR(dok, test.block), NewBSRFromCSR(csr, test.block)} {
+			if bsr.BlockSize() != test.block {
+				t.Errorf("unexpected block size")
+			}
+			for i := 0; i < test.r; i++ {
+				for j := 0; j < test.c; j++ {
+					if bsr.At(i, j) != dok.At(i, j) {
+						t.Errorf("entries not equal at (%d,%d): want %v, got %v", i, j, dok.At(i, j), bsr.At(i, j))
+					}
+				}
+			}
+
+			for _, trans := range []bool{false, true} {
+				r, c := test.r, test.c
+				if trans {
+					r, c = c, r
+				}
+				x := mat64.NewVector(c, nil)
+				for i := 0; i < c; i++ {
+					x.SetVec(i, rnd.NormFloat64())
+				}
+				want := mat64.NewVector(r, nil)
+				got := mat64.NewVector(r, nil)
+				for i := 0; i < r; i++ {
+					want.SetVec(i, float64(i))
+					got.SetVec(i, float64(i))
+				}
+				MulMatVec(want, -3, trans, csr, x)
+				MulMatVec(got, -3, trans, bsr, x)
+				for i := 0; i < r; i++ {
+					if math.Abs(got.At(i, 0)-want.At(i, 0)) > 1e-13 {
+						t.Errorf("%d×%d, block %d, trans=%t: mismatch at %d: want %v, got %v",
+							test.r, test.c, test.block, trans, i, want.At(i, 0), got.At(i, 0))
+					}
+				}
+
+				// Vectors with non-unit increment.
+				xs := mat64.NewDense(c, 2, nil).ColView(1)
+				xs.CopyVec(x)
+				gots := mat64.NewDense(r, 3, nil).ColView(2)
+				for i := 0; i < r; i++ {
+					gots.SetVec(i, float64(i))
+				}
+				MulMatVec(gots, -3, trans, bsr, xs)
+				for i := 0; i < r; i++ {
+					if math.Abs(gots.At(i, 0)-want.At(i, 0)) > 1e-13 {
+						t.Errorf("%d×%d, block %d, trans=%t, strided: mismatch at %d: want %v, got %v",
+							test.r, test.c, test.block, trans, i, want.At(i, 0), gots.At(i, 0))
+					}
+				}
+
+				allocs := testing.AllocsPerRun(10, func() {
+					MulMatVec(got, -3, trans, bsr, x)
+				})
+				if allocs != 0 {
+					t.Errorf("%d×%d, block %d, trans=%t: unexpected allocations: %v", test.r, test.c, test.block, trans, allocs)
+				}
+			}
+		}
+	}
+}
+
+func TestBSRMulMatVecConcurrent(t *testing.T) {
+	bsr := NewBSR(laplacian2DDOK(12), 3)
+	n, _ := bsr.Dims()
+	// Strided vectors go through the pooled buffers.
+	x := mat64.NewDense(n, 2, nil).ColView(1)
+	for i := 0; i < n; i++ {
+		x.SetVec(i, float64(i%5))
+	}
+	want := mat64.NewVector(n, nil)
+	MulMatVec(want, 1, false, bsr, x)
+
+	var wg sync.WaitGroup
+	got := make([]*mat64.Vector, 8)
+	for g := range got {
+		got[g] = mat64.NewDense(n, 2, nil).ColView(0)
+		wg.Add(1)
+		go func(y *mat64.Vector) {
+			defer wg.Done()
+			for rep := 0; rep < 10; rep++ {
+				y.ScaleVec(0, y)
+				MulMatVec(y, 1, false, bsr, x)
+			}
+		}(got[g])
+	}
+	wg.Wait()
+	for g, y := range got {
+		for i := 0; i < n; i++ {
+			if y.At(i, 0) != want.At(i, 0) {
+				t.Errorf("goroutine %d: mismatch at %d: want %v, got %v", g, i, want.At(i, 0), y.At(i, 0))
+				break
+			}
+		}
+	}
+}
+
+func TestBSRBuilder(t *testing.T) {
+	b := NewBSRBuilder(4, 6, 2)
+	b.InsertBlock(1, 2, []float64{1, 2, 3, 4})
+	b.AddBlock(0, 0, []float64{1, 0, 0, 1})
+	b.AddBlock(0, 0, []float64{1, -1, 0, 1})
+	b.InsertEntry(3, 0, 5)
+	a := b.BSR()
+
+	want := [][]float64{
+		{2, -1, 0, 0, 0, 0},
+		{0, 2, 0, 0, 0, 0},
+		{0, 0, 0, 0, 1, 2},
+		{5, 0, 0, 0, 3, 4},
+	}
+	if a.NNZBlocks() != 3 {
+		t.Errorf("unexpected number of blocks: want 3, got %d", a.NNZBlocks())
+	}
+	for i := range want {
+		for j, v := range want[i] {
+			if a.At(i, j) != v {
+				t.Errorf("entries not equal at (%d,%d): want %v, got %v", i, j, v, a.At(i, j))
+			}
+		}
+	}
+
+	var n int
+	a.DoNonZero(func(i, j int, v float64) {
+		n++
+		if v != want[i][j] {
+			t.Errorf("DoNonZero: entries not equal at (%d,%d): want %v, got %v", i, j, want[i][j], v)
+		}
+	})
+	if n != 12 {
+		t.Errorf("DoNonZero: unexpected number of entries: want 12, got %d", n)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected panic for dimensions not multiple of the block size")
+			}
+		}()
+		NewBSRBuilder(4, 5, 2)
+	}()
+}
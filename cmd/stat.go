@@ -0,0 +1,84 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vladimir-ch/sparse"
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("missing file name")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	for i, name := range flag.Args() {
+		a, err := readFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		printStatistics(w, name, sparse.Analyze(a))
+	}
+	w.Flush()
+}
+
+func readFile(name string) (*sparse.DOK, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader
+	if path.Ext(name) == ".gz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.TrimSuffix(name, ".gz")
+		r = gz
+	} else {
+		r = f
+	}
+
+	switch path.Ext(name) {
+	case ".mtx":
+		return sparse.ReadMatrixMarket(r)
+	default:
+		return nil, fmt.Errorf("%s: unknown file extension", name)
+	}
+}
+
+func printStatistics(w io.Writer, name string, s sparse.Statistics) {
+	fmt.Fprintf(w, "File:\t%s\n", name)
+	fmt.Fprintf(w, "Dimensions:\t%d × %d\n", s.Rows, s.Cols)
+	fmt.Fprintf(w, "Non-zeros:\t%d\n", s.NNZ)
+	fmt.Fprintf(w, "Non-zeros per row:\tmin %d, max %d, mean %.2f, std. dev. %.2f\n",
+		s.RowNNZMin, s.RowNNZMax, s.RowNNZMean, s.RowNNZStdDev)
+	fmt.Fprintf(w, "Empty rows / columns:\t%d / %d\n", s.EmptyRows, s.EmptyCols)
+	fmt.Fprintf(w, "Bandwidth:\t%d (lower %d, upper %d)\n", s.Bandwidth, s.LowerBandwidth, s.UpperBandwidth)
+	fmt.Fprintf(w, "Profile:\t%d\n", s.Profile)
+	fmt.Fprintf(w, "Diagonal:\t%d non-zeros, %d missing\n", s.DiagonalNNZ, s.MissingDiagonal)
+	fmt.Fprintf(w, "Structural symmetry:\t%.4f\n", s.StructuralSymmetry)
+	fmt.Fprintf(w, "Numerical symmetry:\t%.4f\n", s.NumericalSymmetry)
+	fmt.Fprintf(w, "Diagonally dominant rows:\t%.4f\n", s.DiagonalDominance)
+	fmt.Fprintf(w, "Values:\t[%g, %g]\n", s.Min, s.Max)
+	fmt.Fprintf(w, "Absolute values:\t[%g, %g]\n", s.MinAbs, s.MaxAbs)
+	fmt.Fprintf(w, "Symmetric:\t%t\n", s.Properties.Symmetric)
+	fmt.Fprintf(w, "Lower triangular:\t%t\n", s.Properties.LowerTriangular)
+	fmt.Fprintf(w, "Upper triangular:\t%t\n", s.Properties.UpperTriangular)
+	fmt.Fprintf(w, "Diagonal matrix:\t%t\n", s.Properties.Diagonal)
+	fmt.Fprintf(w, "Positive diagonal:\t%t\n", s.Properties.PositiveDiagonal)
+}
@@ -6,29 +6,50 @@ package sparse
 
 import (
 	"sort"
+	"sync"
 
+	"github.com/gonum/blas"
 	"github.com/gonum/matrix/mat64"
 )
 
-type CSR struct {
+// CSROf is a sparse matrix with element type T in the Compressed Sparse Row
+// format.
+type CSROf[T Scalar] struct {
 	rows, cols int
 
-	values   []float64
+	values   []T
 	columns  []int
 	rowIndex []int
+
+	props MatrixProperties
+
+	// partition holds the boundaries of row blocks processed by separate
+	// goroutines in MulMatVec. It is nil if the product is computed
+	// serially.
+	partition []int
+}
+
+// CSR is a sparse matrix in the Compressed Sparse Row format.
+type CSR = CSROf[float64]
+
+func NewCSR[T Scalar](dok *DOKOf[T]) *CSROf[T] {
+	rows, cols := dok.Dims()
+	m := newCSR(rows, cols, dok.Triplets())
+	m.props = dok.props
+	return m
 }
 
-func NewCSR(dok *DOK) *CSR {
-	triplets := dok.Triplets()
+// newCSR returns a new CSR matrix with entries given by triplets that must be
+// unique. The triplets will be sorted.
+func newCSR[T Scalar](rows, cols int, triplets []TripletOf[T]) *CSROf[T] {
 	nnz := len(triplets)
 
 	// Triplets from DOK are unique, but not sorted. Alternatively, we could
 	// have something like SortIndices() method to turn the matrix into the
 	// canonical form.
-	sort.Sort(rowWise(triplets))
+	sort.Sort(rowWise[T](triplets))
 
-	rows, cols := dok.Dims()
-	values := make([]float64, nnz)
+	values := make([]T, nnz)
 	columns := make([]int, nnz)
 	rowIndex := make([]int, rows+1)
 
@@ -53,7 +74,7 @@ func NewCSR(dok *DOK) *CSR {
 		offset[t.Row]++
 	}
 
-	return &CSR{
+	return &CSROf[T]{
 		rows:     rows,
 		cols:     cols,
 		values:   values,
@@ -62,11 +83,38 @@ func NewCSR(dok *DOK) *CSR {
 	}
 }
 
-func (m *CSR) Dims() (r, c int) {
+// ConvertCSR returns a copy of the real matrix a with element type converted
+// to T, for example a single precision copy of a double precision matrix. The
+// returned matrix shares the index arrays with a.
+func ConvertCSR[T, S Real](a *CSROf[S]) *CSROf[T] {
+	values := make([]T, len(a.values))
+	for k, v := range a.values {
+		values[k] = T(v)
+	}
+	return &CSROf[T]{
+		rows:     a.rows,
+		cols:     a.cols,
+		values:   values,
+		columns:  a.columns,
+		rowIndex: a.rowIndex,
+		props:    a.props,
+	}
+}
+
+func (m *CSROf[T]) Dims() (r, c int) {
 	return m.rows, m.cols
 }
 
-func (m *CSR) At(r, c int) float64 {
+func (m *CSROf[T]) MulVecTo(dst []T, trans bool, x []T) {
+	if m, ok := any(m).(*CSR); ok {
+		// Use the real kernel which can run in parallel.
+		mulVecTo(any(dst).([]float64), trans, m, any(x).([]float64))
+		return
+	}
+	mulVecToOf[T](dst, trans, m, x)
+}
+
+func (m *CSROf[T]) At(r, c int) T {
 	if r >= m.rows || r < 0 {
 		panic("sparse: row index out of range")
 	}
@@ -82,6 +130,79 @@ func (m *CSR) At(r, c int) float64 {
 	return 0
 }
 
+func (m *CSROf[T]) Properties() MatrixProperties {
+	return m.props
+}
+
+// SetProperties sets the properties of the matrix. The properties are not
+// verified, DetectProperties can be used to determine them from the entries.
+func (m *CSROf[T]) SetProperties(props MatrixProperties) {
+	m.props = props
+}
+
+func (m *CSROf[T]) DoNonZero(fn func(r, c int, v T)) {
+	for i := 0; i < m.rows; i++ {
+		for j := m.rowIndex[i]; j < m.rowIndex[i+1]; j++ {
+			fn(i, m.columns[j], m.values[j])
+		}
+	}
+}
+
+// DoRowNonZero calls fn for each stored entry in row i of m in the order of
+// increasing column index.
+func (m *CSROf[T]) DoRowNonZero(i int, fn func(r, c int, v T)) {
+	if i >= m.rows || i < 0 {
+		panic("sparse: row index out of range")
+	}
+	for j := m.rowIndex[i]; j < m.rowIndex[i+1]; j++ {
+		fn(i, m.columns[j], m.values[j])
+	}
+}
+
+// SetWorkers sets the number of goroutines that MulMatVec uses to compute
+// products with m. Rows of m are partitioned among the workers so that each
+// of them processes about the same number of non-zeros. If n is less than
+// two, the products are computed serially, which is the default.
+//
+// Only products of float64 matrices computed by MulMatVec use the workers.
+// For other element types the partition is recorded and reported by Workers,
+// but MulMatVecOf always computes the products serially.
+func (m *CSROf[T]) SetWorkers(n int) {
+	if n > m.rows {
+		n = m.rows
+	}
+	if n < 2 {
+		m.partition = nil
+		return
+	}
+
+	nnz := m.rowIndex[m.rows]
+	m.partition = make([]int, n+1)
+	for k := 1; k < n; k++ {
+		// Find the first row that starts at or after the k-th fraction of
+		// non-zeros.
+		i := sort.SearchInts(m.rowIndex, k*nnz/n)
+		if i < m.partition[k-1] {
+			i = m.partition[k-1]
+		}
+		if i > m.rows {
+			i = m.rows
+		}
+		m.partition[k] = i
+	}
+	m.partition[n] = m.rows
+}
+
+// Workers returns the number of goroutines that MulMatVec uses to compute
+// products with m. See SetWorkers for the element types that are computed in
+// parallel.
+func (m *CSROf[T]) Workers() int {
+	if m.partition == nil {
+		return 1
+	}
+	return len(m.partition) - 1
+}
+
 func csrMulMatVec(y *mat64.Vector, alpha float64, transA bool, a *CSR, x *mat64.Vector) {
 	r, c := a.Dims()
 	if transA {
@@ -98,6 +219,15 @@ func csrMulMatVec(y *mat64.Vector, alpha float64, transA bool, a *CSR, x *mat64.
 		return
 	}
 
+	if a.partition != nil {
+		if transA {
+			csrMulMatTransVecParallel(y, alpha, a, x)
+		} else {
+			csrMulMatVecParallel(y, alpha, a, x)
+		}
+		return
+	}
+
 	yRaw := y.RawVector()
 	if transA {
 		row := Vector{N: y.Len()}
@@ -119,3 +249,151 @@ func csrMulMatVec(y *mat64.Vector, alpha float64, transA bool, a *CSR, x *mat64.
 		}
 	}
 }
+
+// csrMulMatVecParallel computes y += alpha * A * x with row blocks of A
+// processed concurrently. Each goroutine writes to a disjoint part of y.
+func csrMulMatVecParallel(y *mat64.Vector, alpha float64, a *CSR, x *mat64.Vector) {
+	xRaw := x.RawVector()
+	yRaw := y.RawVector()
+
+	var wg sync.WaitGroup
+	for w := 0; w < len(a.partition)-1; w++ {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				var sum float64
+				for k := a.rowIndex[i]; k < a.rowIndex[i+1]; k++ {
+					sum += a.values[k] * xRaw.Data[a.columns[k]*xRaw.Inc]
+				}
+				yRaw.Data[i*yRaw.Inc] += alpha * sum
+			}
+		}(a.partition[w], a.partition[w+1])
+	}
+	wg.Wait()
+}
+
+// csrMulMatTransVecParallel computes y += alpha * Aᵀ * x with row blocks of A
+// processed concurrently. Since the rows scatter into overlapping parts of y,
+// each goroutine accumulates into its own buffer and the buffers are summed
+// into y afterwards, again concurrently over disjoint blocks of y. The
+// buffers come from workPool, so products with the same matrix may run
+// concurrently.
+func csrMulMatTransVecParallel(y *mat64.Vector, alpha float64, a *CSR, x *mat64.Vector) {
+	xRaw := x.RawVector()
+	yRaw := y.RawVector()
+
+	workers := len(a.partition) - 1
+	bufs := make([]*[]float64, workers)
+	buf := make([][]float64, workers)
+	for w := range buf {
+		bufs[w] = getWork(a.cols)
+		buf[w] = *bufs[w]
+	}
+	defer func() {
+		for _, p := range bufs {
+			putWork(p)
+		}
+	}()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(acc []float64, start, end int) {
+			defer wg.Done()
+			for j := range acc {
+				acc[j] = 0
+			}
+			for i := start; i < end; i++ {
+				xi := xRaw.Data[i*xRaw.Inc]
+				for k := a.rowIndex[i]; k < a.rowIndex[i+1]; k++ {
+					acc[a.columns[k]] += a.values[k] * xi
+				}
+			}
+		}(buf[w], a.partition[w], a.partition[w+1])
+	}
+	wg.Wait()
+
+	chunk := (a.cols + workers - 1) / workers
+	for start := 0; start < a.cols; start += chunk {
+		end := start + chunk
+		if end > a.cols {
+			end = a.cols
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for j := start; j < end; j++ {
+				var sum float64
+				for _, acc := range buf {
+					sum += acc[j]
+				}
+				yRaw.Data[j*yRaw.Inc] += alpha * sum
+			}
+		}(start, end)
+	}
+	wg.Wait()
+}
+
+// workPool holds scratch slices for the matrix-vector products. Taking them
+// from a pool instead of keeping them in the matrix lets products with the
+// same matrix run concurrently.
+var workPool sync.Pool
+
+// getWork returns a pointer to a slice of length n taken from workPool or
+// newly allocated. The contents of the slice are undefined.
+func getWork(n int) *[]float64 {
+	if p, ok := workPool.Get().(*[]float64); ok && cap(*p) >= n {
+		*p = (*p)[:n]
+		return p
+	}
+	s := make([]float64, n)
+	return &s
+}
+
+// putWork returns a slice obtained from getWork to workPool.
+func putWork(p *[]float64) {
+	workPool.Put(p)
+}
+
+func csrMulMatVecOf[T Scalar](y []T, alpha T, trans blas.Transpose, a *CSROf[T], x []T) {
+	r, c := a.Dims()
+	if trans != blas.NoTrans {
+		if r != len(x) || c != len(y) {
+			panic("sparse: dimension mismatch")
+		}
+	} else {
+		if r != len(y) || c != len(x) {
+			panic("sparse: dimension mismatch")
+		}
+	}
+
+	if alpha == 0 {
+		return
+	}
+
+	conj := conjFunc[T]()
+	switch {
+	case trans == blas.NoTrans:
+		for i := 0; i < r; i++ {
+			var sum T
+			for k := a.rowIndex[i]; k < a.rowIndex[i+1]; k++ {
+				sum += a.values[k] * x[a.columns[k]]
+			}
+			y[i] += alpha * sum
+		}
+	case trans == blas.ConjTrans && conj != nil:
+		for i := 0; i < r; i++ {
+			axi := alpha * x[i]
+			for k := a.rowIndex[i]; k < a.rowIndex[i+1]; k++ {
+				y[a.columns[k]] += conj(a.values[k]) * axi
+			}
+		}
+	default:
+		for i := 0; i < r; i++ {
+			axi := alpha * x[i]
+			for k := a.rowIndex[i]; k < a.rowIndex[i+1]; k++ {
+				y[a.columns[k]] += a.values[k] * axi
+			}
+		}
+	}
+}
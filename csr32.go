@@ -0,0 +1,159 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// CSR32 is a sparse matrix in the Compressed Sparse Row format with 32-bit
+// column indices. Compared to CSR it halves the size of the column index
+// array, which reduces the memory traffic of the matrix-vector product by
+// about a third. The number of columns must not exceed math.MaxInt32, the
+// row pointers are 64-bit so the number of non-zeros is not limited.
+type CSR32 struct {
+	rows, cols int
+
+	values   []float64
+	columns  []int32
+	rowIndex []int64
+
+	props MatrixProperties
+}
+
+// NewCSR32 returns a new CSR32 matrix that holds the entries of dok. It will
+// panic if dok has more than math.MaxInt32 columns.
+func NewCSR32(dok *DOK) *CSR32 {
+	return NewCSR32FromCSR(NewCSR(dok))
+}
+
+// NewCSR32FromCSR returns a new CSR32 matrix that holds the entries of a. It
+// will panic if a has more than math.MaxInt32 columns.
+func NewCSR32FromCSR(a *CSR) *CSR32 {
+	if a.cols > math.MaxInt32 {
+		panic("sparse: too many columns for 32-bit indices")
+	}
+
+	nnz := a.rowIndex[a.rows]
+	m := &CSR32{
+		rows:     a.rows,
+		cols:     a.cols,
+		values:   make([]float64, nnz),
+		columns:  make([]int32, nnz),
+		rowIndex: make([]int64, a.rows+1),
+		props:    a.props,
+	}
+	copy(m.values, a.values)
+	for k, j := range a.columns[:nnz] {
+		m.columns[k] = int32(j)
+	}
+	for i, k := range a.rowIndex {
+		m.rowIndex[i] = int64(k)
+	}
+	return m
+}
+
+// ToCSR returns a new CSR matrix with the entries of m.
+func (m *CSR32) ToCSR() *CSR {
+	nnz := m.rowIndex[m.rows]
+	a := &CSR{
+		rows:     m.rows,
+		cols:     m.cols,
+		values:   make([]float64, nnz),
+		columns:  make([]int, nnz),
+		rowIndex: make([]int, m.rows+1),
+		props:    m.props,
+	}
+	copy(a.values, m.values)
+	for k, j := range m.columns[:nnz] {
+		a.columns[k] = int(j)
+	}
+	for i, k := range m.rowIndex {
+		a.rowIndex[i] = int(k)
+	}
+	return a
+}
+
+func (m *CSR32) Dims() (r, c int) {
+	return m.rows, m.cols
+}
+
+func (m *CSR32) MulVecTo(dst []float64, trans bool, x []float64) {
+	mulVecTo(dst, trans, m, x)
+}
+
+func (m *CSR32) At(r, c int) float64 {
+	if r >= m.rows || r < 0 {
+		panic("sparse: row index out of range")
+	}
+	if c >= m.cols || c < 0 {
+		panic("sparse: column index out of range")
+	}
+
+	for j := m.rowIndex[r]; j < m.rowIndex[r+1]; j++ {
+		if int(m.columns[j]) == c {
+			return m.values[j]
+		}
+	}
+	return 0
+}
+
+func (m *CSR32) Properties() MatrixProperties {
+	return m.props
+}
+
+// SetProperties sets the properties of the matrix. The properties are not
+// verified, DetectProperties can be used to determine them from the entries.
+func (m *CSR32) SetProperties(props MatrixProperties) {
+	m.props = props
+}
+
+func (m *CSR32) DoNonZero(fn func(r, c int, v float64)) {
+	for i := 0; i < m.rows; i++ {
+		for j := m.rowIndex[i]; j < m.rowIndex[i+1]; j++ {
+			fn(i, int(m.columns[j]), m.values[j])
+		}
+	}
+}
+
+func csr32MulMatVec(y *mat64.Vector, alpha float64, transA bool, a *CSR32, x *mat64.Vector) {
+	r, c := a.Dims()
+	if transA {
+		if r != x.Len() || c != y.Len() {
+			panic("sparse: dimension mismatch")
+		}
+	} else {
+		if r != y.Len() || c != x.Len() {
+			panic("sparse: dimension mismatch")
+		}
+	}
+
+	if alpha == 0 {
+		return
+	}
+
+	xRaw := x.RawVector()
+	yRaw := y.RawVector()
+	for i := 0; i < r; i++ {
+		start := a.rowIndex[i]
+		end := a.rowIndex[i+1]
+		values := a.values[start:end]
+		columns := a.columns[start:end]
+		if transA {
+			xi := alpha * xRaw.Data[i*xRaw.Inc]
+			for k, v := range values {
+				yRaw.Data[int(columns[k])*yRaw.Inc] += v * xi
+			}
+			continue
+		}
+		var sum float64
+		for k, v := range values {
+			sum += v * xRaw.Data[int(columns[k])*xRaw.Inc]
+		}
+		yRaw.Data[i*yRaw.Inc] += alpha * sum
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestCSR32(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for id, test := range []struct {
+		r, c, nnz int
+	}{
+		{1, 1, 1},
+		{5, 3, 0},
+		{10, 10, 30},
+		{57, 31, 400},
+	} {
+		dok := NewDOK(test.r, test.c)
+		for k := 0; k < test.nnz; k++ {
+			dok.InsertEntry(rnd.Intn(test.r), rnd.Intn(test.c), rnd.NormFloat64())
+		}
+		csr := NewCSR(dok)
+		m := NewCSR32(dok)
+
+		for i := 0; i < test.r; i++ {
+			for j := 0; j < test.c; j++ {
+				if m.At(i, j) != dok.At(i, j) {
+					t.Errorf("%d: entries not equal at (%d,%d)", id+1, i, j)
+				}
+			}
+		}
+		if back := m.ToCSR(); !reflect.DeepEqual(back, csr) {
+			t.Errorf("%d: ToCSR does not return the original matrix", id+1)
+		}
+
+		for _, trans := range []bool{false, true} {
+			r, c := test.r, test.c
+			if trans {
+				r, c = c, r
+			}
+			x := mat64.NewVector(c, nil)
+			for i := 0; i < c; i++ {
+				x.SetVec(i, rnd.NormFloat64())
+			}
+			want := mat64.NewVector(r, nil)
+			MulMatVec(want, 2, trans, csr, x)
+			got := mat64.NewVector(r, nil)
+			MulMatVec(got, 2, trans, m, x)
+			for i := 0; i < r; i++ {
+				if math.Abs(got.At(i, 0)-want.At(i, 0)) > 1e-13 {
+					t.Errorf("%d, trans=%t: mismatch at %d: want %v, got %v",
+						id+1, trans, i, want.At(i, 0), got.At(i, 0))
+					break
+				}
+			}
+		}
+	}
+}
@@ -4,7 +4,15 @@
 
 package sparse
 
-import "testing"
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
 
 func TestCSR(t *testing.T) {
 	for _, test := range []struct {
@@ -42,3 +50,195 @@ func TestCSR(t *testing.T) {
 		}
 	}
 }
+
+func TestCSRDoRowNonZero(t *testing.T) {
+	dok := NewDOK(3, 4)
+	dok.InsertEntry(1, 3, 4)
+	dok.InsertEntry(0, 0, 1)
+	dok.InsertEntry(1, 0, 2)
+	dok.InsertEntry(1, 2, 3)
+	csr := NewCSR(dok)
+
+	for _, test := range []struct {
+		row  int
+		cols []int
+		vals []float64
+	}{
+		{row: 0, cols: []int{0}, vals: []float64{1}},
+		{row: 1, cols: []int{0, 2, 3}, vals: []float64{2, 3, 4}},
+		{row: 2},
+	} {
+		var cols []int
+		var vals []float64
+		csr.DoRowNonZero(test.row, func(i, j int, v float64) {
+			if i != test.row {
+				t.Errorf("row %d: unexpected row index %d", test.row, i)
+			}
+			cols = append(cols, j)
+			vals = append(vals, v)
+		})
+		if fmt.Sprint(cols) != fmt.Sprint(test.cols) || fmt.Sprint(vals) != fmt.Sprint(test.vals) {
+			t.Errorf("row %d: want %v %v, got %v %v", test.row, test.cols, test.vals, cols, vals)
+		}
+	}
+}
+
+func TestCSRProperties(t *testing.T) {
+	dok := NewDOK(2, 2)
+	dok.InsertEntry(0, 0, 1)
+	dok.InsertEntry(1, 1, 1)
+	props := DetectProperties(dok, 0)
+	dok.SetProperties(props)
+
+	csr := NewCSR(dok)
+	if csr.Properties() != props {
+		t.Errorf("properties not carried forward: want %+v, got %+v", props, csr.Properties())
+	}
+}
+
+func TestCSRWorkers(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, test := range []struct {
+		r, c, nnz int
+	}{
+		{1, 1, 1},
+		{5, 3, 0},
+		{10, 10, 30},
+		{57, 31, 400},
+		{100, 200, 1000},
+	} {
+		dok := NewDOK(test.r, test.c)
+		for k := 0; k < test.nnz; k++ {
+			dok.InsertEntry(rnd.Intn(test.r), rnd.Intn(test.c), rnd.NormFloat64())
+		}
+		// Make the row lengths unbalanced.
+		for j := 0; j < test.c; j++ {
+			dok.InsertEntry(0, j, rnd.NormFloat64())
+		}
+		csr := NewCSR(dok)
+
+		for _, trans := range []bool{false, true} {
+			r, c := test.r, test.c
+			if trans {
+				r, c = c, r
+			}
+			x := mat64.NewVector(c, nil)
+			for i := 0; i < c; i++ {
+				x.SetVec(i, rnd.NormFloat64())
+			}
+			want := mat64.NewVector(r, nil)
+			csr.SetWorkers(1)
+			MulMatVec(want, 2, trans, csr, x)
+
+			for _, workers := range []int{2, 3, 8, 1000} {
+				csr.SetWorkers(workers)
+				if csr.Workers() > test.r || csr.Workers() > workers {
+					t.Errorf("unexpected number of workers %d", csr.Workers())
+				}
+				if csr.Workers() > 1 && csr.partition[csr.Workers()] != test.r {
+					t.Errorf("partition does not cover all rows")
+				}
+				// Repeat the product to check that the pooled buffers
+				// are reset between calls.
+				for rep := 0; rep < 2; rep++ {
+					got := mat64.NewVector(r, nil)
+					MulMatVec(got, 2, trans, csr, x)
+					for i := 0; i < r; i++ {
+						if math.Abs(got.At(i, 0)-want.At(i, 0)) > 1e-13 {
+							t.Errorf("%d×%d, trans=%t, workers=%d, rep=%d: mismatch at %d: want %v, got %v",
+								test.r, test.c, trans, workers, rep, i, want.At(i, 0), got.At(i, 0))
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCSRMulMatVecConcurrent(t *testing.T) {
+	a := laplacian2D(20)
+	a.SetWorkers(4)
+	n, _ := a.Dims()
+	x := mat64.NewVector(n, nil)
+	for i := 0; i < n; i++ {
+		x.SetVec(i, float64(i%7))
+	}
+	want := make([]*mat64.Vector, 2)
+	for k, trans := range []bool{false, true} {
+		want[k] = mat64.NewVector(n, nil)
+		MulMatVec(want[k], 1, trans, a, x)
+	}
+
+	var wg sync.WaitGroup
+	got := make([]*mat64.Vector, 16)
+	for g := range got {
+		got[g] = mat64.NewVector(n, nil)
+		wg.Add(1)
+		go func(y *mat64.Vector, trans bool) {
+			defer wg.Done()
+			for rep := 0; rep < 10; rep++ {
+				y.ScaleVec(0, y)
+				MulMatVec(y, 1, trans, a, x)
+			}
+		}(got[g], g%2 == 1)
+	}
+	wg.Wait()
+	for g, y := range got {
+		for i := 0; i < n; i++ {
+			if y.At(i, 0) != want[g%2].At(i, 0) {
+				t.Errorf("goroutine %d: mismatch at %d: want %v, got %v", g, i, want[g%2].At(i, 0), y.At(i, 0))
+				break
+			}
+		}
+	}
+}
+
+// laplacian2D returns the matrix of the 5-point finite difference
+// discretization of the Laplace operator on an n×n grid.
+func laplacian2D(n int) *CSR {
+	return NewCSR(laplacian2DDOK(n))
+}
+
+func laplacian2DDOK(n int) *DOK {
+	dok := NewDOK(n*n, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			k := i*n + j
+			dok.InsertEntry(k, k, 4)
+			if i > 0 {
+				dok.InsertEntry(k, k-n, -1)
+			}
+			if i < n-1 {
+				dok.InsertEntry(k, k+n, -1)
+			}
+			if j > 0 {
+				dok.InsertEntry(k, k-1, -1)
+			}
+			if j < n-1 {
+				dok.InsertEntry(k, k+1, -1)
+			}
+		}
+	}
+	return dok
+}
+
+func BenchmarkCSRMulMatVec(b *testing.B) {
+	a := laplacian2D(300)
+	n, _ := a.Dims()
+	x := mat64.NewVector(n, nil)
+	for i := 0; i < n; i++ {
+		x.SetVec(i, 1)
+	}
+	y := mat64.NewVector(n, nil)
+	for _, trans := range []bool{false, true} {
+		for _, workers := range []int{1, 2, 4, 8} {
+			a.SetWorkers(workers)
+			b.Run(fmt.Sprintf("trans=%t/workers=%d", trans, workers), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					MulMatVec(y, 1, trans, a, x)
+				}
+			})
+		}
+	}
+}
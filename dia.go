@@ -0,0 +1,185 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// DIA is a sparse matrix in the diagonal format. Only diagonals that contain
+// a non-zero entry are stored, each as a dense array. The format suits banded
+// matrices and finite difference operators that consist of a few diagonals
+// with constant offsets.
+type DIA struct {
+	rows, cols int
+	nnz        int
+
+	// offsets holds the sorted offsets of the stored diagonals. The main
+	// diagonal has offset zero, superdiagonals have positive offsets.
+	offsets []int
+
+	// The entry (i, i+offsets[k]) is stored at data[k*rows+i]. Positions
+	// that fall outside of the matrix are zero.
+	data []float64
+
+	props MatrixProperties
+}
+
+// NewDIA returns a new DIA matrix that holds the entries of a. If maxFill is
+// positive and the ratio of the number of stored entries to the number of
+// entries of a exceeds it, NewDIA returns an error instead, because the
+// matrix is not suited to the diagonal format.
+func NewDIA(a *CSR, maxFill float64) (*DIA, error) {
+	var offsets []int
+	seen := make(map[int]bool)
+	for i := 0; i < a.rows; i++ {
+		for j := a.rowIndex[i]; j < a.rowIndex[i+1]; j++ {
+			off := a.columns[j] - i
+			if !seen[off] {
+				seen[off] = true
+				offsets = append(offsets, off)
+			}
+		}
+	}
+	sort.Ints(offsets)
+
+	padding := PaddingStats{
+		NNZ:    a.rowIndex[a.rows],
+		Stored: len(offsets) * a.rows,
+	}
+	if maxFill > 0 && padding.Ratio() > maxFill {
+		return nil, fmt.Errorf("sparse: fill ratio %.2f of the diagonal format exceeds %.2f", padding.Ratio(), maxFill)
+	}
+
+	index := make(map[int]int, len(offsets))
+	for k, off := range offsets {
+		index[off] = k
+	}
+	m := &DIA{
+		rows:    a.rows,
+		cols:    a.cols,
+		nnz:     padding.NNZ,
+		offsets: offsets,
+		data:    make([]float64, len(offsets)*a.rows),
+		props:   a.props,
+	}
+	for i := 0; i < a.rows; i++ {
+		for j := a.rowIndex[i]; j < a.rowIndex[i+1]; j++ {
+			k := index[a.columns[j]-i]
+			m.data[k*a.rows+i] = a.values[j]
+		}
+	}
+	return m, nil
+}
+
+func (m *DIA) Dims() (r, c int) {
+	return m.rows, m.cols
+}
+
+func (m *DIA) MulVecTo(dst []float64, trans bool, x []float64) {
+	mulVecTo(dst, trans, m, x)
+}
+
+func (m *DIA) At(r, c int) float64 {
+	if r >= m.rows || r < 0 {
+		panic("sparse: row index out of range")
+	}
+	if c >= m.cols || c < 0 {
+		panic("sparse: column index out of range")
+	}
+
+	k := sort.SearchInts(m.offsets, c-r)
+	if k == len(m.offsets) || m.offsets[k] != c-r {
+		return 0
+	}
+	return m.data[k*m.rows+r]
+}
+
+func (m *DIA) Properties() MatrixProperties {
+	return m.props
+}
+
+// Offsets returns the sorted offsets of the stored diagonals. The returned
+// slice must not be modified.
+func (m *DIA) Offsets() []int {
+	return m.offsets
+}
+
+// Padding returns statistics of the storage overhead due to zeros stored on
+// the diagonals.
+func (m *DIA) Padding() PaddingStats {
+	return PaddingStats{
+		NNZ:    m.nnz,
+		Stored: len(m.data),
+	}
+}
+
+// Diagonal returns a new vector with the entries of the diagonal with the
+// given offset. The main diagonal has offset zero, superdiagonals have
+// positive and subdiagonals negative offsets. Diagonal will panic if the
+// diagonal lies outside of the matrix.
+func (m *DIA) Diagonal(offset int) *mat64.Vector {
+	start, end := m.diagonalRows(offset)
+	if start >= end {
+		panic("sparse: diagonal offset out of range")
+	}
+
+	d := make([]float64, end-start)
+	k := sort.SearchInts(m.offsets, offset)
+	if k < len(m.offsets) && m.offsets[k] == offset {
+		copy(d, m.data[k*m.rows+start:k*m.rows+end])
+	}
+	return mat64.NewVector(len(d), d)
+}
+
+// diagonalRows returns the range of rows in which the diagonal with the given
+// offset lies within the matrix.
+func (m *DIA) diagonalRows(offset int) (start, end int) {
+	start = 0
+	if offset < 0 {
+		start = -offset
+	}
+	end = m.rows
+	if m.cols-offset < end {
+		end = m.cols - offset
+	}
+	return start, end
+}
+
+func diaMulMatVec(y *mat64.Vector, alpha float64, transA bool, a *DIA, x *mat64.Vector) {
+	r, c := a.Dims()
+	if transA {
+		if r != x.Len() || c != y.Len() {
+			panic("sparse: dimension mismatch")
+		}
+	} else {
+		if r != y.Len() || c != x.Len() {
+			panic("sparse: dimension mismatch")
+		}
+	}
+
+	if alpha == 0 {
+		return
+	}
+
+	xRaw := x.RawVector()
+	yRaw := y.RawVector()
+	for k, off := range a.offsets {
+		start, end := a.diagonalRows(off)
+		d := a.data[k*r : (k+1)*r]
+		if transA {
+			for i := start; i < end; i++ {
+				yRaw.Data[(i+off)*yRaw.Inc] += alpha * d[i] * xRaw.Data[i*xRaw.Inc]
+			}
+		} else {
+			for i := start; i < end; i++ {
+				yRaw.Data[i*yRaw.Inc] += alpha * d[i] * xRaw.Data[(i+off)*xRaw.Inc]
+			}
+		}
+	}
+}
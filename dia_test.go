@@ -0,0 +1,118 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestDIA(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, test := range []struct {
+		r, c    int
+		offsets []int
+	}{
+		{1, 1, []int{0}},
+		{5, 5, []int{-1, 0, 1}},
+		{4, 7, []int{-3, 0, 2, 6}},
+		{7, 4, []int{-6, -2, 0, 3}},
+		{6, 6, nil},
+	} {
+		dok := NewDOK(test.r, test.c)
+		for _, off := range test.offsets {
+			for i := 0; i < test.r; i++ {
+				if j := i + off; 0 <= j && j < test.c {
+					dok.InsertEntry(i, j, rnd.NormFloat64())
+				}
+			}
+		}
+		csr := NewCSR(dok)
+		a, err := NewDIA(csr, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(a.Offsets()) != len(test.offsets) || len(test.offsets) > 0 && !reflect.DeepEqual(a.Offsets(), test.offsets) {
+			t.Errorf("unexpected offsets: want %v, got %v", test.offsets, a.Offsets())
+		}
+		for i := 0; i < test.r; i++ {
+			for j := 0; j < test.c; j++ {
+				if a.At(i, j) != dok.At(i, j) {
+					t.Errorf("entries not equal at (%d,%d): want %v, got %v", i, j, dok.At(i, j), a.At(i, j))
+				}
+			}
+		}
+
+		for off := -test.r + 1; off < test.c; off++ {
+			d := a.Diagonal(off)
+			start := 0
+			if off < 0 {
+				start = -off
+			}
+			for k := 0; k < d.Len(); k++ {
+				if d.At(k, 0) != dok.At(start+k, start+k+off) {
+					t.Errorf("diagonal %d: entries not equal at %d: want %v, got %v", off, k, dok.At(start+k, start+k+off), d.At(k, 0))
+				}
+			}
+			if start+d.Len() != test.r && start+d.Len()+off != test.c {
+				t.Errorf("diagonal %d: unexpected length %d", off, d.Len())
+			}
+		}
+
+		for _, trans := range []bool{false, true} {
+			r, c := test.r, test.c
+			if trans {
+				r, c = c, r
+			}
+			x := mat64.NewVector(c, nil)
+			for i := 0; i < c; i++ {
+				x.SetVec(i, rnd.NormFloat64())
+			}
+			want := mat64.NewVector(r, nil)
+			got := mat64.NewVector(r, nil)
+			MulMatVec(want, 2, trans, csr, x)
+			MulMatVec(got, 2, trans, a, x)
+			for i := 0; i < r; i++ {
+				if math.Abs(got.At(i, 0)-want.At(i, 0)) > 1e-13 {
+					t.Errorf("%d×%d, trans=%t: mismatch at %d: want %v, got %v",
+						test.r, test.c, trans, i, want.At(i, 0), got.At(i, 0))
+				}
+			}
+		}
+	}
+}
+
+func TestDIAFill(t *testing.T) {
+	// An arrow matrix has a bad fill ratio in the diagonal format.
+	n := 10
+	dok := NewDOK(n, n)
+	for i := 0; i < n; i++ {
+		dok.InsertEntry(i, i, 1)
+		dok.InsertEntry(0, i, 1)
+		dok.InsertEntry(i, 0, 1)
+	}
+	csr := NewCSR(dok)
+
+	if _, err := NewDIA(csr, 2); err == nil {
+		t.Errorf("expected error for bad fill ratio")
+	}
+	a, err := NewDIA(csr, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := PaddingStats{NNZ: 3*n - 2, Stored: (2*n - 1) * n}
+	if a.Padding() != want {
+		t.Errorf("unexpected padding: want %+v, got %+v", want, a.Padding())
+	}
+
+	if _, err := NewDIA(laplacian2D(10), 2); err != nil {
+		t.Errorf("unexpected error for stencil matrix: %v", err)
+	}
+}
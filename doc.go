@@ -0,0 +1,14 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sparse provides sparse matrix formats and the basic linear algebra
+// operations with them.
+//
+// The generic types CSROf, DOKOf and VectorOf and the functions with the Of
+// suffix support float32, float64, complex64 and complex128 entries. CSR,
+// DOK and Vector are their float64 instances. The other formats, BSR, CSR32,
+// DIA, ELL, SELL, Skyline and SymCSR, store only float64 entries, so
+// a matrix with different entries must be stored in CSROf or DOKOf. For
+// example, ConvertCSR returns a single precision copy of a CSR matrix.
+package sparse
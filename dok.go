@@ -7,30 +7,46 @@ package sparse
 import (
 	"fmt"
 
+	"github.com/gonum/blas"
 	"github.com/gonum/matrix/mat64"
 )
 
 type Index [2]int
 
-type DOK struct {
+// DOKOf is a sparse matrix with element type T in the dictionary of keys
+// format.
+type DOKOf[T Scalar] struct {
 	rows, cols int
-	data       map[Index]float64
+	data       map[Index]T
 	props      MatrixProperties
 }
 
+// DOK is a sparse matrix in the dictionary of keys format.
+type DOK = DOKOf[float64]
+
 func NewDOK(r, c int) *DOK {
-	return &DOK{
+	return NewDOKOf[float64](r, c)
+}
+
+// NewDOKOf returns a new r×c matrix with element type T in the dictionary of
+// keys format.
+func NewDOKOf[T Scalar](r, c int) *DOKOf[T] {
+	return &DOKOf[T]{
 		rows: r,
 		cols: c,
-		data: make(map[Index]float64),
+		data: make(map[Index]T),
 	}
 }
 
-func (m *DOK) Dims() (r, c int) {
+func (m *DOKOf[T]) Dims() (r, c int) {
 	return m.rows, m.cols
 }
 
-func (m *DOK) At(r, c int) float64 {
+func (m *DOKOf[T]) MulVecTo(dst []T, trans bool, x []T) {
+	mulVecToOf[T](dst, trans, m, x)
+}
+
+func (m *DOKOf[T]) At(r, c int) T {
 	if r >= m.rows || r < 0 {
 		panic("sparse: row index out of range")
 	}
@@ -41,11 +57,17 @@ func (m *DOK) At(r, c int) float64 {
 	return m.data[Index{r, c}]
 }
 
-func (m *DOK) Properties() MatrixProperties {
+func (m *DOKOf[T]) Properties() MatrixProperties {
 	return m.props
 }
 
-func (m *DOK) SetSparse(r, c int, v float64) {
+// SetProperties sets the properties of the matrix. The properties are not
+// verified, DetectProperties can be used to determine them from the entries.
+func (m *DOKOf[T]) SetProperties(props MatrixProperties) {
+	m.props = props
+}
+
+func (m *DOKOf[T]) SetSparse(r, c int, v T) {
 	if r >= m.rows || r < 0 {
 		panic("sparse: row index out of range")
 	}
@@ -59,7 +81,7 @@ func (m *DOK) SetSparse(r, c int, v float64) {
 	m.data[Index{r, c}] = v
 }
 
-func (m *DOK) InsertEntry(r, c int, v float64) {
+func (m *DOKOf[T]) InsertEntry(r, c int, v T) {
 	if r >= m.rows || r < 0 {
 		panic("sparse: row index out of range")
 	}
@@ -70,14 +92,20 @@ func (m *DOK) InsertEntry(r, c int, v float64) {
 	m.data[Index{r, c}] = v
 }
 
-func (m *DOK) Triplets() []Triplet {
-	var t []Triplet
+func (m *DOKOf[T]) Triplets() []TripletOf[T] {
+	var t []TripletOf[T]
 	for k, v := range m.data {
-		t = append(t, Triplet{k[0], k[1], v})
+		t = append(t, TripletOf[T]{k[0], k[1], v})
 	}
 	return t
 }
 
+func (m *DOKOf[T]) DoNonZero(fn func(r, c int, v T)) {
+	for ij, v := range m.data {
+		fn(ij[0], ij[1], v)
+	}
+}
+
 func dokMulMatVec(y *mat64.Vector, alpha float64, transA bool, a *DOK, x *mat64.Vector) {
 	r, c := a.Dims()
 	if transA {
@@ -106,3 +134,36 @@ func dokMulMatVec(y *mat64.Vector, alpha float64, transA bool, a *DOK, x *mat64.
 		}
 	}
 }
+
+func dokMulMatVecOf[T Scalar](y []T, alpha T, trans blas.Transpose, a *DOKOf[T], x []T) {
+	r, c := a.Dims()
+	if trans != blas.NoTrans {
+		if r != len(x) || c != len(y) {
+			panic("sparse: dimension mismatch")
+		}
+	} else {
+		if r != len(y) || c != len(x) {
+			panic("sparse: dimension mismatch")
+		}
+	}
+
+	if alpha == 0 {
+		return
+	}
+
+	conj := conjFunc[T]()
+	switch {
+	case trans == blas.NoTrans:
+		for ij, aij := range a.data {
+			y[ij[0]] += alpha * aij * x[ij[1]]
+		}
+	case trans == blas.ConjTrans && conj != nil:
+		for ij, aij := range a.data {
+			y[ij[1]] += alpha * conj(aij) * x[ij[0]]
+		}
+	default:
+		for ij, aij := range a.data {
+			y[ij[1]] += alpha * aij * x[ij[0]]
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import "github.com/gonum/matrix/mat64"
+
+// PaddingStats describes the storage overhead of a padded sparse format.
+type PaddingStats struct {
+	NNZ    int // Number of stored entries of the source matrix.
+	Stored int // Number of stored entries including padding.
+}
+
+// Padding returns the number of padding entries.
+func (p PaddingStats) Padding() int {
+	return p.Stored - p.NNZ
+}
+
+// Ratio returns the ratio of stored entries to entries of the source matrix.
+// It is one if there is no padding.
+func (p PaddingStats) Ratio() float64 {
+	if p.NNZ == 0 {
+		if p.Stored == 0 {
+			return 1
+		}
+		return float64(p.Stored)
+	}
+	return float64(p.Stored) / float64(p.NNZ)
+}
+
+// ELL is a sparse matrix in the ELLPACK format. Every row is padded to the
+// length of the longest row and the entries are stored column-wise, that is,
+// the k-th entries of all rows are contiguous. The format suits matrices with
+// regular row lengths, such as those arising from stencils, for which the
+// inner loop of the matrix-vector product runs over rows with unit stride.
+type ELL struct {
+	rows, cols int
+	width      int // Length of the longest row.
+	nnz        int
+
+	// The k-th entry of row i is stored at k*rows+i. Padding entries have
+	// zero value and column index -1 and are skipped in products, so that
+	// NaN or Inf in a vector does not leak into rows that do not reference
+	// it.
+	values  []float64
+	columns []int
+
+	props MatrixProperties
+}
+
+// NewELL returns a new ELL matrix that holds the entries of a.
+func NewELL(a *CSR) *ELL {
+	var width int
+	for i := 0; i < a.rows; i++ {
+		if n := a.rowIndex[i+1] - a.rowIndex[i]; n > width {
+			width = n
+		}
+	}
+
+	m := &ELL{
+		rows:    a.rows,
+		cols:    a.cols,
+		width:   width,
+		nnz:     a.rowIndex[a.rows],
+		values:  make([]float64, a.rows*width),
+		columns: make([]int, a.rows*width),
+		props:   a.props,
+	}
+	for k := range m.columns {
+		m.columns[k] = -1
+	}
+	for i := 0; i < a.rows; i++ {
+		for k, j := 0, a.rowIndex[i]; j < a.rowIndex[i+1]; k, j = k+1, j+1 {
+			m.values[k*a.rows+i] = a.values[j]
+			m.columns[k*a.rows+i] = a.columns[j]
+		}
+	}
+	return m
+}
+
+func (m *ELL) Dims() (r, c int) {
+	return m.rows, m.cols
+}
+
+func (m *ELL) MulVecTo(dst []float64, trans bool, x []float64) {
+	mulVecTo(dst, trans, m, x)
+}
+
+func (m *ELL) At(r, c int) float64 {
+	if r >= m.rows || r < 0 {
+		panic("sparse: row index out of range")
+	}
+	if c >= m.cols || c < 0 {
+		panic("sparse: column index out of range")
+	}
+
+	for k := 0; k < m.width; k++ {
+		if m.columns[k*m.rows+r] == c {
+			return m.values[k*m.rows+r]
+		}
+	}
+	return 0
+}
+
+func (m *ELL) Properties() MatrixProperties {
+	return m.props
+}
+
+// Width returns the length of the longest row, to which all rows are padded.
+func (m *ELL) Width() int {
+	return m.width
+}
+
+// Padding returns statistics of the storage overhead due to padding.
+func (m *ELL) Padding() PaddingStats {
+	return PaddingStats{
+		NNZ:    m.nnz,
+		Stored: m.rows * m.width,
+	}
+}
+
+func ellMulMatVec(y *mat64.Vector, alpha float64, transA bool, a *ELL, x *mat64.Vector) {
+	r, c := a.Dims()
+	if transA {
+		if r != x.Len() || c != y.Len() {
+			panic("sparse: dimension mismatch")
+		}
+	} else {
+		if r != y.Len() || c != x.Len() {
+			panic("sparse: dimension mismatch")
+		}
+	}
+
+	if alpha == 0 {
+		return
+	}
+
+	xRaw := x.RawVector()
+	yRaw := y.RawVector()
+	if transA {
+		for k := 0; k < a.width; k++ {
+			values := a.values[k*r : (k+1)*r]
+			columns := a.columns[k*r : (k+1)*r]
+			for i, v := range values {
+				if columns[i] < 0 {
+					continue
+				}
+				yRaw.Data[columns[i]*yRaw.Inc] += alpha * v * xRaw.Data[i*xRaw.Inc]
+			}
+		}
+		return
+	}
+
+	for k := 0; k < a.width; k++ {
+		values := a.values[k*r : (k+1)*r]
+		columns := a.columns[k*r : (k+1)*r]
+		for i, v := range values {
+			if columns[i] < 0 {
+				continue
+			}
+			yRaw.Data[i*yRaw.Inc] += alpha * v * xRaw.Data[columns[i]*xRaw.Inc]
+		}
+	}
+}
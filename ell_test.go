@@ -0,0 +1,181 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestELLAndSELL(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, test := range []struct {
+		r, c, nnz int
+	}{
+		{1, 1, 1},
+		{3, 4, 0},
+		{7, 5, 12},
+		{20, 20, 60},
+		{33, 17, 150},
+	} {
+		dok := NewDOK(test.r, test.c)
+		for k := 0; k < test.nnz; k++ {
+			dok.InsertEntry(rnd.Intn(test.r), rnd.Intn(test.c), rnd.NormFloat64())
+		}
+		csr := NewCSR(dok)
+
+		mats := []Matrix{NewELL(csr)}
+		for _, cs := range [][2]int{{1, 1}, {4, 1}, {4, 8}, {8, 8}, {3, 300}} {
+			mats = append(mats, NewSELL(csr, cs[0], cs[1]))
+		}
+		for _, a := range mats {
+			name := fmt.Sprintf("%T", a)
+			if s, ok := a.(*SELL); ok {
+				name = fmt.Sprintf("SELL-%d-%d", s.ChunkSize(), s.SortingWindow())
+			}
+			for i := 0; i < test.r; i++ {
+				for j := 0; j < test.c; j++ {
+					if a.At(i, j) != dok.At(i, j) {
+						t.Errorf("%s: entries not equal at (%d,%d): want %v, got %v", name, i, j, dok.At(i, j), a.At(i, j))
+					}
+				}
+			}
+
+			for _, trans := range []bool{false, true} {
+				r, c := test.r, test.c
+				if trans {
+					r, c = c, r
+				}
+				x := mat64.NewVector(c, nil)
+				for i := 0; i < c; i++ {
+					x.SetVec(i, rnd.NormFloat64())
+				}
+				want := mat64.NewVector(r, nil)
+				got := mat64.NewVector(r, nil)
+				for i := 0; i < r; i++ {
+					want.SetVec(i, 1)
+					got.SetVec(i, 1)
+				}
+				MulMatVec(want, 0.5, trans, csr, x)
+				MulMatVec(got, 0.5, trans, a, x)
+				for i := 0; i < r; i++ {
+					if math.Abs(got.At(i, 0)-want.At(i, 0)) > 1e-13 {
+						t.Errorf("%s, %d×%d, trans=%t: mismatch at %d: want %v, got %v",
+							name, test.r, test.c, trans, i, want.At(i, 0), got.At(i, 0))
+					}
+				}
+
+				allocs := testing.AllocsPerRun(10, func() {
+					MulMatVec(got, 0.5, trans, a, x)
+				})
+				if allocs != 0 {
+					t.Errorf("%s, %d×%d, trans=%t: unexpected allocations: %v", name, test.r, test.c, trans, allocs)
+				}
+			}
+		}
+	}
+}
+
+func TestPadding(t *testing.T) {
+	// Row lengths 1, 4, 1, 2, 1, 1.
+	dok := NewDOK(6, 4)
+	for _, e := range []Triplet{
+		{0, 0, 1},
+		{1, 0, 1}, {1, 1, 1}, {1, 2, 1}, {1, 3, 1},
+		{2, 2, 1},
+		{3, 1, 1}, {3, 3, 1},
+		{4, 0, 1},
+		{5, 3, 1},
+	} {
+		dok.InsertEntry(e.Row, e.Col, e.Value)
+	}
+	csr := NewCSR(dok)
+
+	for _, test := range []struct {
+		a    interface{ Padding() PaddingStats }
+		want PaddingStats
+	}{
+		{NewELL(csr), PaddingStats{NNZ: 10, Stored: 24}},
+		{NewSELL(csr, 2, 1), PaddingStats{NNZ: 10, Stored: 14}},
+		{NewSELL(csr, 2, 6), PaddingStats{NNZ: 10, Stored: 12}},
+		{NewSELL(csr, 4, 4), PaddingStats{NNZ: 10, Stored: 20}},
+	} {
+		got := test.a.Padding()
+		if got != test.want {
+			t.Errorf("%T: unexpected padding: want %+v, got %+v", test.a, test.want, got)
+		}
+	}
+	if r := (PaddingStats{NNZ: 10, Stored: 14}).Ratio(); r != 1.4 {
+		t.Errorf("unexpected padding ratio: want 1.4, got %v", r)
+	}
+}
+
+func BenchmarkFormatMulMatVec(b *testing.B) {
+	csr := laplacian2D(300)
+	n, _ := csr.Dims()
+	x := mat64.NewVector(n, nil)
+	for i := 0; i < n; i++ {
+		x.SetVec(i, 1)
+	}
+	y := mat64.NewVector(n, nil)
+	for _, test := range []struct {
+		name string
+		a    Matrix
+	}{
+		{"CSR", csr},
+		{"CSR32", NewCSR32FromCSR(csr)},
+		{"ELL", NewELL(csr)},
+		{"SELL-8-1", NewSELL(csr, 8, 1)},
+		{"SELL-8-64", NewSELL(csr, 8, 64)},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				MulMatVec(y, 1, false, test.a, x)
+			}
+		})
+	}
+}
+
+func TestELLAndSELLNonFinite(t *testing.T) {
+	// Rows of different lengths, so that ELL and SELL store padding.
+	dok := NewDOK(4, 4)
+	dok.InsertEntry(0, 0, 1)
+	dok.InsertEntry(0, 1, 2)
+	dok.InsertEntry(0, 2, 3)
+	dok.InsertEntry(1, 3, 4)
+	dok.InsertEntry(2, 1, 5)
+	dok.InsertEntry(3, 0, 6)
+	dok.InsertEntry(3, 2, 7)
+	csr := NewCSR(dok)
+
+	mats := []Matrix{NewELL(csr), NewSELL(csr, 1, 1), NewSELL(csr, 3, 1), NewSELL(csr, 2, 4)}
+	for _, inf := range []float64{math.Inf(1), math.NaN()} {
+		for _, trans := range []bool{false, true} {
+			// Column 0 is referenced by rows 0 and 3 and row 0 by
+			// columns 0, 1 and 2, so a non-finite value in x must not
+			// reach the other entries of y.
+			x := mat64.NewVector(4, []float64{inf, 1, 1, 1})
+			if trans {
+				x = mat64.NewVector(4, []float64{1, inf, 1, 1})
+			}
+			want := mat64.NewVector(4, nil)
+			MulMatVec(want, 1, trans, csr, x)
+			for _, a := range mats {
+				got := mat64.NewVector(4, nil)
+				MulMatVec(got, 1, trans, a, x)
+				for i := 0; i < 4; i++ {
+					w, g := want.At(i, 0), got.At(i, 0)
+					if math.IsNaN(w) != math.IsNaN(g) || (!math.IsNaN(w) && w != g) {
+						t.Errorf("%T, x contains %v, trans=%t: mismatch at %d: want %v, got %v", a, inf, trans, i, w, g)
+					}
+				}
+			}
+		}
+	}
+}
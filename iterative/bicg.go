@@ -12,18 +12,32 @@ import (
 
 // BiCG implements the Bi-Conjugate Gradient iterative method with
 // preconditioning for solving the linear system Ax = b.
+//
+// Every iteration requires one product with A and one with Aᵀ. The method
+// needs solves with both M and Mᵀ, so the preconditioner must be symmetric.
+//
+// BiCG breaks down when z̃ᵀ r̃ or p̃ᵀ A p is negligible compared to the norms
+// of the vectors, that is, less than BreakdownTolerance times their
+// product. If BreakdownTolerance is zero, machine epsilon is used.
 type BiCG struct {
 	BreakdownTolerance float64
 
-	resume int
-	rho    float64
+	resume    int
+	first     bool
+	rho, rho1 float64
+
+	z  *mat64.Vector // Preconditioned residual M⁻¹ r.
+	rt *mat64.Vector // Shadow residual r̃.
+	pt *mat64.Vector // Shadow direction p̃.
 }
 
 func (bicg *BiCG) Init(ctx *Context) Operation {
 	if bicg.BreakdownTolerance == 0 {
-		bicg.BreakdownTolerance = 1e-6
+		bicg.BreakdownTolerance = dlamchE
 	}
+	bicg.first = true
 	bicg.rho = math.NaN()
+	bicg.rho1 = math.NaN()
 
 	dim := ctx.X.Len()
 	if ctx.P == nil || ctx.P.Len() != dim {
@@ -41,6 +55,12 @@ func (bicg *BiCG) Init(ctx *Context) Operation {
 	if ctx.Z == nil || ctx.Z.Len() != dim {
 		ctx.Z = mat64.NewVector(dim, nil)
 	}
+	bicg.z = reuseVector(bicg.z, dim)
+	bicg.rt = reuseVector(bicg.rt, dim)
+	bicg.pt = reuseVector(bicg.pt, dim)
+
+	// r̃ = r_0
+	bicg.rt.CopyVec(ctx.Residual)
 
 	bicg.resume = 2
 	return SolvePreconditioner
@@ -50,12 +70,65 @@ func (bicg *BiCG) Init(ctx *Context) Operation {
 func (bicg *BiCG) Iterate(ctx *Context) Operation {
 	switch bicg.resume {
 	case 1:
-		cg.resume = 2
+		bicg.resume = 2
 		return SolvePreconditioner
 		// Solve M z = r_{i-1}
 	case 2:
-		// ρ_i = r_{i-1} · z
-		cg.rho = mat64.Dot(ctx.Residual, ctx.Z)
+		bicg.z.CopyVec(ctx.Z)
+		ctx.Q.CopyVec(bicg.rt)
+		bicg.resume = 3
+		return SolvePreconditionerQ
+		// Solve Mᵀ z̃ = r̃_{i-1}
+	case 3:
+		// ρ_i = z · r̃_{i-1}
+		bicg.rho = mat64.Dot(bicg.z, bicg.rt)
+		if negligible(bicg.rho, bicg.z, bicg.rt, bicg.BreakdownTolerance) {
+			ctx.Err = &BreakdownError{Method: "BiCG", Quantity: "ρ", Value: bicg.rho}
+			bicg.resume = 0
+			return CheckConvergence
+		}
+		if bicg.first {
+			// p_i = z, p̃_i = z̃
+			ctx.P.CopyVec(bicg.z)
+			bicg.pt.CopyVec(ctx.Z)
+		} else {
+			// β = ρ_i / ρ_{i-1}
+			beta := bicg.rho / bicg.rho1
+			// p_i = z + β p_{i-1}, p̃_i = z̃ + β p̃_{i-1}
+			ctx.P.AddScaledVec(bicg.z, beta, ctx.P)
+			bicg.pt.AddScaledVec(ctx.Z, beta, bicg.pt)
+		}
+		bicg.first = false
+
+		bicg.resume = 4
+		return ComputeAp
+		// Compute Ap
+	case 4:
+		ctx.Q.CopyVec(bicg.pt)
+		bicg.resume = 5
+		return ComputeATq
+		// Compute Aᵀ p̃
+	case 5:
+		// σ = p̃_i · A p_i
+		sigma := mat64.Dot(bicg.pt, ctx.Ap)
+		if negligible(sigma, bicg.pt, ctx.Ap, bicg.BreakdownTolerance) {
+			ctx.Err = &BreakdownError{Method: "BiCG", Quantity: "σ", Value: sigma}
+			bicg.resume = 0
+			return CheckConvergence
+		}
+		// α = ρ_i / σ
+		alpha := bicg.rho / sigma
+		// x_i = x_{i-1} + α p_i
+		ctx.X.AddScaledVec(ctx.X, alpha, ctx.P)
+		// r_i = r_{i-1} - α Ap_i
+		ctx.Residual.AddScaledVec(ctx.Residual, -alpha, ctx.Ap)
+		// r̃_i = r̃_{i-1} - α Aᵀ p̃_i
+		bicg.rt.AddScaledVec(bicg.rt, -alpha, ctx.Aq)
+
+		bicg.rho1 = bicg.rho
+
+		bicg.resume = 1
+		return CheckConvergence
 	default:
 		panic("unreachable")
 	}
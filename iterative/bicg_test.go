@@ -0,0 +1,14 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import "testing"
+
+func TestBiCG(t *testing.T) {
+	for _, p := range nonsymProblems(t) {
+		testSolve(t, "BiCG", p, &BiCG{}, 1e-10)
+	}
+	testBreakdown(t, "BiCG", &BiCG{})
+}
@@ -0,0 +1,224 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"github.com/gonum/matrix/mat64"
+)
+
+// BiCGStabL implements the BiCGStab(ℓ) method of Sleijpen and Fokkema with
+// right preconditioning for solving the linear system Ax = b with
+// a nonsymmetric matrix A. It combines ℓ BiCG steps with a minimal residual
+// polynomial of degree ℓ, which helps when the matrix has eigenvalues with
+// large imaginary parts and BiCGStab stagnates.
+//
+// L is the degree ℓ. If it is zero, 2 is used. A cycle of BiCGStab(ℓ)
+// requires 2ℓ matrix-vector products and is followed by a single
+// convergence check, so Stats.Iterations counts the cycles. The iterate is
+// updated once per cycle which costs one extra preconditioner solve.
+//
+// BiCGStab(ℓ) breaks down when r̃ᵀ r_j or r̃ᵀ u_{j+1} is negligible compared
+// to the norms of the vectors, that is, less than BreakdownTolerance times
+// their product, or when the minimal residual polynomial vanishes. If
+// BreakdownTolerance is zero, machine epsilon is used.
+//
+// Badly scaled matrices such as nos7 from the Harwell-Boeing collection need
+// at least a Jacobi preconditioner, without one the iteration stagnates or
+// breaks down.
+type BiCGStabL struct {
+	L                  int
+	BreakdownTolerance float64
+
+	resume int
+	j      int
+	l      int
+
+	rho0, alpha, omega float64
+
+	rt   *mat64.Vector   // Shadow residual r̃.
+	xh   *mat64.Vector   // Update of the preconditioned iterate in the cycle.
+	r, u []*mat64.Vector // r[0] is Context.Residual.
+
+	tau       []float64 // Strictly upper triangular ℓ×ℓ matrix stored by rows.
+	sigma     []float64
+	g, g1, g2 []float64 // γ, γ' and γ'' indexed from 1.
+}
+
+func (b *BiCGStabL) Init(ctx *Context) Operation {
+	b.l = b.L
+	if b.l <= 0 {
+		b.l = 2
+	}
+	if b.BreakdownTolerance == 0 {
+		b.BreakdownTolerance = dlamchE
+	}
+
+	l := b.l
+	dim := ctx.X.Len()
+	if len(b.u) != l+1 || b.u[0].Len() != dim {
+		b.r = make([]*mat64.Vector, l+1)
+		b.u = make([]*mat64.Vector, l+1)
+		for i := range b.u {
+			b.r[i] = mat64.NewVector(dim, nil)
+			b.u[i] = mat64.NewVector(dim, nil)
+		}
+		b.tau = make([]float64, (l+1)*(l+1))
+		b.sigma = make([]float64, l+1)
+		b.g = make([]float64, l+1)
+		b.g1 = make([]float64, l+1)
+		b.g2 = make([]float64, l+1)
+	}
+	b.r[0] = ctx.Residual
+	b.rt = reuseVector(b.rt, dim)
+	b.xh = reuseVector(b.xh, dim)
+	ctx.P = reuseVector(ctx.P, dim)
+	ctx.Ap = reuseVector(ctx.Ap, dim)
+	ctx.Q = reuseVector(ctx.Q, dim)
+	ctx.Z = reuseVector(ctx.Z, dim)
+
+	// r̃ = r_0, u_0 = 0
+	b.rt.CopyVec(ctx.Residual)
+	b.u[0].ScaleVec(0, b.u[0])
+	b.rho0 = 1
+	b.alpha = 0
+	b.omega = 1
+
+	b.resume = 1
+	return b.Iterate(ctx)
+}
+
+func (b *BiCGStabL) Iterate(ctx *Context) Operation {
+	l := b.l
+	r, u := b.r, b.u
+	switch b.resume {
+	case 1:
+		// Start a new cycle.
+		b.xh.ScaleVec(0, b.xh)
+		if b.omega == 0 {
+			ctx.Err = &BreakdownError{Method: "BiCGStab(ℓ)", Quantity: "ω", Value: b.omega}
+			b.resume = 0
+			return CheckConvergence
+		}
+		b.rho0 *= -b.omega
+		b.j = 0
+		fallthrough
+	case 2:
+		// BiCG part.
+		j := b.j
+		// ρ_1 = r̃ · r_j
+		rho1 := mat64.Dot(b.rt, r[j])
+		if negligible(rho1, b.rt, r[j], b.BreakdownTolerance) {
+			ctx.Err = &BreakdownError{Method: "BiCGStab(ℓ)", Quantity: "ρ", Value: rho1}
+			b.resume = 0
+			return CheckConvergence
+		}
+		// β = α ρ_1 / ρ_0
+		beta := b.alpha * rho1 / b.rho0
+		b.rho0 = rho1
+		// u_i = r_i - β u_i, i = 0, ..., j
+		for i := 0; i <= j; i++ {
+			u[i].AddScaledVec(r[i], -beta, u[i])
+		}
+
+		ctx.Q.CopyVec(u[j])
+		b.resume = 3
+		return SolvePreconditionerQ
+		// Solve M û = u_j
+	case 3:
+		ctx.P.CopyVec(ctx.Z)
+		b.resume = 4
+		return ComputeAp
+		// Compute u_{j+1} = A û
+	case 4:
+		j := b.j
+		u[j+1].CopyVec(ctx.Ap)
+		// γ = r̃ · u_{j+1}
+		gamma := mat64.Dot(b.rt, u[j+1])
+		if negligible(gamma, b.rt, u[j+1], b.BreakdownTolerance) {
+			ctx.Err = &BreakdownError{Method: "BiCGStab(ℓ)", Quantity: "γ", Value: gamma}
+			b.resume = 0
+			return CheckConvergence
+		}
+		// α = ρ_0 / γ
+		b.alpha = b.rho0 / gamma
+		// r_i = r_i - α u_{i+1}, i = 0, ..., j
+		for i := 0; i <= j; i++ {
+			r[i].AddScaledVec(r[i], -b.alpha, u[i+1])
+		}
+		// x̂ = x̂ + α u_0
+		b.xh.AddScaledVec(b.xh, b.alpha, u[0])
+
+		ctx.Q.CopyVec(r[j])
+		b.resume = 5
+		return SolvePreconditionerQ
+		// Solve M r̂ = r_j
+	case 5:
+		ctx.P.CopyVec(ctx.Z)
+		b.resume = 6
+		return ComputeAp
+		// Compute r_{j+1} = A r̂
+	case 6:
+		r[b.j+1].CopyVec(ctx.Ap)
+		b.j++
+		if b.j < l {
+			b.resume = 2
+			return b.Iterate(ctx)
+		}
+
+		// MR part: modified Gram-Schmidt orthogonalization of r_1, ..., r_ℓ.
+		tau := b.tau
+		ld := l + 1
+		for j := 1; j <= l; j++ {
+			for i := 1; i < j; i++ {
+				tau[i*ld+j] = mat64.Dot(r[j], r[i]) / b.sigma[i]
+				r[j].AddScaledVec(r[j], -tau[i*ld+j], r[i])
+			}
+			b.sigma[j] = mat64.Dot(r[j], r[j])
+			if b.sigma[j] == 0 {
+				ctx.Err = &BreakdownError{Method: "BiCGStab(ℓ)", Quantity: "σ", Value: b.sigma[j]}
+				b.resume = 0
+				return CheckConvergence
+			}
+			b.g1[j] = mat64.Dot(r[0], r[j]) / b.sigma[j]
+		}
+		b.g[l] = b.g1[l]
+		b.omega = b.g[l]
+		for j := l - 1; j >= 1; j-- {
+			b.g[j] = b.g1[j]
+			for i := j + 1; i <= l; i++ {
+				b.g[j] -= tau[j*ld+i] * b.g[i]
+			}
+		}
+		for j := 1; j < l; j++ {
+			b.g2[j] = b.g[j+1]
+			for i := j + 1; i < l; i++ {
+				b.g2[j] += tau[j*ld+i] * b.g[i+1]
+			}
+		}
+
+		// x̂ = x̂ + γ_1 r_0, r_0 = r_0 - γ'_ℓ r_ℓ, u_0 = u_0 - γ_ℓ u_ℓ
+		b.xh.AddScaledVec(b.xh, b.g[1], r[0])
+		r[0].AddScaledVec(r[0], -b.g1[l], r[l])
+		u[0].AddScaledVec(u[0], -b.g[l], u[l])
+		for j := 1; j < l; j++ {
+			u[0].AddScaledVec(u[0], -b.g[j], u[j])
+			b.xh.AddScaledVec(b.xh, b.g2[j], r[j])
+			r[0].AddScaledVec(r[0], -b.g1[j], r[j])
+		}
+
+		ctx.Q.CopyVec(b.xh)
+		b.resume = 7
+		return SolvePreconditionerQ
+		// Solve M z = x̂
+	case 7:
+		// x = x + z
+		ctx.X.AddVec(ctx.X, ctx.Z)
+
+		b.resume = 1
+		return CheckConvergence
+	default:
+		panic("unreachable")
+	}
+}
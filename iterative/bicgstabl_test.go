@@ -0,0 +1,20 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBiCGStabL(t *testing.T) {
+	for _, l := range []int{0, 1, 2, 4} {
+		name := fmt.Sprintf("BiCGStab(%d)", l)
+		for _, p := range nonsymProblems(t) {
+			testSolve(t, name, p, &BiCGStabL{L: l}, 1e-10)
+		}
+		testNos7(t, name, func() Method { return &BiCGStabL{L: l} })
+	}
+}
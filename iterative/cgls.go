@@ -0,0 +1,145 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"context"
+	"time"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+// CGLS solves the regularized least squares problem
+//
+//  min ‖A x - b‖² + λ² ‖x‖²
+//
+// for a rectangular matrix A by the conjugate gradient method applied to the
+// normal equations
+//
+//  (Aᵀ A + λ² I) x = Aᵀ b
+//
+// without forming Aᵀ A. λ is given by damp. CGLS is mathematically
+// equivalent to LSQR and cheaper per iteration, but less reliable when A is
+// ill-conditioned. Every iteration requires one product with A and one with
+// Aᵀ.
+//
+// The residual checked by the stopping criterion in settings is the
+// residual of the normal equations s = Aᵀ (b - A x) - λ² x, and the
+// right-hand side is Aᵀ b. The preconditioner in settings, if any, must be
+// a symmetric positive definite approximation of Aᵀ A + λ² I. If the
+// problem has many solutions, the one found with a preconditioner need not
+// be the minimum norm one. If settings is nil, DefaultSettings is used with
+// the number of columns of A.
+func CGLS(a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, damp float64) (result Result, err error) {
+	return CGLSContext(context.Background(), a, b, xInit, settings, damp)
+}
+
+// CGLSContext is like CGLS but stops with an error wrapping ErrCanceled
+// when ctx is done. The context is checked after every iteration.
+func CGLSContext(ctx context.Context, a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, damp float64) (result Result, err error) {
+	stats := Stats{
+		StartTime: time.Now(),
+	}
+	x, settings, _ := lsSetup(a, b, xInit, settings, nil)
+	m, n := a.Dims()
+
+	var history []float64
+	defer func() {
+		result = Result{
+			X:       x,
+			Stats:   stats,
+			Runtime: time.Since(stats.StartTime),
+			History: history,
+		}
+	}()
+	if err = initRecorder(settings); err != nil {
+		return result, err
+	}
+	dampsq := damp * damp
+
+	// r = b - A x
+	r := mat64.NewVector(m, nil)
+	r.CopyVec(b)
+	// s = Aᵀ r - λ² x
+	s := mat64.NewVector(n, nil)
+	sparse.MulMatVec(s, 1, true, a, b)
+	stats.MatVecMultiplies++
+	atbNorm := mat64.Norm(s, 2)
+	if xInit != nil {
+		sparse.MulMatVec(r, -1, false, a, x)
+		s.ScaleVec(-dampsq, x)
+		sparse.MulMatVec(s, 1, true, a, r)
+		stats.MatVecMultiplies += 2
+	}
+	z := mat64.NewVector(n, nil)
+	applyPreconditioner(settings, &stats, z, s)
+	p := mat64.NewVector(n, nil)
+	p.CopyVec(z)
+	q := mat64.NewVector(m, nil)
+	gamma := mat64.Dot(s, z)
+
+	stop := stoppingCriterion(settings)
+	state := ConvergenceState{
+		RHSNorm: atbNorm,
+		solutionNorm: func() float64 {
+			return mat64.Norm(x, 2)
+		},
+		precResidualNorm: func() float64 {
+			return mat64.Norm(z, 2)
+		},
+	}
+	if atbNorm == 0 {
+		atbNorm = 1
+	}
+	sNorm := mat64.Norm(s, 2)
+	stats.Residual = sNorm / atbNorm
+	state.next(0, sNorm)
+	stop.Init(&state)
+	if converged, err := stop.Check(&state); converged || err != nil {
+		return result, err
+	}
+
+	for {
+		if gamma <= 0 {
+			// The preconditioner is not positive definite.
+			return result, &BreakdownError{Method: "CGLS", Quantity: "sᵀ M⁻¹ s", Value: gamma}
+		}
+		// q = A p
+		q.ScaleVec(0, q)
+		sparse.MulMatVec(q, 1, false, a, p)
+		stats.MatVecMultiplies++
+		// δ = ‖q‖² + λ² ‖p‖²
+		delta := mat64.Dot(q, q) + dampsq*mat64.Dot(p, p)
+		if delta == 0 {
+			return result, &BreakdownError{Method: "CGLS", Quantity: "δ", Value: delta}
+		}
+		alpha := gamma / delta
+		// x = x + α p, r = r - α q
+		x.AddScaledVec(x, alpha, p)
+		r.AddScaledVec(r, -alpha, q)
+		// s = Aᵀ r - λ² x
+		s.ScaleVec(-dampsq, x)
+		sparse.MulMatVec(s, 1, true, a, r)
+		stats.MatVecMultiplies++
+
+		applyPreconditioner(settings, &stats, z, s)
+		gamma1 := gamma
+		gamma = mat64.Dot(s, z)
+		// p = z + β p
+		p.AddScaledVec(z, gamma/gamma1, p)
+
+		stats.Iterations++
+		sNorm = mat64.Norm(s, 2)
+		stats.Residual = sNorm / atbNorm
+		if err = record(settings, &stats, &history); err != nil {
+			return result, err
+		}
+		state.next(stats.Iterations, sNorm)
+		if done, err := checkStop(ctx, settings, &stats, stop, &state); done {
+			return result, err
+		}
+	}
+}
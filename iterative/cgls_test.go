@@ -0,0 +1,57 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+func TestCGLS(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, dims := range [][2]int{{60, 30}, {300, 100}, {30, 60}, {100, 300}} {
+		m, n := dims[0], dims[1]
+		a := randRect(m, n, rnd)
+		b := mat64.NewVector(m, nil)
+		for i := 0; i < m; i++ {
+			b.SetVec(i, rnd.NormFloat64())
+		}
+		for _, damp := range []float64{0, 0.3} {
+			want := lsReference(t, a, b, damp)
+			_, cols := squaredNorms(a, damp)
+			for _, precon := range []Preconditioner{nil, cols} {
+				if precon != nil && m < n && damp == 0 {
+					// The problem has many solutions and the
+					// preconditioned one need not be the minimum norm
+					// one.
+					continue
+				}
+				settings := DefaultSettings(n)
+				settings.Tolerance = 1e-12
+				settings.Preconditioner = precon
+				result, err := CGLS(a, b, nil, settings, damp)
+				if err != nil {
+					t.Errorf("%v, damp %v, preconditioned %v: unexpected error: %v", dims, damp, precon != nil, err)
+					continue
+				}
+				if d := relDiff(result.X, want); d > 1e-8 {
+					t.Errorf("%v, damp %v, preconditioned %v: solution differs from LSQR by %v", dims, damp, precon != nil, d)
+				}
+				_, gNorm := lsResiduals(a, b, result.X, damp)
+				atb := mat64.NewVector(n, nil)
+				sparse.MulMatVec(atb, 1, true, a, b)
+				if res := gNorm / mat64.Norm(atb, 2); res > 1e-11 {
+					t.Errorf("%v, damp %v, preconditioned %v: normal equations residual %v too large", dims, damp, precon != nil, res)
+				}
+				if precon != nil && result.Stats.PrecondionerSolves == 0 {
+					t.Errorf("%v, damp %v: preconditioner not used", dims, damp)
+				}
+			}
+		}
+	}
+}
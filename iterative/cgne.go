@@ -0,0 +1,138 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"context"
+	"time"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+// CGNE solves the regularized minimum norm problem
+//
+//  min ‖x‖² + ‖y‖²  subject to  A x + λ y = b
+//
+// for a rectangular matrix A by the method of Craig, that is, by the
+// conjugate gradient method applied to
+//
+//  (A Aᵀ + λ² I) y = b,  x = Aᵀ y
+//
+// without forming A Aᵀ. λ is given by damp. If λ is zero and A x = b is
+// consistent, CGNE finds its minimum norm solution, or the solution closest
+// to xInit if it is not nil. Every iteration requires one product with
+// A and one with Aᵀ.
+//
+// The residual checked by the stopping criterion in settings is the
+// residual b - A x - λ² y, which is the residual of A x = b if λ is zero.
+// The preconditioner in settings, if any, must be a symmetric positive
+// definite approximation of A Aᵀ + λ² I. If settings is nil,
+// DefaultSettings is used with the number of columns of A.
+func CGNE(a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, damp float64) (result Result, err error) {
+	return CGNEContext(context.Background(), a, b, xInit, settings, damp)
+}
+
+// CGNEContext is like CGNE but stops with an error wrapping ErrCanceled
+// when ctx is done. The context is checked after every iteration.
+func CGNEContext(ctx context.Context, a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, damp float64) (result Result, err error) {
+	stats := Stats{
+		StartTime: time.Now(),
+	}
+	x, settings, _ := lsSetup(a, b, xInit, settings, nil)
+	m, n := a.Dims()
+
+	var history []float64
+	defer func() {
+		result = Result{
+			X:       x,
+			Stats:   stats,
+			Runtime: time.Since(stats.StartTime),
+			History: history,
+		}
+	}()
+	if err = initRecorder(settings); err != nil {
+		return result, err
+	}
+	dampsq := damp * damp
+
+	// r = b - A x
+	r := mat64.NewVector(m, nil)
+	r.CopyVec(b)
+	if xInit != nil {
+		sparse.MulMatVec(r, -1, false, a, x)
+		stats.MatVecMultiplies++
+	}
+	z := mat64.NewVector(m, nil)
+	applyPreconditioner(settings, &stats, z, r)
+	p := mat64.NewVector(m, nil)
+	p.CopyVec(z)
+	q := mat64.NewVector(m, nil)
+	w := mat64.NewVector(n, nil)
+	gamma := mat64.Dot(r, z)
+
+	bNorm := mat64.Norm(b, 2)
+	stop := stoppingCriterion(settings)
+	state := ConvergenceState{
+		RHSNorm: bNorm,
+		solutionNorm: func() float64 {
+			return mat64.Norm(x, 2)
+		},
+		precResidualNorm: func() float64 {
+			return mat64.Norm(z, 2)
+		},
+	}
+	if bNorm == 0 {
+		bNorm = 1
+	}
+	rNorm := mat64.Norm(r, 2)
+	stats.Residual = rNorm / bNorm
+	state.next(0, rNorm)
+	stop.Init(&state)
+	if converged, err := stop.Check(&state); converged || err != nil {
+		return result, err
+	}
+
+	for {
+		if gamma <= 0 {
+			// The preconditioner is not positive definite.
+			return result, &BreakdownError{Method: "CGNE", Quantity: "rᵀ M⁻¹ r", Value: gamma}
+		}
+		// w = Aᵀ p
+		w.ScaleVec(0, w)
+		sparse.MulMatVec(w, 1, true, a, p)
+		stats.MatVecMultiplies++
+		// δ = ‖w‖² + λ² ‖p‖²
+		delta := mat64.Dot(w, w) + dampsq*mat64.Dot(p, p)
+		if delta == 0 {
+			return result, &BreakdownError{Method: "CGNE", Quantity: "δ", Value: delta}
+		}
+		alpha := gamma / delta
+		// x = x + α w
+		x.AddScaledVec(x, alpha, w)
+		// r = r - α (A w + λ² p)
+		q.ScaleVec(dampsq, p)
+		sparse.MulMatVec(q, 1, false, a, w)
+		stats.MatVecMultiplies++
+		r.AddScaledVec(r, -alpha, q)
+
+		applyPreconditioner(settings, &stats, z, r)
+		gamma1 := gamma
+		gamma = mat64.Dot(r, z)
+		// p = z + β p
+		p.AddScaledVec(z, gamma/gamma1, p)
+
+		stats.Iterations++
+		rNorm = mat64.Norm(r, 2)
+		stats.Residual = rNorm / bNorm
+		if err = record(settings, &stats, &history); err != nil {
+			return result, err
+		}
+		state.next(stats.Iterations, rNorm)
+		if done, err := checkStop(ctx, settings, &stats, stop, &state); done {
+			return result, err
+		}
+	}
+}
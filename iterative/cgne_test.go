@@ -0,0 +1,88 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+func TestCGNE(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, dims := range [][2]int{{30, 60}, {100, 300}, {100, 100}, {60, 30}} {
+		m, n := dims[0], dims[1]
+		a := randRect(m, n, rnd)
+		b := mat64.NewVector(m, nil)
+		for i := 0; i < m; i++ {
+			b.SetVec(i, rnd.NormFloat64())
+		}
+		for _, damp := range []float64{0, 0.3} {
+			if m > n && damp == 0 {
+				// A x = b is inconsistent.
+				continue
+			}
+			// x = Aᵀ (A Aᵀ + λ² I)⁻¹ b is also the solution of the
+			// damped least squares problem, and the minimum norm
+			// solution of A x = b if λ is zero.
+			want := lsReference(t, a, b, damp)
+			rows, _ := squaredNorms(a, damp)
+			for _, precon := range []Preconditioner{nil, rows} {
+				settings := DefaultSettings(n)
+				settings.Tolerance = 1e-12
+				settings.Preconditioner = precon
+				result, err := CGNE(a, b, nil, settings, damp)
+				if err != nil {
+					t.Errorf("%v, damp %v, preconditioned %v: unexpected error: %v", dims, damp, precon != nil, err)
+					continue
+				}
+				if d := relDiff(result.X, want); d > 1e-8 {
+					t.Errorf("%v, damp %v, preconditioned %v: solution differs from LSQR by %v", dims, damp, precon != nil, d)
+				}
+				if damp == 0 {
+					if res := trueResidual(a, b, result.X); res > 1e-11 {
+						t.Errorf("%v, preconditioned %v: relative residual %v too large", dims, precon != nil, res)
+					}
+				}
+				if precon != nil && result.Stats.PrecondionerSolves == 0 {
+					t.Errorf("%v, damp %v: preconditioner not used", dims, damp)
+				}
+			}
+		}
+	}
+}
+
+func TestCGNEInitialGuess(t *testing.T) {
+	// With an initial guess, CGNE finds the solution closest to it, that is,
+	// x0 plus the minimum norm solution of A d = b - A x0.
+	rnd := rand.New(rand.NewSource(1))
+	const m, n = 30, 60
+	a := randRect(m, n, rnd)
+	b := mat64.NewVector(m, nil)
+	x0 := mat64.NewVector(n, nil)
+	for i := 0; i < m; i++ {
+		b.SetVec(i, rnd.NormFloat64())
+	}
+	for j := 0; j < n; j++ {
+		x0.SetVec(j, rnd.NormFloat64())
+	}
+	r0 := mat64.NewVector(m, nil)
+	r0.CopyVec(b)
+	sparse.MulMatVec(r0, -1, false, a, x0)
+	want := lsReference(t, a, r0, 0)
+	want.AddVec(want, x0)
+
+	settings := DefaultSettings(n)
+	settings.Tolerance = 1e-12
+	result, err := CGNE(a, b, x0, settings, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := relDiff(result.X, want); d > 1e-8 {
+		t.Errorf("solution differs from the closest one by %v", d)
+	}
+}
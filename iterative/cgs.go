@@ -0,0 +1,131 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// CGS implements the Conjugate Gradient Squared method with right
+// preconditioning for solving the linear system Ax = b with a nonsymmetric
+// matrix A. Unlike BiCG, CGS does not need products with Aᵀ.
+//
+// CGS breaks down when r̃ᵀ r or r̃ᵀ A p̂ is negligible compared to the norms
+// of the vectors, that is, less than BreakdownTolerance times their
+// product. If BreakdownTolerance is zero, machine epsilon is used.
+type CGS struct {
+	BreakdownTolerance float64
+
+	resume    int
+	first     bool
+	rho, rho1 float64
+	alpha     float64
+
+	rt      *mat64.Vector // Shadow residual r̃.
+	u, p, q *mat64.Vector
+}
+
+func (cgs *CGS) Init(ctx *Context) Operation {
+	if cgs.BreakdownTolerance == 0 {
+		cgs.BreakdownTolerance = dlamchE
+	}
+	cgs.first = true
+
+	dim := ctx.X.Len()
+	cgs.rt = reuseVector(cgs.rt, dim)
+	cgs.u = reuseVector(cgs.u, dim)
+	cgs.p = reuseVector(cgs.p, dim)
+	cgs.q = reuseVector(cgs.q, dim)
+	ctx.P = reuseVector(ctx.P, dim)
+	ctx.Ap = reuseVector(ctx.Ap, dim)
+	ctx.Q = reuseVector(ctx.Q, dim)
+	ctx.Z = reuseVector(ctx.Z, dim)
+
+	// r̃ = r_0
+	cgs.rt.CopyVec(ctx.Residual)
+
+	cgs.resume = 1
+	return cgs.Iterate(ctx)
+}
+
+func (cgs *CGS) Iterate(ctx *Context) Operation {
+	switch cgs.resume {
+	case 1:
+		// ρ_i = r̃ · r_{i-1}
+		cgs.rho = mat64.Dot(cgs.rt, ctx.Residual)
+		if negligible(cgs.rho, cgs.rt, ctx.Residual, cgs.BreakdownTolerance) {
+			ctx.Err = &BreakdownError{Method: "CGS", Quantity: "ρ", Value: cgs.rho}
+			cgs.resume = 0
+			return CheckConvergence
+		}
+		if cgs.first {
+			// u = r_{i-1}, p = u
+			cgs.u.CopyVec(ctx.Residual)
+			cgs.p.CopyVec(cgs.u)
+		} else {
+			// β = ρ_i / ρ_{i-1}
+			beta := cgs.rho / cgs.rho1
+			// u = r_{i-1} + β q
+			cgs.u.AddScaledVec(ctx.Residual, beta, cgs.q)
+			// p = u + β (q + β p)
+			cgs.p.AddScaledVec(cgs.q, beta, cgs.p)
+			cgs.p.AddScaledVec(cgs.u, beta, cgs.p)
+		}
+		cgs.first = false
+
+		ctx.Q.CopyVec(cgs.p)
+		cgs.resume = 2
+		return SolvePreconditionerQ
+		// Solve M p̂ = p
+	case 2:
+		ctx.P.CopyVec(ctx.Z)
+		cgs.resume = 3
+		return ComputeAp
+		// Compute v̂ = A p̂
+	case 3:
+		// σ = r̃ · v̂
+		sigma := mat64.Dot(cgs.rt, ctx.Ap)
+		if negligible(sigma, cgs.rt, ctx.Ap, cgs.BreakdownTolerance) {
+			ctx.Err = &BreakdownError{Method: "CGS", Quantity: "σ", Value: sigma}
+			cgs.resume = 0
+			return CheckConvergence
+		}
+		// α = ρ_i / σ
+		cgs.alpha = cgs.rho / sigma
+		// q = u - α v̂
+		cgs.q.AddScaledVec(cgs.u, -cgs.alpha, ctx.Ap)
+
+		// Q = u + q
+		ctx.Q.AddVec(cgs.u, cgs.q)
+		cgs.resume = 4
+		return SolvePreconditionerQ
+		// Solve M û = u + q
+	case 4:
+		// x_i = x_{i-1} + α û
+		ctx.X.AddScaledVec(ctx.X, cgs.alpha, ctx.Z)
+		ctx.P.CopyVec(ctx.Z)
+		cgs.resume = 5
+		return ComputeAp
+		// Compute q̂ = A û
+	case 5:
+		// r_i = r_{i-1} - α q̂
+		ctx.Residual.AddScaledVec(ctx.Residual, -cgs.alpha, ctx.Ap)
+
+		cgs.rho1 = cgs.rho
+
+		cgs.resume = 1
+		return CheckConvergence
+	default:
+		panic("unreachable")
+	}
+}
+
+// negligible reports whether the dot product of x and y is less than tol
+// times the product of their norms.
+func negligible(dot float64, x, y *mat64.Vector, tol float64) bool {
+	return math.Abs(dot) < tol*mat64.Norm(x, 2)*mat64.Norm(y, 2)
+}
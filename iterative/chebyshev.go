@@ -0,0 +1,228 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Chebyshev implements the Chebyshev iteration for solving the linear system
+// Ax = b where the eigenvalues of M⁻¹ A are real and positive, for example
+// when A and the preconditioner M are symmetric positive definite. Unlike
+// Krylov methods, the Chebyshev iteration computes no inner products, which
+// makes it attractive when they are a synchronization bottleneck. Every
+// iteration requires one matrix-vector product and one preconditioner solve.
+//
+// Min and Max are the bounds on the eigenvalues of M⁻¹ A with
+// 0 < Min < Max. The iteration may diverge if Max is smaller than the
+// largest eigenvalue, while a too large Min only slows down the
+// convergence. If Max is zero, the bounds are estimated in Init by
+// EstimateSteps steps of the Lanczos method, which are not counted as
+// iterations in Stats. The largest Ritz value is increased by 10% to bound
+// the spectrum from above. If EstimateSteps is zero, 20 is used.
+type Chebyshev struct {
+	Min, Max      float64
+	EstimateSteps int
+
+	resume int
+	first  bool
+
+	theta, delta, sigma, rho float64
+
+	// Lanczos process for the estimate of the bounds.
+	steps    int
+	beta     float64
+	alpha    []float64
+	offdiag  []float64
+	r, u, up *mat64.Vector
+}
+
+func (c *Chebyshev) Init(ctx *Context) Operation {
+	dim := ctx.X.Len()
+	ctx.P = reuseVector(ctx.P, dim)
+	ctx.Ap = reuseVector(ctx.Ap, dim)
+	ctx.Z = reuseVector(ctx.Z, dim)
+	c.first = true
+
+	if c.Max != 0 {
+		c.setBounds(c.Min, c.Max)
+		c.resume = 4
+		return SolvePreconditioner
+		// Solve M z = r
+	}
+
+	c.steps = c.EstimateSteps
+	if c.steps <= 0 {
+		c.steps = 20
+	}
+	if c.steps > dim {
+		c.steps = dim
+	}
+	c.alpha = c.alpha[:0]
+	c.offdiag = c.offdiag[:0]
+	c.r = reuseVector(c.r, dim)
+	c.u = reuseVector(c.u, dim)
+	c.up = reuseVector(c.up, dim)
+	ctx.Q = reuseVector(ctx.Q, dim)
+	ctx.Aq = reuseVector(ctx.Aq, dim)
+
+	// The Lanczos process starts from the initial residual.
+	c.r.CopyVec(ctx.Residual)
+	ctx.Q.CopyVec(c.r)
+	c.resume = 1
+	return SolvePreconditionerQ
+	// Solve M z = q
+}
+
+func (c *Chebyshev) Iterate(ctx *Context) Operation {
+	switch c.resume {
+	case 1:
+		// β² = rᵀ M⁻¹ r
+		beta2 := mat64.Dot(c.r, ctx.Z)
+		if beta2 <= 0 || len(c.alpha) == c.steps {
+			if len(c.alpha) == 0 {
+				ctx.Err = &BreakdownError{Method: "Chebyshev", Quantity: "rᵀ M⁻¹ r", Value: beta2}
+				return CheckConvergence
+			}
+			// The Lanczos process has found an invariant subspace or
+			// has done the requested number of steps.
+			lmin, lmax := tridiagBounds(c.alpha, c.offdiag)
+			if lmin <= 0 {
+				// M⁻¹ A is not positive definite.
+				ctx.Err = &BreakdownError{Method: "Chebyshev", Quantity: "smallest Ritz value", Value: lmin}
+				return CheckConvergence
+			}
+			c.setBounds(lmin, 1.1*lmax)
+			c.resume = 4
+			return SolvePreconditioner
+			// Solve M z = r
+		}
+		c.beta = math.Sqrt(beta2)
+		if len(c.alpha) > 0 {
+			c.offdiag = append(c.offdiag, c.beta)
+		}
+		// q = M⁻¹ r / β is the Lanczos vector of M⁻¹ A and u = r / β = M q.
+		ctx.Q.ScaleVec(1/c.beta, ctx.Z)
+		c.u, c.up = c.up, c.u
+		c.u.ScaleVec(1/c.beta, c.r)
+		c.resume = 2
+		return ComputeAq
+		// Compute Aq
+	case 2:
+		// α = qᵀ A q
+		alpha := mat64.Dot(ctx.Q, ctx.Aq)
+		c.alpha = append(c.alpha, alpha)
+		// r = A q - α u - β u_prev
+		c.r.AddScaledVec(ctx.Aq, -alpha, c.u)
+		if len(c.alpha) > 1 {
+			c.r.AddScaledVec(c.r, -c.beta, c.up)
+		}
+		ctx.Q.CopyVec(c.r)
+		c.resume = 1
+		return SolvePreconditionerQ
+		// Solve M z = q
+	case 3:
+		c.resume = 4
+		return SolvePreconditioner
+		// Solve M z = r
+	case 4:
+		if c.first {
+			// p = z / θ
+			ctx.P.ScaleVec(1/c.theta, ctx.Z)
+			c.first = false
+		} else {
+			rho := 1 / (2*c.sigma - c.rho)
+			// p = ρ_new ρ p + 2 ρ_new / δ z
+			ctx.P.ScaleVec(rho*c.rho, ctx.P)
+			ctx.P.AddScaledVec(ctx.P, 2*rho/c.delta, ctx.Z)
+			c.rho = rho
+		}
+		// x = x + p
+		ctx.X.AddVec(ctx.X, ctx.P)
+		c.resume = 5
+		return ComputeAp
+		// Compute Ap
+	case 5:
+		// r = r - Ap
+		ctx.Residual.SubVec(ctx.Residual, ctx.Ap)
+		c.resume = 3
+		return CheckConvergence
+	default:
+		panic("unreachable")
+	}
+}
+
+func (c *Chebyshev) setBounds(min, max float64) {
+	if !(0 < min && min < max) {
+		panic("iterative: invalid eigenvalue bounds")
+	}
+	c.theta = (max + min) / 2
+	c.delta = (max - min) / 2
+	c.sigma = c.theta / c.delta
+	c.rho = 1 / c.sigma
+}
+
+// tridiagBounds returns the smallest and the largest eigenvalue of the
+// symmetric tridiagonal matrix with the diagonal d and the off-diagonal e.
+// The eigenvalues are found by bisection with Sturm sequences.
+func tridiagBounds(d, e []float64) (min, max float64) {
+	// Gershgorin bounds on the spectrum.
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i, v := range d {
+		var radius float64
+		if i > 0 {
+			radius += math.Abs(e[i-1])
+		}
+		if i < len(e) {
+			radius += math.Abs(e[i])
+		}
+		lo = math.Min(lo, v-radius)
+		hi = math.Max(hi, v+radius)
+	}
+	min = tridiagBisect(d, e, lo, hi, 1)
+	max = tridiagBisect(d, e, lo, hi, len(d))
+	return min, max
+}
+
+// tridiagBisect returns the k-th smallest eigenvalue of the symmetric
+// tridiagonal matrix with the diagonal d and the off-diagonal e that lies in
+// the interval [lo, hi].
+func tridiagBisect(d, e []float64, lo, hi float64, k int) float64 {
+	for hi-lo > 2*dlamchE*math.Max(math.Abs(lo), math.Abs(hi)) {
+		mid := lo + (hi-lo)/2
+		if mid == lo || mid == hi {
+			break
+		}
+		if sturmCount(d, e, mid) >= k {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return lo + (hi-lo)/2
+}
+
+// sturmCount returns the number of eigenvalues smaller than x of the
+// symmetric tridiagonal matrix with the diagonal d and the off-diagonal e.
+func sturmCount(d, e []float64, x float64) int {
+	var count int
+	var q float64
+	for i, v := range d {
+		if i == 0 {
+			q = v - x
+		} else {
+			q = v - x - e[i-1]*e[i-1]/q
+		}
+		if q == 0 {
+			q = dlamchE * math.Max(math.Abs(x), 1)
+		}
+		if q < 0 {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,99 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestTridiagBounds(t *testing.T) {
+	// The eigenvalues of the 1D Laplacian are 2 - 2 cos(kπ/(n+1)).
+	const n = 10
+	d := make([]float64, n)
+	e := make([]float64, n-1)
+	for i := range d {
+		d[i] = 2
+	}
+	for i := range e {
+		e[i] = -1
+	}
+	min, max := tridiagBounds(d, e)
+	wantMin := 2 - 2*math.Cos(math.Pi/(n+1))
+	wantMax := 2 - 2*math.Cos(n*math.Pi/(n+1))
+	if math.Abs(min-wantMin) > 1e-12 || math.Abs(max-wantMax) > 1e-12 {
+		t.Errorf("want bounds %v, %v, got %v, %v", wantMin, wantMax, min, max)
+	}
+	min, max = tridiagBounds([]float64{3}, nil)
+	if min != 3 || max != 3 {
+		t.Errorf("1×1: want bounds 3, 3, got %v, %v", min, max)
+	}
+}
+
+func TestChebyshev(t *testing.T) {
+	for _, n := range []int{3, 10} {
+		a := lap2D(n)
+		b, want := rhs(a)
+		for _, test := range []struct {
+			name   string
+			method *Chebyshev
+			precon Preconditioner
+		}{
+			{"exact", &Chebyshev{Min: lap2DMin(n), Max: lap2DMax(n)}, nil},
+			{"estimated", &Chebyshev{}, nil},
+			{"estimated/50", &Chebyshev{EstimateSteps: 50}, nil},
+			{"estimated/Jacobi", &Chebyshev{}, NewJacobi(a)},
+			{"estimated/SSOR", &Chebyshev{}, NewSSOR(a, 1.5)},
+		} {
+			settings := DefaultSettings(b.Len())
+			settings.Tolerance = 1e-10
+			settings.Iterations = 2000
+			settings.Preconditioner = test.precon
+			result, err := Solve(a, b, nil, settings, test.method)
+			if err != nil {
+				t.Errorf("%d/%s: unexpected error: %v", n, test.name, err)
+				continue
+			}
+			for i := 0; i < b.Len(); i++ {
+				if math.Abs(result.X.At(i, 0)-want.At(i, 0)) > 1e-7 {
+					t.Errorf("%d/%s: unexpected solution at %d: want %v, got %v", n, test.name, i, want.At(i, 0), result.X.At(i, 0))
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestChebyshevPolynomialNos7(t *testing.T) {
+	// With an upper bound below the spectrum, p(A) is indefinite and the
+	// Chebyshev iteration diverges.
+	a := readMatrix(t, "nos7.mtx.gz")
+	b, _ := rhs(a)
+	settings := DefaultSettings(b.Len())
+	settings.Tolerance = 1e-8
+	settings.Iterations = 500
+	settings.Preconditioner = NewChebyshevPolynomial(a, 4, 0, 0)
+	settings.Stop = Or(RelativeResidual{Tolerance: 1e-8}, &Divergence{})
+	result, err := Solve(a, b, nil, settings, &Chebyshev{})
+	if err != nil && err != ErrIterationLimit {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res := trueResidual(a, b, result.X); !(res < 1e-2) {
+		t.Errorf("true relative residual %v not below 1e-2", res)
+	}
+}
+
+func TestChebyshevIndefinite(t *testing.T) {
+	a := diagMatrix([]float64{1, -2, 3})
+	b := mat64.NewVector(3, []float64{1, 1, 1})
+	_, err := Solve(a, b, nil, nil, &Chebyshev{})
+	var be *BreakdownError
+	if !errors.As(err, &be) || be.Method != "Chebyshev" {
+		t.Errorf("want Chebyshev breakdown, got %v", err)
+	}
+}
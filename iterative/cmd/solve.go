@@ -1,16 +1,13 @@
 package main
 
 import (
-	"bufio"
 	"compress/gzip"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path"
-	"strconv"
 	"strings"
 
 	"github.com/gonum/blas/blas64"
@@ -21,6 +18,7 @@ import (
 )
 
 func main() {
+	verbose := flag.Bool("v", false, "log the residual after every iteration")
 	flag.Parse()
 	if flag.NArg() == 0 {
 		log.Fatal("missing file name")
@@ -48,10 +46,10 @@ func main() {
 	// blas64.Use(cgo.Implementation{})
 	blas64.Use(native.Implementation{})
 
-	var aDok *sparse.DOK
+	var a *sparse.SymCSR
 	switch path.Ext(name) {
 	case ".mtx":
-		aDok, err = readMatrixMarket(r)
+		a, err = sparse.ReadSymMatrixMarket(r)
 	case ".rsa":
 		log.Fatal("reading of Harwell-Boeing format not yet implemented")
 	default:
@@ -61,7 +59,6 @@ func main() {
 		log.Fatal(err)
 	}
 
-	a := sparse.NewCSR(aDok)
 	n, _ := a.Dims()
 
 	// Create the right-hand side so that the solution is [1 1 ... 1].
@@ -73,7 +70,11 @@ func main() {
 	bVec := mat64.NewVector(n, make([]float64, n))
 	sparse.MulMatVec(bVec, 1, false, a, xVec)
 
-	result, err := iterative.Solve(a, bVec, nil, nil, &iterative.CG{})
+	settings := iterative.DefaultSettings(n)
+	if *verbose {
+		settings.Recorder = &iterative.LogRecorder{}
+	}
+	result, err := iterative.Solve(a, bVec, nil, settings, &iterative.CG{})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -83,74 +84,3 @@ func main() {
 		fmt.Println("Solution:", result.X.RawVector())
 	}
 }
-
-func readMatrixMarket(r io.Reader) (*sparse.DOK, error) {
-	s := bufio.NewScanner(r)
-	s.Scan()
-	line := s.Text()
-	if line != "%%MatrixMarket matrix coordinate real symmetric" {
-		return nil, errors.New("matrix not symmetric")
-	}
-
-	for s.Scan() {
-		line = s.Text()
-		if !strings.HasPrefix(line, "%") {
-			break
-		}
-	}
-	if err := s.Err(); err != nil {
-		return nil, err
-	}
-
-	fields := strings.Fields(line)
-	rows, err := strconv.Atoi(fields[0])
-	if err != nil {
-		return nil, err
-	}
-	cols, err := strconv.Atoi(fields[1])
-	if err != nil {
-		return nil, err
-	}
-	if rows != cols {
-		return nil, errors.New("matrix is not square")
-	}
-	nnz, err := strconv.Atoi(fields[2])
-	if err != nil {
-		return nil, err
-	}
-
-	a := sparse.NewDOK(rows, cols)
-	var count int
-	for s.Scan() {
-		line = s.Text()
-		fields := strings.Fields(line)
-
-		i, err := strconv.Atoi(fields[0])
-		if err != nil {
-			return nil, err
-		}
-		j, err := strconv.Atoi(fields[1])
-		if err != nil {
-			return nil, err
-		}
-		v, err := strconv.ParseFloat(fields[2], 64)
-		if err != nil {
-			return nil, err
-		}
-
-		a.InsertEntry(i-1, j-1, v)
-		if i != j {
-			a.InsertEntry(j-1, i-1, v)
-		}
-		count++
-	}
-	if err := s.Err(); err != nil {
-		return nil, err
-	}
-
-	if count != nnz {
-		return nil, errors.New("mismatched number of non-zeros")
-	}
-
-	return a, nil
-}
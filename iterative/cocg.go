@@ -0,0 +1,86 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import "math/cmplx"
+
+// COCG implements the Conjugate Orthogonal Conjugate Gradient method for
+// solving the linear system Ax = b with a complex symmetric (non-Hermitian)
+// matrix A, that is, A = Aᵀ. It is CG with the unconjugated bilinear form
+// xᵀy in place of the inner product.
+//
+// The bilinear form is not definite, so COCG breaks down when rᵀ z or pᵀ A p
+// is negligible compared to the norms of the vectors. The breakdown is
+// reported as a *BreakdownError with the modulus of the vanished value.
+type COCG struct {
+	first     bool
+	resume    int
+	rho, rho1 complex128
+}
+
+func (cocg *COCG) Init(ctx *ComplexContext) Operation {
+	cocg.first = true
+	cocg.rho = cmplx.NaN()
+	cocg.rho1 = cmplx.NaN()
+
+	dim := len(ctx.X)
+	ctx.P = reuseComplex(ctx.P, dim)
+	ctx.Ap = reuseComplex(ctx.Ap, dim)
+	ctx.Z = reuseComplex(ctx.Z, dim)
+
+	cocg.resume = 2
+	return SolvePreconditioner
+	// Solve M z = r_{i-1}
+}
+
+func (cocg *COCG) Iterate(ctx *ComplexContext) Operation {
+	switch cocg.resume {
+	case 1:
+		cocg.resume = 2
+		return SolvePreconditioner
+		// Solve M z = r_{i-1}
+	case 2:
+		// ρ_i = r_{i-1}ᵀ z
+		cocg.rho = zdotu(ctx.Residual, ctx.Z)
+		if negligibleComplex(cocg.rho, ctx.Residual, ctx.Z) {
+			ctx.Err = &BreakdownError{Method: "COCG", Quantity: "ρ", Value: cmplx.Abs(cocg.rho)}
+			cocg.resume = 0
+			return CheckConvergence
+		}
+		if !cocg.first {
+			// β = ρ_i / ρ_{i-1}
+			beta := cocg.rho / cocg.rho1
+			// z = z + β p_{i-1}
+			zaxpy(beta, ctx.P, ctx.Z)
+		}
+		cocg.first = false
+		// p_i = z
+		copy(ctx.P, ctx.Z)
+
+		cocg.resume = 3
+		return ComputeAp
+		// Compute Ap
+	case 3:
+		sigma := zdotu(ctx.P, ctx.Ap)
+		if negligibleComplex(sigma, ctx.P, ctx.Ap) {
+			ctx.Err = &BreakdownError{Method: "COCG", Quantity: "pᵀ A p", Value: cmplx.Abs(sigma)}
+			cocg.resume = 0
+			return CheckConvergence
+		}
+		// α = ρ_i / (p_iᵀ Ap_i)
+		alpha := cocg.rho / sigma
+		// x_i = x_{i-1} + α p_i
+		zaxpy(alpha, ctx.P, ctx.X)
+		// r_i = r_{i-1} - α Ap_i
+		zaxpy(-alpha, ctx.Ap, ctx.Residual)
+
+		cocg.rho1 = cocg.rho
+
+		cocg.resume = 1
+		return CheckConvergence
+	default:
+		panic("unreachable")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import "math/cmplx"
+
+// COCR implements the Conjugate Orthogonal Conjugate Residual method for
+// solving the linear system Ax = b with a complex symmetric (non-Hermitian)
+// matrix A. It is the Conjugate Residual method with the unconjugated
+// bilinear form xᵀy and often converges more smoothly than COCG. COCR does
+// not use a preconditioner.
+//
+// COCR breaks down when rᵀ A r or (Ap)ᵀ Ap is negligible compared to the
+// norms of the vectors. The breakdown is reported as a *BreakdownError with
+// the modulus of the vanished value.
+type COCR struct {
+	resume int
+	rho    complex128
+}
+
+func (cocr *COCR) Init(ctx *ComplexContext) Operation {
+	cocr.rho = cmplx.NaN()
+
+	dim := len(ctx.X)
+	ctx.P = reuseComplex(ctx.P, dim)
+	ctx.Ap = reuseComplex(ctx.Ap, dim)
+	ctx.Q = reuseComplex(ctx.Q, dim)
+	ctx.Aq = reuseComplex(ctx.Aq, dim)
+
+	// q = r_0
+	copy(ctx.Q, ctx.Residual)
+	cocr.resume = 1
+	return ComputeAq
+	// Compute A r_0
+}
+
+func (cocr *COCR) Iterate(ctx *ComplexContext) Operation {
+	switch cocr.resume {
+	case 1:
+		// ρ_0 = r_0ᵀ A r_0
+		cocr.rho = zdotu(ctx.Residual, ctx.Aq)
+		if negligibleComplex(cocr.rho, ctx.Residual, ctx.Aq) {
+			ctx.Err = &BreakdownError{Method: "COCR", Quantity: "ρ", Value: cmplx.Abs(cocr.rho)}
+			cocr.resume = 0
+			return CheckConvergence
+		}
+		// p_0 = r_0
+		copy(ctx.P, ctx.Residual)
+		// Ap_0 = A r_0
+		copy(ctx.Ap, ctx.Aq)
+		fallthrough
+	case 2:
+		sigma := zdotu(ctx.Ap, ctx.Ap)
+		if negligibleComplex(sigma, ctx.Ap, ctx.Ap) {
+			ctx.Err = &BreakdownError{Method: "COCR", Quantity: "(Ap)ᵀ Ap", Value: cmplx.Abs(sigma)}
+			cocr.resume = 0
+			return CheckConvergence
+		}
+		// α = ρ_i / (Ap_iᵀ Ap_i)
+		alpha := cocr.rho / sigma
+		// x_{i+1} = x_i + α p_i
+		zaxpy(alpha, ctx.P, ctx.X)
+		// r_{i+1} = r_i - α Ap_i
+		zaxpy(-alpha, ctx.Ap, ctx.Residual)
+
+		cocr.resume = 3
+		return CheckConvergence
+	case 3:
+		// q = r_{i+1}
+		copy(ctx.Q, ctx.Residual)
+		cocr.resume = 4
+		return ComputeAq
+		// Compute A r_{i+1}
+	case 4:
+		// ρ_{i+1} = r_{i+1}ᵀ A r_{i+1}
+		rho := zdotu(ctx.Residual, ctx.Aq)
+		if negligibleComplex(rho, ctx.Residual, ctx.Aq) {
+			ctx.Err = &BreakdownError{Method: "COCR", Quantity: "ρ", Value: cmplx.Abs(rho)}
+			cocr.resume = 0
+			return CheckConvergence
+		}
+		// β = ρ_{i+1} / ρ_i
+		beta := rho / cocr.rho
+		cocr.rho = rho
+		// p_{i+1} = r_{i+1} + β p_i
+		zscal(beta, ctx.P)
+		zaxpy(1, ctx.Residual, ctx.P)
+		// Ap_{i+1} = A r_{i+1} + β Ap_i
+		zscal(beta, ctx.Ap)
+		zaxpy(1, ctx.Aq, ctx.Ap)
+
+		cocr.resume = 2
+		return NoOperation
+	default:
+		panic("unreachable")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"context"
+	"math"
+	"math/cmplx"
+	"time"
+
+	"github.com/vladimir-ch/sparse"
+)
+
+// ComplexMethod is an iterative method for complex linear systems. It
+// communicates with SolveComplex in the same way as Method does with Solve.
+type ComplexMethod interface {
+	Init(*ComplexContext) Operation
+	Iterate(*ComplexContext) Operation
+}
+
+type ComplexResult struct {
+	X       []complex128
+	Stats   Stats
+	Runtime time.Duration
+
+	// History holds the relative residual after every iteration if
+	// Settings.History is true.
+	History []float64
+}
+
+// ComplexContext holds the vectors shared between SolveComplex and
+// a ComplexMethod.
+type ComplexContext struct {
+	X        []complex128
+	Residual []complex128
+	P        []complex128
+	Ap       []complex128
+	Q        []complex128
+	Aq       []complex128
+	Z        []complex128
+
+	// ResidualNorm is the norm of the residual for methods that do not
+	// update Residual in every iteration. It is treated as in Context.
+	ResidualNorm float64
+
+	// Err is set by a method that cannot continue, for example after
+	// a breakdown. SolveComplex stops with Err when CheckConvergence is
+	// requested.
+	Err error
+}
+
+// SolveComplex solves the complex linear system A * x = b with the given
+// method, starting from xInit. If xInit is nil, the initial guess is zero.
+// Preconditioning is not supported, SolveComplex panics if
+// settings.Preconditioner is not nil.
+func SolveComplex(a sparse.LinearOperatorOf[complex128], b, xInit []complex128, settings *Settings, method ComplexMethod) (result ComplexResult, err error) {
+	return SolveComplexContext(context.Background(), a, b, xInit, settings, method)
+}
+
+// SolveComplexContext is like SolveComplex but stops with an error wrapping
+// ErrCanceled when ctx is done, as SolveContext does. If it returns an
+// error, ComplexResult holds the iterate with the smallest residual found so
+// far.
+func SolveComplexContext(ctx context.Context, a sparse.LinearOperatorOf[complex128], b, xInit []complex128, settings *Settings, method ComplexMethod) (result ComplexResult, err error) {
+	stats := Stats{
+		StartTime: time.Now(),
+	}
+
+	dim, c := a.Dims()
+	if dim != c {
+		panic("iterative: matrix is not square")
+	}
+	if xInit != nil && dim != len(xInit) {
+		panic("iterative: mismatched size of the initial guess")
+	}
+	if len(b) != dim {
+		panic("iterative: mismatched size of the right-hand side vector")
+	}
+
+	if settings == nil {
+		settings = DefaultSettings(dim)
+	}
+	if settings.Preconditioner != nil {
+		panic("iterative: preconditioner not supported for complex systems")
+	}
+
+	mctx := ComplexContext{
+		X:            make([]complex128, dim),
+		Residual:     make([]complex128, dim),
+		ResidualNorm: math.NaN(),
+	}
+	if xInit != nil {
+		copy(mctx.X, xInit)
+		// Residual = Ax
+		a.MulVecTo(mctx.Residual, false, mctx.X)
+		stats.MatVecMultiplies++
+	}
+	// Residual = b - Ax
+	zscal(-1, mctx.Residual)
+	zaxpy(1, b, mctx.Residual)
+
+	var history []float64
+	best := &bestIterate[complex128]{residual: math.Inf(1)}
+	err = initRecorder(settings)
+	if err == nil {
+		sys := &complexSystem{
+			method: method,
+			a:      a,
+			b:      b,
+			stats:  &stats,
+			ctx:    &mctx,
+		}
+		err = drive[complex128](ctx, sys, dznrm2(b), settings, &stats, &history, best)
+	}
+
+	x := mctx.X
+	if err != nil && best.better(stats.Residual) {
+		x = best.x
+		stats.Residual = best.residual
+	}
+	result = ComplexResult{
+		X:       x,
+		Stats:   stats,
+		Runtime: time.Since(stats.StartTime),
+		History: history,
+	}
+	return result, err
+}
+
+// complexSystem is the system of SolveComplex.
+type complexSystem struct {
+	method ComplexMethod
+	a      sparse.LinearOperatorOf[complex128]
+	b      []complex128
+	stats  *Stats
+	ctx    *ComplexContext
+}
+
+func (s *complexSystem) init() Operation    { return s.method.Init(s.ctx) }
+func (s *complexSystem) iterate() Operation { return s.method.Iterate(s.ctx) }
+func (s *complexSystem) methodErr() error   { return s.ctx.Err }
+
+func (s *complexSystem) perform(op Operation) {
+	ctx := s.ctx
+	switch op {
+	case ComputeAp:
+		s.a.MulVecTo(ctx.Ap, false, ctx.P)
+		s.stats.MatVecMultiplies++
+
+	case ComputeAq:
+		s.a.MulVecTo(ctx.Aq, false, ctx.Q)
+		s.stats.MatVecMultiplies++
+
+	case SolvePreconditioner:
+		// Z = Residual, there is no preconditioner.
+		copy(ctx.Z, ctx.Residual)
+		s.stats.PrecondionerSolves++
+
+	default:
+		panic("iterative: operation not supported for complex systems")
+	}
+}
+
+func (s *complexSystem) reportedNorm() float64 {
+	rNorm := s.ctx.ResidualNorm
+	s.ctx.ResidualNorm = math.NaN()
+	return rNorm
+}
+
+func (s *complexSystem) residualNorm() float64 {
+	return dznrm2(s.ctx.Residual)
+}
+
+func (s *complexSystem) updateResidual() {
+	// Residual = b - A X
+	s.a.MulVecTo(s.ctx.Residual, false, s.ctx.X)
+	zscal(-1, s.ctx.Residual)
+	zaxpy(1, s.b, s.ctx.Residual)
+}
+
+func (s *complexSystem) precResidualNorm() float64 {
+	// SolveComplex does not use a preconditioner.
+	return dznrm2(s.ctx.Residual)
+}
+
+func (s *complexSystem) solution() []complex128 {
+	return s.ctx.X
+}
+
+func (s *complexSystem) solutionNorm() float64 {
+	return dznrm2(s.ctx.X)
+}
+
+// reuseComplex returns v if it has length n, otherwise it returns a new slice
+// of length n.
+func reuseComplex(v []complex128, n int) []complex128 {
+	if len(v) != n {
+		return make([]complex128, n)
+	}
+	return v
+}
+
+// negligibleComplex reports whether the modulus of the product dot of x and
+// y is less than machine epsilon times the product of their norms.
+func negligibleComplex(dot complex128, x, y []complex128) bool {
+	return cmplx.Abs(dot) < dlamchE*dznrm2(x)*dznrm2(y)
+}
+
+// zdotu returns the unconjugated product xᵀ * y.
+func zdotu(x, y []complex128) (dot complex128) {
+	for i, v := range x {
+		dot += v * y[i]
+	}
+	return dot
+}
+
+// zdotc returns the conjugated product xᴴ * y.
+func zdotc(x, y []complex128) (dot complex128) {
+	for i, v := range x {
+		dot += cmplx.Conj(v) * y[i]
+	}
+	return dot
+}
+
+// zaxpy computes y += alpha * x.
+func zaxpy(alpha complex128, x, y []complex128) {
+	for i, v := range x {
+		y[i] += alpha * v
+	}
+}
+
+// zscal computes x *= alpha.
+func zscal(alpha complex128, x []complex128) {
+	for i := range x {
+		x[i] *= alpha
+	}
+}
+
+func zzero(x []complex128) {
+	for i := range x {
+		x[i] = 0
+	}
+}
+
+// dznrm2 returns the Euclidean norm of x.
+func dznrm2(x []complex128) float64 {
+	var scale, ssq float64 = 0, 1
+	for _, v := range x {
+		for _, a := range [2]float64{math.Abs(real(v)), math.Abs(imag(v))} {
+			if a == 0 {
+				continue
+			}
+			if scale < a {
+				ssq = 1 + ssq*(scale/a)*(scale/a)
+				scale = a
+			} else {
+				ssq += (a / scale) * (a / scale)
+			}
+		}
+	}
+	return scale * math.Sqrt(ssq)
+}
@@ -0,0 +1,189 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"errors"
+	"math/cmplx"
+	"testing"
+
+	"github.com/gonum/blas"
+	"github.com/vladimir-ch/sparse"
+)
+
+// helmholtz returns the complex symmetric matrix of the 1D Helmholtz
+// operator with absorption on n grid points. If nonsym is true, an entry is
+// added to the upper right corner to make the matrix nonsymmetric.
+func helmholtz(n int, nonsym bool) *sparse.CSROf[complex128] {
+	dok := sparse.NewDOKOf[complex128](n, n)
+	for i := 0; i < n; i++ {
+		dok.InsertEntry(i, i, 1.7+0.2i)
+		if i > 0 {
+			dok.InsertEntry(i, i-1, -1)
+		}
+		if i < n-1 {
+			dok.InsertEntry(i, i+1, -1)
+		}
+	}
+	if nonsym {
+		dok.InsertEntry(0, n-1, 0.5i)
+	}
+	return sparse.NewCSR(dok)
+}
+
+func TestSolveComplex(t *testing.T) {
+	const n = 50
+	want := make([]complex128, n)
+	for i := range want {
+		want[i] = complex(1, float64(i)/10)
+	}
+	for _, test := range []struct {
+		name   string
+		nonsym bool
+		method ComplexMethod
+	}{
+		{"COCG", false, &COCG{}},
+		{"COCR", false, &COCR{}},
+		{"GMRES", false, &ComplexGMRES{}},
+		{"GMRES(5)", true, &ComplexGMRES{Restart: 5}},
+		{"GMRES(100)", true, &ComplexGMRES{Restart: 100}},
+	} {
+		a := helmholtz(n, test.nonsym)
+		b := make([]complex128, n)
+		sparse.MulMatVecOf(b, 1, blas.NoTrans, a, want)
+
+		settings := DefaultSettings(n)
+		settings.Tolerance = 1e-10
+		settings.Iterations = 5000
+		result, err := SolveComplex(a, b, nil, settings, test.method)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		// The true residual must agree with the reported one.
+		r := make([]complex128, n)
+		copy(r, b)
+		sparse.MulMatVecOf(r, -1, blas.NoTrans, a, result.X)
+		if res := dznrm2(r) / dznrm2(b); res > 1e-10 {
+			t.Errorf("%s: true relative residual %v too large", test.name, res)
+		}
+		if result.Stats.Residual > 1e-10 {
+			t.Errorf("%s: reported relative residual %v too large", test.name, result.Stats.Residual)
+		}
+		for i, v := range result.X {
+			if cmplx.Abs(v-want[i]) > 1e-7 {
+				t.Errorf("%s: unexpected solution at %d: want %v, got %v", test.name, i, want[i], v)
+				break
+			}
+		}
+	}
+}
+
+func TestSolveComplexPreconditioner(t *testing.T) {
+	a := helmholtz(5, false)
+	b := make([]complex128, 5)
+	settings := DefaultSettings(5)
+	settings.Preconditioner = diagPrecon([]float64{1, 1, 1, 1, 1})
+	defer func() {
+		if recover() == nil {
+			t.Errorf("SolveComplex did not panic with a preconditioner")
+		}
+	}()
+	SolveComplex(a, b, nil, settings, &COCG{})
+}
+
+func TestSolveComplexBreakdown(t *testing.T) {
+	// With A = I and b = (1, i) the bilinear form bᵀ b vanishes, so the
+	// methods based on it break down in the first iteration.
+	dok := sparse.NewDOKOf[complex128](2, 2)
+	dok.InsertEntry(0, 0, 1)
+	dok.InsertEntry(1, 1, 1)
+	a := sparse.NewCSR(dok)
+	b := []complex128{1, 1i}
+	for _, test := range []struct {
+		name   string
+		method ComplexMethod
+	}{
+		{"COCG", &COCG{}},
+		{"COCR", &COCR{}},
+	} {
+		_, err := SolveComplex(a, b, nil, nil, test.method)
+		var be *BreakdownError
+		if !errors.As(err, &be) || !errors.Is(err, ErrBreakdown) {
+			t.Errorf("%s: want breakdown, got %v", test.name, err)
+			continue
+		}
+		if be.Method != test.name {
+			t.Errorf("%s: want method %v in the breakdown error, got %v", test.name, test.name, be.Method)
+		}
+	}
+}
+
+// zeroEstimate is a ComplexMethod that claims a zero residual norm at every
+// convergence check.
+type zeroEstimate struct {
+	ComplexMethod
+}
+
+func (m zeroEstimate) Init(ctx *ComplexContext) Operation {
+	return m.report(ctx, m.ComplexMethod.Init(ctx))
+}
+
+func (m zeroEstimate) Iterate(ctx *ComplexContext) Operation {
+	return m.report(ctx, m.ComplexMethod.Iterate(ctx))
+}
+
+func (m zeroEstimate) report(ctx *ComplexContext, op Operation) Operation {
+	if op == CheckConvergence {
+		ctx.ResidualNorm = 0
+	}
+	return op
+}
+
+// lastRecord is a Recorder that keeps the last recorded statistics.
+type lastRecord struct {
+	stats Stats
+}
+
+func (r *lastRecord) Init() error { return nil }
+
+func (r *lastRecord) Record(s Stats) error {
+	r.stats = s
+	return nil
+}
+
+func TestSolveComplexResidualEstimate(t *testing.T) {
+	const n = 50
+	a := helmholtz(n, false)
+	b := make([]complex128, n)
+	for i := range b {
+		b[i] = 1
+	}
+	rec := &lastRecord{}
+	settings := DefaultSettings(n)
+	settings.Tolerance = 1e-10
+	settings.Recorder = rec
+	result, err := SolveComplex(a, b, nil, settings, zeroEstimate{&COCG{}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Stats.Iterations == 1 {
+		t.Errorf("the zero estimate was accepted without confirmation")
+	}
+	r := make([]complex128, n)
+	copy(r, b)
+	sparse.MulMatVecOf(r, -1, blas.NoTrans, a, result.X)
+	res := dznrm2(r) / dznrm2(b)
+	if res > 1e-10 {
+		t.Errorf("true relative residual %v too large", res)
+	}
+	if result.Stats.Residual == 0 || result.Stats.Residual > 1e-10 {
+		t.Errorf("want the confirmed residual, got %v", result.Stats.Residual)
+	}
+	if rec.stats.Residual != result.Stats.Residual {
+		t.Errorf("want the confirmed residual %v recorded, got %v", result.Stats.Residual, rec.stats.Residual)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// ComplexGMRES implements the restarted Generalized Minimal Residual method
+// GMRES(m) for solving the linear system Ax = b with a general complex matrix
+// A. The Krylov basis is orthogonalized by the modified Gram-Schmidt process
+// and the least-squares problem is solved by complex Givens rotations.
+//
+// The residual is updated explicitly only at restarts, in other iterations
+// the method reports its norm through ComplexContext.ResidualNorm.
+type ComplexGMRES struct {
+	// Restart is the number of iterations after which the method is
+	// restarted. If it is zero, the method is restarted after
+	// min(dim, 30) iterations.
+	Restart int
+
+	resume    int
+	m, j      int
+	breakdown bool
+
+	v  [][]complex128 // Orthonormal basis of the Krylov subspace.
+	h  []complex128   // Hessenberg matrix reduced to upper triangular form, column-major (m+1)×m.
+	cs []float64      // Cosines of the Givens rotations.
+	sn []complex128   // Sines of the Givens rotations.
+	g  []complex128   // Rotated right-hand side of the least-squares problem.
+	y  []complex128
+	x0 []complex128 // Solution at the start of the current cycle.
+}
+
+func (gm *ComplexGMRES) Init(ctx *ComplexContext) Operation {
+	dim := len(ctx.X)
+	gm.m = gm.Restart
+	if gm.m <= 0 {
+		gm.m = 30
+	}
+	if gm.m > dim {
+		gm.m = dim
+	}
+
+	m := gm.m
+	if len(gm.v) != m+1 {
+		gm.v = make([][]complex128, m+1)
+	}
+	for i := range gm.v {
+		gm.v[i] = reuseComplex(gm.v[i], dim)
+	}
+	gm.h = reuseComplex(gm.h, (m+1)*m)
+	if len(gm.cs) != m {
+		gm.cs = make([]float64, m)
+	}
+	gm.sn = reuseComplex(gm.sn, m)
+	gm.g = reuseComplex(gm.g, m+1)
+	gm.y = reuseComplex(gm.y, m)
+	gm.x0 = reuseComplex(gm.x0, dim)
+	ctx.P = reuseComplex(ctx.P, dim)
+	ctx.Ap = reuseComplex(ctx.Ap, dim)
+
+	return gm.startCycle(ctx)
+}
+
+func (gm *ComplexGMRES) Iterate(ctx *ComplexContext) Operation {
+	switch gm.resume {
+	case 1:
+		gm.arnoldi(ctx.Ap)
+		gm.updateX(ctx.X)
+		ctx.ResidualNorm = cmplx.Abs(gm.g[gm.j])
+
+		gm.resume = 2
+		return CheckConvergence
+	case 2:
+		if gm.j < gm.m && !gm.breakdown {
+			copy(ctx.P, gm.v[gm.j])
+			gm.resume = 1
+			return ComputeAp
+			// Compute A v_j
+		}
+		// p = x - x_0
+		copy(ctx.P, ctx.X)
+		zaxpy(-1, gm.x0, ctx.P)
+		gm.resume = 3
+		return ComputeAp
+		// Compute A (x - x_0)
+	case 3:
+		// r = r_0 - A (x - x_0)
+		zaxpy(-1, ctx.Ap, ctx.Residual)
+		return gm.startCycle(ctx)
+	default:
+		panic("unreachable")
+	}
+}
+
+// startCycle starts a new cycle of GMRES from the current residual.
+func (gm *ComplexGMRES) startCycle(ctx *ComplexContext) Operation {
+	gm.j = 0
+	gm.breakdown = false
+	copy(gm.x0, ctx.X)
+
+	beta := dznrm2(ctx.Residual)
+	zzero(gm.g)
+	gm.g[0] = complex(beta, 0)
+	if beta == 0 {
+		// The current iterate is the exact solution.
+		gm.breakdown = true
+		ctx.ResidualNorm = 0
+		gm.resume = 2
+		return CheckConvergence
+	}
+	// v_0 = r / β
+	copy(gm.v[0], ctx.Residual)
+	zscal(complex(1/beta, 0), gm.v[0])
+
+	copy(ctx.P, gm.v[0])
+	gm.resume = 1
+	return ComputeAp
+	// Compute A v_0
+}
+
+// arnoldi extends the Krylov basis by w = A v_j, updates the j-th column of
+// the triangular factor and the rotated right-hand side, and increments j.
+func (gm *ComplexGMRES) arnoldi(w []complex128) {
+	m, j := gm.m, gm.j
+	hj := gm.h[j*(m+1) : (j+1)*(m+1)]
+
+	for i := 0; i <= j; i++ {
+		// h_ij = v_iᴴ w
+		hj[i] = zdotc(gm.v[i], w)
+		// w = w - h_ij v_i
+		zaxpy(-hj[i], gm.v[i], w)
+	}
+	hNorm := dznrm2(w)
+	hj[j+1] = complex(hNorm, 0)
+	if hNorm != 0 {
+		// v_{j+1} = w / h_{j+1,j}
+		copy(gm.v[j+1], w)
+		zscal(complex(1/hNorm, 0), gm.v[j+1])
+	} else {
+		// The Krylov subspace is invariant under A and the current
+		// iterate will be the exact solution.
+		gm.breakdown = true
+	}
+
+	// Apply the previous rotations to the new column.
+	for i := 0; i < j; i++ {
+		tmp := complex(gm.cs[i], 0)*hj[i] + gm.sn[i]*hj[i+1]
+		hj[i+1] = -cmplx.Conj(gm.sn[i])*hj[i] + complex(gm.cs[i], 0)*hj[i+1]
+		hj[i] = tmp
+	}
+
+	// Compute the rotation that eliminates h_{j+1,j}.
+	a, b := hj[j], hj[j+1]
+	aAbs := cmplx.Abs(a)
+	if aAbs == 0 {
+		gm.cs[j] = 0
+		gm.sn[j] = 1
+		hj[j] = b
+	} else {
+		r := math.Hypot(aAbs, cmplx.Abs(b))
+		phase := a / complex(aAbs, 0)
+		gm.cs[j] = aAbs / r
+		gm.sn[j] = phase * cmplx.Conj(b) / complex(r, 0)
+		hj[j] = phase * complex(r, 0)
+	}
+	hj[j+1] = 0
+	gm.g[j+1] = -cmplx.Conj(gm.sn[j]) * gm.g[j]
+	gm.g[j] *= complex(gm.cs[j], 0)
+
+	gm.j++
+}
+
+// updateX sets x = x_0 + V y where y solves the triangular system R y = g
+// of size j.
+func (gm *ComplexGMRES) updateX(x []complex128) {
+	m, j := gm.m, gm.j
+	y := gm.y[:j]
+	for i := j - 1; i >= 0; i-- {
+		sum := gm.g[i]
+		for k := i + 1; k < j; k++ {
+			sum -= gm.h[k*(m+1)+i] * y[k]
+		}
+		y[i] = sum / gm.h[i*(m+1)+i]
+	}
+	copy(x, gm.x0)
+	for i, yi := range y {
+		zaxpy(yi, gm.v[i], x)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// IDRS implements the Induced Dimension Reduction method IDR(s) of van Gijzen
+// and Sonneveld with biorthogonalization and right preconditioning for
+// solving the linear system Ax = b with a nonsymmetric matrix A.
+//
+// S is the dimension of the shadow space. If it is zero, 4 is used. IDR(1)
+// is mathematically equivalent to BiCGStab, larger S usually lowers the
+// number of matrix-vector products at the cost of storing 3S additional
+// vectors. Every step of IDR(s) requires one matrix-vector product and is
+// followed by a convergence check, so a cycle of S+1 steps counts as S+1
+// iterations in Stats.
+//
+// The shadow space is spanned by S orthonormalized random vectors. If Src is
+// nil, a fixed seed is used so that the iteration is reproducible.
+//
+// IDR(s) breaks down when Pᵀ G is singular or when the residual r is
+// orthogonal to A M⁻¹ r. Angle is the smallest cosine of the angle between
+// these two vectors that is accepted when computing ω, a smaller one is
+// replaced by Angle to avoid stagnation. If Angle is zero, 0.7 is used.
+//
+// Badly scaled matrices such as nos7 from the Harwell-Boeing collection need
+// at least a Jacobi preconditioner, without one the iteration stagnates or
+// breaks down.
+type IDRS struct {
+	S     int
+	Src   rand.Source
+	Angle float64
+
+	resume int
+	k      int
+	s      int
+
+	omega float64
+	f, c  []float64
+	m     []float64 // Lower triangular s×s matrix Pᵀ G stored by rows.
+
+	p, g, u []*mat64.Vector
+	v       *mat64.Vector
+}
+
+func (idr *IDRS) Init(ctx *Context) Operation {
+	idr.s = idr.S
+	if idr.s <= 0 {
+		idr.s = 4
+	}
+	if idr.Angle == 0 {
+		idr.Angle = 0.7
+	}
+	src := idr.Src
+	if src == nil {
+		src = rand.NewSource(1)
+	}
+	rnd := rand.New(src)
+
+	s := idr.s
+	dim := ctx.X.Len()
+	if len(idr.p) != s || idr.p[0].Len() != dim {
+		idr.p = make([]*mat64.Vector, s)
+		idr.g = make([]*mat64.Vector, s)
+		idr.u = make([]*mat64.Vector, s)
+		for i := range idr.p {
+			idr.p[i] = mat64.NewVector(dim, nil)
+			idr.g[i] = mat64.NewVector(dim, nil)
+			idr.u[i] = mat64.NewVector(dim, nil)
+		}
+		idr.f = make([]float64, s)
+		idr.c = make([]float64, s)
+		idr.m = make([]float64, s*s)
+	}
+	idr.v = reuseVector(idr.v, dim)
+	ctx.P = reuseVector(ctx.P, dim)
+	ctx.Ap = reuseVector(ctx.Ap, dim)
+	ctx.Q = reuseVector(ctx.Q, dim)
+	ctx.Z = reuseVector(ctx.Z, dim)
+
+	// Orthonormalize the random shadow vectors by the modified Gram-Schmidt
+	// process.
+	for i, pi := range idr.p {
+		for j := 0; j < dim; j++ {
+			pi.SetVec(j, rnd.NormFloat64())
+		}
+		for _, pj := range idr.p[:i] {
+			pi.AddScaledVec(pi, -mat64.Dot(pj, pi), pj)
+		}
+		pi.ScaleVec(1/mat64.Norm(pi, 2), pi)
+	}
+	// G = U = 0, M = I
+	for i := range idr.g {
+		idr.g[i].ScaleVec(0, idr.g[i])
+		idr.u[i].ScaleVec(0, idr.u[i])
+	}
+	for i := range idr.m {
+		idr.m[i] = 0
+	}
+	for i := 0; i < s; i++ {
+		idr.m[i*s+i] = 1
+	}
+	idr.omega = 1
+
+	idr.resume = 1
+	return idr.Iterate(ctx)
+}
+
+func (idr *IDRS) Iterate(ctx *Context) Operation {
+	s := idr.s
+	switch idr.resume {
+	case 1:
+		// f = Pᵀ r
+		for i, pi := range idr.p {
+			idr.f[i] = mat64.Dot(pi, ctx.Residual)
+		}
+		idr.k = 0
+		fallthrough
+	case 2:
+		k := idr.k
+		// Solve M[k:s,k:s] c = f[k:s] by forward substitution.
+		for i := k; i < s; i++ {
+			sum := idr.f[i]
+			for j := k; j < i; j++ {
+				sum -= idr.m[i*s+j] * idr.c[j]
+			}
+			idr.c[i] = sum / idr.m[i*s+i]
+		}
+		// v = r - G[:,k:s] c
+		idr.v.CopyVec(ctx.Residual)
+		for i := k; i < s; i++ {
+			idr.v.AddScaledVec(idr.v, -idr.c[i], idr.g[i])
+		}
+
+		ctx.Q.CopyVec(idr.v)
+		idr.resume = 3
+		return SolvePreconditionerQ
+		// Solve M v̂ = v
+	case 3:
+		k := idr.k
+		// U[:,k] = U[:,k:s] c + ω v̂
+		ctx.P.ScaleVec(idr.omega, ctx.Z)
+		for i := k; i < s; i++ {
+			ctx.P.AddScaledVec(ctx.P, idr.c[i], idr.u[i])
+		}
+		idr.u[k].CopyVec(ctx.P)
+		idr.resume = 4
+		return ComputeAp
+		// Compute G[:,k] = A U[:,k]
+	case 4:
+		k := idr.k
+		gk, uk := idr.g[k], idr.u[k]
+		gk.CopyVec(ctx.Ap)
+		// Make G[:,k] orthogonal to P[:,0:k].
+		for i := 0; i < k; i++ {
+			alpha := mat64.Dot(idr.p[i], gk) / idr.m[i*s+i]
+			gk.AddScaledVec(gk, -alpha, idr.g[i])
+			uk.AddScaledVec(uk, -alpha, idr.u[i])
+		}
+		// M[k:s,k] = P[:,k:s]ᵀ G[:,k]
+		for i := k; i < s; i++ {
+			idr.m[i*s+k] = mat64.Dot(idr.p[i], gk)
+		}
+		mkk := idr.m[k*s+k]
+		if mkk == 0 || math.Abs(mkk) < dlamchE*mat64.Norm(gk, 2) {
+			ctx.Err = &BreakdownError{Method: "IDR(s)", Quantity: "M_kk", Value: mkk}
+			idr.resume = 0
+			return CheckConvergence
+		}
+		// β = f_k / M_kk
+		beta := idr.f[k] / mkk
+		// r = r - β G[:,k], x = x + β U[:,k]
+		ctx.Residual.AddScaledVec(ctx.Residual, -beta, gk)
+		ctx.X.AddScaledVec(ctx.X, beta, uk)
+		// f[k+1:s] = f[k+1:s] - β M[k+1:s,k]
+		for i := k + 1; i < s; i++ {
+			idr.f[i] -= beta * idr.m[i*s+k]
+		}
+
+		idr.k++
+		if idr.k < s {
+			idr.resume = 2
+		} else {
+			idr.resume = 5
+		}
+		return CheckConvergence
+	case 5:
+		// Dimension reduction step.
+		idr.resume = 6
+		return SolvePreconditioner
+		// Solve M v̂ = r
+	case 6:
+		ctx.P.CopyVec(ctx.Z)
+		idr.resume = 7
+		return ComputeAp
+		// Compute t = A v̂
+	case 7:
+		idr.omega = idr.computeOmega(ctx.Ap, ctx.Residual)
+		if idr.omega == 0 {
+			ctx.Err = &BreakdownError{Method: "IDR(s)", Quantity: "ω", Value: idr.omega}
+			idr.resume = 0
+			return CheckConvergence
+		}
+		// r = r - ω t, x = x + ω v̂
+		ctx.Residual.AddScaledVec(ctx.Residual, -idr.omega, ctx.Ap)
+		ctx.X.AddScaledVec(ctx.X, idr.omega, ctx.P)
+
+		idr.resume = 1
+		return CheckConvergence
+	default:
+		panic("unreachable")
+	}
+}
+
+// computeOmega returns ω that minimizes ‖r - ω t‖ unless the angle between
+// t and r is too large, in which case ω is increased so that the cosine of
+// the angle is at least Angle.
+func (idr *IDRS) computeOmega(t, r *mat64.Vector) float64 {
+	tNorm := mat64.Norm(t, 2)
+	rNorm := mat64.Norm(r, 2)
+	tr := mat64.Dot(t, r)
+	if tr == 0 {
+		return 0
+	}
+	omega := tr / (tNorm * tNorm)
+	rho := math.Abs(tr / (tNorm * rNorm))
+	if rho < idr.Angle {
+		omega *= idr.Angle / rho
+	}
+	return omega
+}
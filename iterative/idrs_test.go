@@ -0,0 +1,20 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIDRS(t *testing.T) {
+	for _, s := range []int{0, 1, 2, 8} {
+		name := fmt.Sprintf("IDR(%d)", s)
+		for _, p := range nonsymProblems(t) {
+			testSolve(t, name, p, &IDRS{S: s}, 1e-10)
+		}
+		testNos7(t, name, func() Method { return &IDRS{S: s} })
+	}
+}
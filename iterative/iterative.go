@@ -5,6 +5,7 @@
 package iterative
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -22,8 +23,41 @@ const (
 	ComputeAq
 	SolvePreconditioner
 	CheckConvergence
+	SolvePreconditionerQ
+	ComputeATq
 )
 
+var (
+	// ErrCanceled is returned when the context of SolveContext is done.
+	ErrCanceled = errors.New("iterative: solve canceled")
+	// ErrTimeLimit is returned when Settings.MaxRuntime is exceeded.
+	ErrTimeLimit = errors.New("iterative: reached time limit")
+	// ErrIterationLimit is returned when Settings.Iterations is reached.
+	ErrIterationLimit = errors.New("iterative: reached iteration limit")
+	// ErrBreakdown is returned when the method cannot continue. Methods
+	// that detect a breakdown return a *BreakdownError that matches
+	// ErrBreakdown, a residual that is not finite is reported as
+	// ErrBreakdown itself.
+	ErrBreakdown = errors.New("iterative: method broke down")
+)
+
+// BreakdownError is returned when a method cannot continue because a scalar
+// that it divides by has vanished. It matches ErrBreakdown with errors.Is.
+type BreakdownError struct {
+	Method string
+	// Quantity names the scalar that vanished.
+	Quantity string
+	Value    float64
+}
+
+func (e *BreakdownError) Error() string {
+	return fmt.Sprintf("iterative: %s broke down: %s = %g", e.Method, e.Quantity, e.Value)
+}
+
+func (e *BreakdownError) Is(target error) bool {
+	return target == ErrBreakdown
+}
+
 type Method interface {
 	Init(*Context) Operation
 	Iterate(*Context) Operation
@@ -35,12 +69,19 @@ type Stats struct {
 	PrecondionerSolves int
 	Residual           float64
 	StartTime          time.Time
+	// Runtime is the time elapsed since StartTime when the statistics
+	// were last recorded.
+	Runtime time.Duration
 }
 
 type Result struct {
 	X       *mat64.Vector
 	Stats   Stats
 	Runtime time.Duration
+
+	// History holds the relative residual after every iteration if
+	// Settings.History is true.
+	History []float64
 }
 
 type Context struct {
@@ -51,11 +92,45 @@ type Context struct {
 	Q        *mat64.Vector
 	Aq       *mat64.Vector
 	Z        *mat64.Vector
+
+	// ResidualNorm is the norm of the residual for methods that do not
+	// update Residual in every iteration. If it is NaN when
+	// CheckConvergence is requested, the norm of Residual is used instead.
+	// Otherwise Solve treats it as an estimate and overwrites Residual with
+	// b - A*X when the stopping criterion may report convergence, see
+	// ConvergenceTester, and when it needs the preconditioned residual.
+	// Solve resets it to NaN after every convergence check.
+	ResidualNorm float64
+
+	// Err is set by a method that cannot continue, for example after
+	// a breakdown. Solve stops with Err when CheckConvergence is requested.
+	Err error
 }
 
 type Settings struct {
 	Tolerance  float64
 	Iterations int
+
+	// Preconditioner is used by methods that request SolvePreconditioner
+	// or SolvePreconditionerQ. If it is nil, no preconditioning is done.
+	// SolveComplex does not support preconditioning. Solve ignores it if
+	// the method is itself a Preconditioner.
+	Preconditioner Preconditioner
+
+	// Stop decides when the iteration has converged. If it is nil,
+	// RelativeResidual with Tolerance is used.
+	Stop StoppingCriterion
+
+	// MaxRuntime limits the wall-clock time of the solve measured from
+	// Stats.StartTime. If it is zero, the time is not limited.
+	MaxRuntime time.Duration
+
+	// Recorder receives the statistics after every iteration. If it is
+	// nil, the progress is not reported.
+	Recorder Recorder
+	// History specifies whether the residual history is returned in
+	// Result.
+	History bool
 }
 
 func DefaultSettings(dim int) *Settings {
@@ -65,7 +140,21 @@ func DefaultSettings(dim int) *Settings {
 	}
 }
 
-func Solve(a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, method Method) (result Result, err error) {
+// Solve solves the linear system A * x = b with the given method, starting
+// from xInit. If xInit is nil, the initial guess is zero. The operator A is
+// accessed only through MulVecTo, so it does not need to be stored as
+// a matrix. If the method is also a Preconditioner, as the stationary
+// methods are, it replaces the preconditioner in settings.
+func Solve(a sparse.LinearOperator, b, xInit *mat64.Vector, settings *Settings, method Method) (result Result, err error) {
+	return SolveContext(context.Background(), a, b, xInit, settings, method)
+}
+
+// SolveContext is like Solve but stops with an error wrapping ErrCanceled
+// when ctx is done. The context is checked after every iteration.
+//
+// If SolveContext returns an error, Result holds the iterate with the
+// smallest residual found so far.
+func SolveContext(ctx context.Context, a sparse.LinearOperator, b, xInit *mat64.Vector, settings *Settings, method Method) (result Result, err error) {
 	stats := Stats{
 		StartTime: time.Now(),
 	}
@@ -87,72 +176,326 @@ func Solve(a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, method M
 	if settings == nil {
 		settings = DefaultSettings(dim)
 	}
+	if m, ok := method.(Preconditioner); ok {
+		// The method applies its own preconditioner.
+		s := *settings
+		s.Preconditioner = m
+		settings = &s
+	}
 
-	ctx := Context{
-		X:        mat64.NewVector(dim, nil),
-		Residual: mat64.NewVector(dim, nil),
+	mctx := Context{
+		X:            mat64.NewVector(dim, nil),
+		Residual:     mat64.NewVector(dim, nil),
+		ResidualNorm: math.NaN(),
 	}
 	// X = xInit
-	ctx.X.CopyVec(xInit)
-	if mat64.Norm(ctx.X, math.Inf(1)) > 0 {
+	mctx.X.CopyVec(xInit)
+	if mat64.Norm(mctx.X, math.Inf(1)) > 0 {
 		// Residual = Ax
-		sparse.MulMatVec(ctx.Residual, 1, false, a, ctx.X)
+		mulVec(a, mctx.Residual, mctx.X)
 		stats.MatVecMultiplies++
 	}
-	// Residual = Ax - b
-	ctx.Residual.SubVec(ctx.Residual, b)
+	// Residual = b - Ax
+	mctx.Residual.SubVec(b, mctx.Residual)
 
-	if mat64.Norm(ctx.Residual, 2) >= settings.Tolerance {
-		err = iterate(method, a, b, settings, &ctx, &stats)
+	var history []float64
+	best := &bestIterate[float64]{residual: math.Inf(1)}
+	err = initRecorder(settings)
+	if err == nil {
+		sys := &realSystem{
+			method:   method,
+			a:        a,
+			b:        b,
+			settings: settings,
+			stats:    &stats,
+			ctx:      &mctx,
+		}
+		err = drive[float64](ctx, sys, mat64.Norm(b, 2), settings, &stats, &history, best)
 	}
 
+	x := mctx.X
+	if err != nil && best.better(stats.Residual) {
+		x = mat64.NewVector(dim, best.x)
+		stats.Residual = best.residual
+	}
 	result = Result{
-		X:       ctx.X,
+		X:       x,
 		Stats:   stats,
 		Runtime: time.Since(stats.StartTime),
+		History: history,
 	}
 	return result, err
 }
 
-func iterate(method Method, a sparse.Matrix, b *mat64.Vector, settings *Settings, ctx *Context, stats *Stats) error {
-	bNorm := mat64.Norm(b, 2)
+// system gives drive access to the linear system and to the context of the
+// method, so that real and complex systems share one driver.
+type system[T float64 | complex128] interface {
+	// init and iterate call the Init and Iterate methods of the method.
+	init() Operation
+	iterate() Operation
+	// perform carries out an operation other than CheckConvergence.
+	perform(op Operation)
+	// methodErr returns the error set by the method.
+	methodErr() error
+	// reportedNorm returns the residual norm reported by the method, which
+	// is NaN if there is none, and resets it to NaN.
+	reportedNorm() float64
+	// residualNorm returns the norm of the residual vector.
+	residualNorm() float64
+	// updateResidual overwrites the residual vector with b - A x.
+	updateResidual()
+	// precResidualNorm returns the norm of the preconditioned residual
+	// vector.
+	precResidualNorm() float64
+	// solution returns the current iterate.
+	solution() []T
+	solutionNorm() float64
+}
+
+// drive runs the method until the stopping criterion is satisfied or an
+// error occurs.
+func drive[T float64 | complex128](ctx context.Context, sys system[T], bNorm float64, settings *Settings, stats *Stats, history *[]float64, best *bestIterate[T]) error {
+	stop := stoppingCriterion(settings)
+	// stale is true if the method reported ResidualNorm and so the residual
+	// vector may not be the residual of X.
+	var stale bool
+	updateResidual := func() {
+		sys.updateResidual()
+		stats.MatVecMultiplies++
+		stale = false
+	}
+	state := ConvergenceState{
+		RHSNorm:      bNorm,
+		solutionNorm: sys.solutionNorm,
+		precResidualNorm: func() float64 {
+			if stale {
+				updateResidual()
+			}
+			return sys.precResidualNorm()
+		},
+	}
 	if bNorm == 0 {
 		bNorm = 1
 	}
 
-	op := method.Init(ctx)
+	rNorm := sys.residualNorm()
+	stats.Residual = rNorm / bNorm
+	state.next(0, rNorm)
+	stop.Init(&state)
+	if converged, err := stop.Check(&state); converged || err != nil {
+		return err
+	}
+
+	op := sys.init()
 	for {
 		switch op {
 		case NoOperation:
 
-		case ComputeAp:
-			ctx.Ap.ScaleVec(0, ctx.Ap)
-			sparse.MulMatVec(ctx.Ap, 1, false, a, ctx.P)
-			stats.MatVecMultiplies++
-
-		case ComputeAq:
-			ctx.Aq.ScaleVec(0, ctx.Aq)
-			sparse.MulMatVec(ctx.Aq, 1, false, a, ctx.Q)
-			stats.MatVecMultiplies++
-
-		case SolvePreconditioner:
-			// TODO(vladimir-ch): Add preconditioners.
-			// Z = Residual
-			ctx.Z.CopyVec(ctx.Residual)
-			stats.PrecondionerSolves++
-
 		case CheckConvergence:
+			if err := sys.methodErr(); err != nil {
+				return err
+			}
 			stats.Iterations++
-			stats.Residual = mat64.Norm(ctx.Residual, 2) / bNorm
-			fmt.Println(stats.Residual)
-			if stats.Residual < settings.Tolerance {
-				return nil
+			rNorm := sys.reportedNorm()
+			estimate := !math.IsNaN(rNorm)
+			if !estimate {
+				rNorm = sys.residualNorm()
 			}
-			if stats.Iterations == settings.Iterations {
-				return errors.New("iterative: reached iteration limit")
+			stale = estimate
+			state.next(stats.Iterations, rNorm)
+			if estimate && mayConverge(stop, &state) {
+				// The reported norm is only an estimate, possibly in
+				// another norm, so convergence is checked with the
+				// true residual.
+				if stale {
+					updateResidual()
+				}
+				rNorm = sys.residualNorm()
+				// The cached norms remain valid, the residual
+				// has been refreshed before any of them was
+				// computed.
+				state.ResidualNorm = rNorm
 			}
+			stats.Residual = rNorm / bNorm
+			done, err := checkStop(ctx, settings, stats, stop, &state)
+			if err := record(settings, stats, history); err != nil {
+				return err
+			}
+			if stats.Residual < best.residual {
+				best.update(stats.Residual, sys.solution())
+			}
+			if done {
+				return err
+			}
+
+		default:
+			sys.perform(op)
 		}
 
-		op = method.Iterate(ctx)
+		op = sys.iterate()
+	}
+}
+
+// realSystem is the system of Solve.
+type realSystem struct {
+	method   Method
+	a        sparse.LinearOperator
+	b        *mat64.Vector
+	settings *Settings
+	stats    *Stats
+	ctx      *Context
+	z        *mat64.Vector
+}
+
+func (s *realSystem) init() Operation    { return s.method.Init(s.ctx) }
+func (s *realSystem) iterate() Operation { return s.method.Iterate(s.ctx) }
+func (s *realSystem) methodErr() error   { return s.ctx.Err }
+
+func (s *realSystem) perform(op Operation) {
+	ctx := s.ctx
+	switch op {
+	case ComputeAp:
+		mulVec(s.a, ctx.Ap, ctx.P)
+		s.stats.MatVecMultiplies++
+
+	case ComputeAq:
+		mulVec(s.a, ctx.Aq, ctx.Q)
+		s.stats.MatVecMultiplies++
+
+	case ComputeATq:
+		// Aq = Aᵀ Q
+		s.a.MulVecTo(ctx.Aq.RawVector().Data[:ctx.Aq.Len()], true, ctx.Q.RawVector().Data[:ctx.Q.Len()])
+		s.stats.MatVecMultiplies++
+
+	case SolvePreconditioner:
+		// Z = M⁻¹ Residual
+		applyPreconditioner(s.settings, s.stats, ctx.Z, ctx.Residual)
+
+	case SolvePreconditionerQ:
+		// Z = M⁻¹ Q
+		applyPreconditioner(s.settings, s.stats, ctx.Z, ctx.Q)
+
+	default:
+		panic("iterative: unknown operation")
+	}
+}
+
+func (s *realSystem) reportedNorm() float64 {
+	rNorm := s.ctx.ResidualNorm
+	s.ctx.ResidualNorm = math.NaN()
+	return rNorm
+}
+
+func (s *realSystem) residualNorm() float64 {
+	return mat64.Norm(s.ctx.Residual, 2)
+}
+
+func (s *realSystem) updateResidual() {
+	// Residual = b - A X
+	mulVec(s.a, s.ctx.Residual, s.ctx.X)
+	s.ctx.Residual.SubVec(s.b, s.ctx.Residual)
+}
+
+func (s *realSystem) precResidualNorm() float64 {
+	if s.settings.Preconditioner == nil {
+		return mat64.Norm(s.ctx.Residual, 2)
+	}
+	s.z = reuseVector(s.z, s.ctx.Residual.Len())
+	precondSolve(s.settings.Preconditioner, s.z, s.ctx.Residual)
+	s.stats.PrecondionerSolves++
+	return mat64.Norm(s.z, 2)
+}
+
+func (s *realSystem) solution() []float64 {
+	return s.ctx.X.RawVector().Data[:s.ctx.X.Len()]
+}
+
+func (s *realSystem) solutionNorm() float64 {
+	return mat64.Norm(s.ctx.X, 2)
+}
+
+// dlamchE is the machine epsilon.
+const dlamchE = 1.0 / (1 << 53)
+
+// reuseVector returns v if it has length n, otherwise it returns a new vector
+// of length n.
+func reuseVector(v *mat64.Vector, n int) *mat64.Vector {
+	if v == nil || v.Len() != n {
+		return mat64.NewVector(n, nil)
+	}
+	return v
+}
+
+// applyPreconditioner computes dst = M⁻¹ * r with the preconditioner in
+// settings, or copies r to dst if there is none.
+func applyPreconditioner(settings *Settings, stats *Stats, dst, r *mat64.Vector) {
+	if settings.Preconditioner == nil {
+		dst.CopyVec(r)
+	} else {
+		precondSolve(settings.Preconditioner, dst, r)
+	}
+	stats.PrecondionerSolves++
+}
+
+// precondSolve computes dst = M⁻¹ * r. The vectors must have unit increment.
+func precondSolve(m Preconditioner, dst, r *mat64.Vector) {
+	m.PreconSolve(dst.RawVector().Data[:dst.Len()], r.RawVector().Data[:r.Len()])
+}
+
+// mulVec computes dst = A * x. The vectors must have unit increment, which
+// holds for all vectors allocated by Solve and the methods.
+func mulVec(a sparse.LinearOperator, dst, x *mat64.Vector) {
+	a.MulVecTo(dst.RawVector().Data[:dst.Len()], false, x.RawVector().Data[:x.Len()])
+}
+
+// stoppingCriterion returns the stopping criterion given by settings.
+func stoppingCriterion(settings *Settings) StoppingCriterion {
+	if settings.Stop != nil {
+		return settings.Stop
+	}
+	return RelativeResidual{Tolerance: settings.Tolerance}
+}
+
+// checkStop reports whether the iteration should stop after a convergence
+// check. The returned error is nil if the iteration has converged. An
+// infinite residual is passed to the stopping criterion first, so that
+// Divergence can report it.
+func checkStop(ctx context.Context, settings *Settings, stats *Stats, stop StoppingCriterion, state *ConvergenceState) (bool, error) {
+	if math.IsNaN(stats.Residual) {
+		return true, ErrBreakdown
+	}
+	if converged, err := stop.Check(state); converged || err != nil {
+		return true, err
+	}
+	switch {
+	case math.IsInf(stats.Residual, 0):
+		return true, ErrBreakdown
+	case ctx.Err() != nil:
+		return true, fmt.Errorf("%w: %v", ErrCanceled, ctx.Err())
+	case settings.MaxRuntime > 0 && time.Since(stats.StartTime) >= settings.MaxRuntime:
+		return true, ErrTimeLimit
+	case stats.Iterations == settings.Iterations:
+		return true, ErrIterationLimit
+	}
+	return false, nil
+}
+
+// bestIterate keeps a copy of the iterate with the smallest residual.
+type bestIterate[T float64 | complex128] struct {
+	residual float64
+	x        []T
+}
+
+// better reports whether the kept iterate is better than the current one
+// with the given residual.
+func (b *bestIterate[T]) better(residual float64) bool {
+	return b.x != nil && !(residual <= b.residual)
+}
+
+func (b *bestIterate[T]) update(residual float64, x []T) {
+	b.residual = residual
+	if b.x == nil {
+		b.x = make([]T, len(x))
 	}
+	copy(b.x, x)
 }
@@ -0,0 +1,389 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+// diagPrecon is the preconditioner M = diag(d).
+type diagPrecon []float64
+
+func (d diagPrecon) PreconSolve(dst, r []float64) {
+	for i, v := range r {
+		dst[i] = v / d[i]
+	}
+}
+
+// lap2D returns the matrix of the 5-point Laplacian on the n×n grid.
+func lap2D(n int) *sparse.CSR {
+	dok := sparse.NewDOK(n*n, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			k := i*n + j
+			dok.InsertEntry(k, k, 4)
+			if i > 0 {
+				dok.InsertEntry(k, k-n, -1)
+			}
+			if i < n-1 {
+				dok.InsertEntry(k, k+n, -1)
+			}
+			if j > 0 {
+				dok.InsertEntry(k, k-1, -1)
+			}
+			if j < n-1 {
+				dok.InsertEntry(k, k+1, -1)
+			}
+		}
+	}
+	return sparse.NewCSR(dok)
+}
+
+// shiftedLap2D returns the matrix of the 5-point Laplacian on the n×n grid
+// shifted by -σ I. For σ between the extreme eigenvalues, 8 sin²(π/(2(n+1)))
+// and 8 cos²(π/(2(n+1))), it is symmetric indefinite.
+func shiftedLap2D(n int, sigma float64) *sparse.CSR {
+	dok := sparse.NewDOK(n*n, n*n)
+	lap2D(n).DoNonZero(func(i, j int, v float64) {
+		if i == j {
+			v -= sigma
+		}
+		dok.InsertEntry(i, j, v)
+	})
+	return sparse.NewCSR(dok)
+}
+
+// saddle returns the symmetric indefinite saddle point matrix
+//
+//  [A  Bᵀ]
+//  [B  0 ]
+//
+// where A is the 5-point Laplacian on the n×n grid and B has m < n*n/2 rows
+// of discrete differences of disjoint pairs of unknowns, so it has full
+// rank.
+func saddle(n, m int) *sparse.CSR {
+	dim := n * n
+	dok := sparse.NewDOK(dim+m, dim+m)
+	lap2D(n).DoNonZero(func(i, j int, v float64) {
+		dok.InsertEntry(i, j, v)
+	})
+	for k := 0; k < m; k++ {
+		i := dim + k
+		dok.InsertEntry(i, 2*k, 1)
+		dok.InsertEntry(2*k, i, 1)
+		dok.InsertEntry(i, 2*k+1, -1)
+		dok.InsertEntry(2*k+1, i, -1)
+	}
+	return sparse.NewCSR(dok)
+}
+
+// convDiff returns the nonsymmetric matrix of the 5-point upwind-like
+// discretization of a convection-diffusion operator on the n×n grid with the
+// convection strength c and a varying diagonal.
+func convDiff(n int, c float64) *sparse.CSR {
+	dok := sparse.NewDOK(n*n, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			k := i*n + j
+			dok.InsertEntry(k, k, 4+float64(k%3))
+			if i > 0 {
+				dok.InsertEntry(k, k-n, -1-c)
+			}
+			if i < n-1 {
+				dok.InsertEntry(k, k+n, -1+c)
+			}
+			if j > 0 {
+				dok.InsertEntry(k, k-1, -1-c/2)
+			}
+			if j < n-1 {
+				dok.InsertEntry(k, k+1, -1+c/2)
+			}
+		}
+	}
+	return sparse.NewCSR(dok)
+}
+
+// randNonsym returns a random nonsymmetric n×n matrix with a dominant
+// diagonal and about four off-diagonal entries in every row.
+func randNonsym(n int, rnd *rand.Rand) *sparse.CSR {
+	dok := sparse.NewDOK(n, n)
+	for i := 0; i < n; i++ {
+		dok.InsertEntry(i, i, 4+rnd.Float64())
+		for k := 0; k < 4; k++ {
+			j := rnd.Intn(n)
+			if j != i {
+				dok.InsertEntry(i, j, rnd.NormFloat64()/2)
+			}
+		}
+	}
+	return sparse.NewCSR(dok)
+}
+
+// rotation returns the 2×2 rotation matrix for which the Lanczos-type
+// methods break down in the first iteration with b = e_1 because
+// r̃ᵀ A r̃ = 0.
+func rotation() *sparse.CSR {
+	dok := sparse.NewDOK(2, 2)
+	dok.InsertEntry(0, 1, 1)
+	dok.InsertEntry(1, 0, -1)
+	return sparse.NewCSR(dok)
+}
+
+// readMatrix reads the symmetric matrix in the gzipped Matrix Market file
+// with the given name from the data directory.
+func readMatrix(t *testing.T, name string) *sparse.CSR {
+	f, err := os.Open(filepath.Join("data", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := sparse.ReadSymMatrixMarket(gz)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return a.ToCSR()
+}
+
+// jacobi returns the Jacobi preconditioner for a. It uses the absolute
+// values of the diagonal and replaces zeros by one, so that it is positive
+// definite also for symmetric indefinite and saddle point matrices.
+func jacobi(a *sparse.CSR) diagPrecon {
+	n, _ := a.Dims()
+	d := make(diagPrecon, n)
+	for i := range d {
+		d[i] = math.Abs(a.At(i, i))
+		if d[i] == 0 {
+			d[i] = 1
+		}
+	}
+	return d
+}
+
+// rhs returns the right-hand side b = A*x where x_i = 1 + i/n.
+func rhs(a sparse.Matrix) (b, x *mat64.Vector) {
+	n, _ := a.Dims()
+	x = mat64.NewVector(n, nil)
+	for i := 0; i < n; i++ {
+		x.SetVec(i, 1+float64(i)/float64(n))
+	}
+	b = mat64.NewVector(n, nil)
+	sparse.MulMatVec(b, 1, false, a, x)
+	return b, x
+}
+
+// trueResidual returns ‖b - A*x‖ / ‖b‖.
+func trueResidual(a sparse.Matrix, b, x *mat64.Vector) float64 {
+	r := mat64.NewVector(b.Len(), nil)
+	r.CopyVec(b)
+	sparse.MulMatVec(r, -1, false, a, x)
+	return mat64.Norm(r, 2) / mat64.Norm(b, 2)
+}
+
+// testProblem is a linear system for the tests of the nonsymmetric solvers.
+type testProblem struct {
+	name string
+	a    *sparse.CSR
+	// jacobi specifies whether the Jacobi preconditioner is used.
+	jacobi bool
+	// cond is a rough upper bound on the condition number of a.
+	cond float64
+}
+
+// nonsymProblems returns the test problems for the nonsymmetric solvers.
+func nonsymProblems(t *testing.T) []testProblem {
+	rnd := rand.New(rand.NewSource(1))
+	gr := readMatrix(t, "gr_30_30.mtx.gz")
+	return []testProblem{
+		{"convDiff(5)", convDiff(5, 0.6), false, 100},
+		{"convDiff(20)", convDiff(20, 0.6), false, 100},
+		{"convDiff(20)/Jacobi", convDiff(20, 0.6), true, 100},
+		{"randNonsym(100)", randNonsym(100, rnd), false, 100},
+		{"randNonsym(1000)", randNonsym(1000, rnd), false, 100},
+		{"gr_30_30", gr, false, 1000},
+		{"gr_30_30/Jacobi", gr, true, 1000},
+	}
+}
+
+// indefiniteProblems returns symmetric indefinite test problems for MINRES
+// and SYMMLQ.
+func indefiniteProblems() []testProblem {
+	return []testProblem{
+		{"lap2D(10)-2I", shiftedLap2D(10, 2), false, 1000},
+		{"lap2D(10)-2I/Jacobi", shiftedLap2D(10, 2), true, 1000},
+		{"saddle(10,20)", saddle(10, 20), false, 1000},
+		{"saddle(10,20)/Jacobi", saddle(10, 20), true, 1000},
+	}
+}
+
+// testSolve solves the problem with the given method and reports an error
+// if the true relative residual is not below tol or does not agree with the
+// reported one.
+func testSolve(t *testing.T, name string, p testProblem, method Method, tol float64) Result {
+	b, want := rhs(p.a)
+	settings := DefaultSettings(b.Len())
+	settings.Tolerance = tol
+	if p.jacobi {
+		settings.Preconditioner = jacobi(p.a)
+	}
+	name += "/" + p.name
+	result, err := Solve(p.a, b, nil, settings, method)
+	if err != nil {
+		t.Errorf("%s: unexpected error: %v", name, err)
+		return result
+	}
+	res := trueResidual(p.a, b, result.X)
+	if res >= tol {
+		t.Errorf("%s: true relative residual %v not below %v", name, res, tol)
+	}
+	if math.Abs(result.Stats.Residual-res) > 1e-3*tol {
+		t.Errorf("%s: want reported residual %v, got %v", name, res, result.Stats.Residual)
+	}
+	diff := mat64.NewVector(b.Len(), nil)
+	diff.SubVec(result.X, want)
+	if e := mat64.Norm(diff, math.Inf(1)); e > p.cond*tol*mat64.Norm(want, math.Inf(1)) {
+		t.Errorf("%s: solution error %v too large", name, e)
+	}
+	return result
+}
+
+// testBreakdown checks that method reports a breakdown on the rotation
+// matrix.
+func testBreakdown(t *testing.T, name string, method Method) {
+	b := mat64.NewVector(2, []float64{1, 0})
+	_, err := Solve(rotation(), b, nil, nil, method)
+	var be *BreakdownError
+	if !errors.As(err, &be) || !errors.Is(err, ErrBreakdown) {
+		t.Errorf("%s: want breakdown, got %v", name, err)
+		return
+	}
+	if be.Method != name {
+		t.Errorf("%s: want method %v in the breakdown error, got %v", name, name, be.Method)
+	}
+}
+
+// testNos7 checks that method converges on nos7 with the Jacobi
+// preconditioner and that without it the method stops with an error and
+// returns the best iterate that it found. nos7 has a condition number of
+// about 4e9 and its diagonal spans nine orders of magnitude, so the
+// nonsymmetric methods stagnate or break down on it unless it is scaled.
+func testNos7(t *testing.T, name string, method func() Method) {
+	a := readMatrix(t, "nos7.mtx.gz")
+	testSolve(t, name, testProblem{"nos7/Jacobi", a, true, 4e9}, method(), 1e-8)
+
+	b, _ := rhs(a)
+	settings := DefaultSettings(b.Len())
+	settings.Tolerance = 1e-8
+	settings.Iterations = 2000
+	result, err := Solve(a, b, nil, settings, method())
+	if !errors.Is(err, ErrBreakdown) && err != ErrIterationLimit {
+		t.Errorf("%s/nos7: want breakdown or iteration limit, got %v", name, err)
+	}
+	if res := trueResidual(a, b, result.X); math.Abs(result.Stats.Residual-res) > 1e-6*res {
+		t.Errorf("%s/nos7: want reported residual %v of the best iterate, got %v", name, res, result.Stats.Residual)
+	}
+}
+
+func TestResidualEstimate(t *testing.T) {
+	a := readMatrix(t, "gr_30_30.mtx.gz")
+	b, _ := rhs(a)
+	n := b.Len()
+	for _, test := range []struct {
+		name   string
+		method func() Method
+	}{
+		{"MINRES", func() Method { return &MINRES{} }},
+		{"SYMMLQ", func() Method { return &SYMMLQ{} }},
+		{"TFQMR", func() Method { return &TFQMR{} }},
+	} {
+		for _, stop := range []struct {
+			name string
+			stop func() StoppingCriterion
+		}{
+			{"RelativeResidual", func() StoppingCriterion { return RelativeResidual{Tolerance: 1e-8} }},
+			{"PreconditionedResidual", func() StoppingCriterion { return &PreconditionedResidual{Tolerance: 1e-8} }},
+		} {
+			name := test.name + "/" + stop.name
+			settings := DefaultSettings(n)
+			settings.Preconditioner = jacobi(a)
+			settings.Stop = stop.stop()
+			result, err := Solve(a, b, nil, settings, test.method())
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", name, err)
+				continue
+			}
+			res := trueResidual(a, b, result.X)
+			if res >= 1e-8 {
+				t.Errorf("%s: true relative residual %v not below 1e-8", name, res)
+			}
+			if math.Abs(result.Stats.Residual-res) > 1e-3*res {
+				t.Errorf("%s: want reported residual %v, got %v", name, res, result.Stats.Residual)
+			}
+		}
+	}
+}
+
+func TestSolveStops(t *testing.T) {
+	// The residual of CGS is far from monotone on this problem, so the
+	// last iterate is usually not the best one.
+	a := convDiff(20, 40)
+	b, _ := rhs(a)
+	n := b.Len()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, test := range []struct {
+		name       string
+		ctx        context.Context
+		iterations int
+		maxRuntime time.Duration
+		want       error
+	}{
+		{"canceled", canceled, 10 * n, 0, ErrCanceled},
+		{"time limit", context.Background(), 10 * n, time.Nanosecond, ErrTimeLimit},
+		{"iteration limit", context.Background(), 25, 0, ErrIterationLimit},
+	} {
+		settings := DefaultSettings(n)
+		settings.Tolerance = 1e-12
+		settings.Iterations = test.iterations
+		settings.MaxRuntime = test.maxRuntime
+		settings.History = true
+		result, err := SolveContext(test.ctx, a, b, nil, settings, &CGS{})
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: want %v, got %v", test.name, test.want, err)
+			continue
+		}
+		if test.iterations != 10*n && result.Stats.Iterations != test.iterations {
+			t.Errorf("%s: want %v iterations, got %v", test.name, test.iterations, result.Stats.Iterations)
+		}
+		if test.iterations == 10*n && result.Stats.Iterations != 1 {
+			t.Errorf("%s: want to stop after the first iteration, got %v", test.name, result.Stats.Iterations)
+		}
+		// Result holds the best iterate.
+		best := math.Inf(1)
+		for _, res := range result.History {
+			best = math.Min(best, res)
+		}
+		if result.Stats.Residual != best {
+			t.Errorf("%s: want the smallest residual %v, got %v", test.name, best, result.Stats.Residual)
+		}
+		if res := trueResidual(a, b, result.X); math.Abs(res-best) > 1e-6*best {
+			t.Errorf("%s: want residual %v of the returned iterate, got %v", test.name, best, res)
+		}
+	}
+}
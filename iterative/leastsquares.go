@@ -0,0 +1,170 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+// ErrConditionLimit is returned by LSQR and LSMR when the estimate of the
+// condition number exceeds LeastSquares.ConLim or is so large that further
+// iterations cannot improve the solution in floating-point arithmetic.
+var ErrConditionLimit = errors.New("iterative: reached condition number limit")
+
+// LeastSquares holds the parameters of LSQR and LSMR which solve the
+// damped least squares problem
+//
+//  min ‖[A; λI] x - [b; 0]‖
+//
+// for a rectangular matrix A. If λ is zero and Ax = b is consistent, they
+// find a solution of Ax = b, the minimum norm one if the initial guess is
+// zero.
+type LeastSquares struct {
+	// Damp is the damping (Tikhonov regularization) parameter λ.
+	Damp float64
+
+	// ATol and BTol are the relative accuracies of A and b. The iteration
+	// stops when
+	//
+	//  ‖r‖ ≤ BTol ‖b‖ + ATol ‖A‖ ‖x‖
+	//
+	// or when
+	//
+	//  ‖Aᵀ r - λ² x‖ ≤ ATol ‖A‖ ‖r‖,
+	//
+	// where r = b - A x. The first test succeeds for consistent systems,
+	// the second one for least squares problems. If ATol or BTol is zero,
+	// Settings.Tolerance is used.
+	ATol, BTol float64
+
+	// ConLim is the limit of the estimate of cond([A; λI]). If it is
+	// zero, 1e8 is used.
+	ConLim float64
+}
+
+// LeastSquaresResult is the result of LSQR and LSMR. The norms are
+// estimates at the last iteration obtained at no additional cost.
+type LeastSquaresResult struct {
+	Result
+
+	// ANorm is the estimate of the Frobenius norm of [A; λI].
+	ANorm float64
+	// ACond is the estimate of cond([A; λI]).
+	ACond float64
+	// RNorm is the estimate of ‖b - A x‖.
+	RNorm float64
+	// ARNorm is the estimate of ‖Aᵀ (b - A x) - λ² x‖.
+	ARNorm float64
+	// XNorm is the estimate of ‖x‖.
+	XNorm float64
+}
+
+// lsSetup checks the dimensions of the least squares problem and returns the
+// initial iterate and the settings with defaults filled in.
+func lsSetup(a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, ls *LeastSquares) (*mat64.Vector, *Settings, LeastSquares) {
+	m, n := a.Dims()
+	if xInit != nil && n != xInit.Len() {
+		panic("iterative: mismatched size of the initial guess")
+	}
+	if b.Len() != m {
+		panic("iterative: mismatched size of the right-hand side vector")
+	}
+
+	if settings == nil {
+		settings = DefaultSettings(n)
+	}
+	var p LeastSquares
+	if ls != nil {
+		p = *ls
+	}
+	if p.ATol == 0 {
+		p.ATol = settings.Tolerance
+	}
+	if p.BTol == 0 {
+		p.BTol = settings.Tolerance
+	}
+	if p.ConLim == 0 {
+		p.ConLim = 1e8
+	}
+
+	x := mat64.NewVector(n, nil)
+	if xInit != nil {
+		x.CopyVec(xInit)
+	}
+	return x, settings, p
+}
+
+// check reports whether LSQR or LSMR should stop after an iteration. The
+// returned error is nil if the iteration has converged. The tests are those
+// of Paige and Saunders, evaluated in the order of their priority.
+func (p *LeastSquares) check(ctx context.Context, settings *Settings, stats *Stats, res *LeastSquaresResult, bNorm float64) (bool, error) {
+	test1 := res.RNorm / bNorm
+	test2 := math.Inf(1)
+	if res.ANorm*res.RNorm != 0 {
+		test2 = res.ARNorm / (res.ANorm * res.RNorm)
+	}
+	test3 := 1 / res.ACond
+	t1 := test1 / (1 + res.ANorm*res.XNorm/bNorm)
+	rtol := p.BTol + p.ATol*res.ANorm*res.XNorm/bNorm
+
+	switch {
+	case test1 <= rtol, test2 <= p.ATol:
+		return true, nil
+	case test3 <= 1/p.ConLim:
+		return true, ErrConditionLimit
+	case 1+t1 <= 1, 1+test2 <= 1:
+		// The tolerances are below machine precision.
+		return true, nil
+	case 1+test3 <= 1:
+		return true, ErrConditionLimit
+	case ctx.Err() != nil:
+		return true, fmt.Errorf("%w: %v", ErrCanceled, ctx.Err())
+	case settings.MaxRuntime > 0 && time.Since(stats.StartTime) >= settings.MaxRuntime:
+		return true, ErrTimeLimit
+	case stats.Iterations == settings.Iterations:
+		return true, ErrIterationLimit
+	}
+	return false, nil
+}
+
+// symOrtho computes a stable plane rotation such that
+//
+//  [ c  s] [a]   [r]
+//  [-s  c] [b] = [0].
+func symOrtho(a, b float64) (c, s, r float64) {
+	switch {
+	case b == 0:
+		return sign(a), 0, math.Abs(a)
+	case a == 0:
+		return 0, sign(b), math.Abs(b)
+	case math.Abs(b) > math.Abs(a):
+		tau := a / b
+		s = sign(b) / math.Sqrt(1+tau*tau)
+		c = s * tau
+		return c, s, b / s
+	default:
+		tau := b / a
+		c = sign(a) / math.Sqrt(1+tau*tau)
+		s = c * tau
+		return c, s, a / c
+	}
+}
+
+func sign(a float64) float64 {
+	switch {
+	case a > 0:
+		return 1
+	case a < 0:
+		return -1
+	}
+	return 0
+}
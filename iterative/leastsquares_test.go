@@ -0,0 +1,348 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+type lsSolver func(sparse.Matrix, *mat64.Vector, *mat64.Vector, *Settings, *LeastSquares) (LeastSquaresResult, error)
+
+var lsSolvers = []struct {
+	name  string
+	solve lsSolver
+}{
+	{"LSQR", LSQR},
+	{"LSMR", LSMR},
+}
+
+// randRect returns a random m×n matrix with a dominant diagonal and three
+// more entries in every row.
+func randRect(m, n int, rnd *rand.Rand) *sparse.CSR {
+	dok := sparse.NewDOK(m, n)
+	for i := 0; i < m; i++ {
+		if i < n {
+			dok.InsertEntry(i, i, 2+rnd.Float64())
+		}
+		for k := 0; k < 3; k++ {
+			dok.InsertEntry(i, rnd.Intn(n), rnd.NormFloat64())
+		}
+	}
+	return sparse.NewCSR(dok)
+}
+
+// diagMatrix returns the diagonal matrix with the given diagonal.
+func diagMatrix(d []float64) *sparse.CSR {
+	dok := sparse.NewDOK(len(d), len(d))
+	for i, v := range d {
+		dok.InsertEntry(i, i, v)
+	}
+	return sparse.NewCSR(dok)
+}
+
+// lsResiduals returns r = b - A x and the norm of g = Aᵀ r - λ² x.
+func lsResiduals(a sparse.Matrix, b, x *mat64.Vector, damp float64) (r *mat64.Vector, gNorm float64) {
+	m, n := a.Dims()
+	r = mat64.NewVector(m, nil)
+	r.CopyVec(b)
+	sparse.MulMatVec(r, -1, false, a, x)
+	g := mat64.NewVector(n, nil)
+	sparse.MulMatVec(g, 1, true, a, r)
+	g.AddScaledVec(g, -damp*damp, x)
+	return r, mat64.Norm(g, 2)
+}
+
+func TestLeastSquares(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, dims := range [][2]int{{50, 20}, {300, 100}, {20, 50}, {100, 100}} {
+		m, n := dims[0], dims[1]
+		a := randRect(m, n, rnd)
+		b := mat64.NewVector(m, nil)
+		for i := 0; i < m; i++ {
+			b.SetVec(i, rnd.NormFloat64())
+		}
+		for _, damp := range []float64{0, 0.5} {
+			var xs []*mat64.Vector
+			for _, solver := range lsSolvers {
+				settings := DefaultSettings(n)
+				settings.Tolerance = 1e-12
+				settings.History = true
+				result, err := solver.solve(a, b, nil, settings, &LeastSquares{Damp: damp})
+				if err != nil {
+					t.Errorf("%s %v, damp %v: unexpected error: %v", solver.name, dims, damp, err)
+					continue
+				}
+				r, gNorm := lsResiduals(a, b, result.X, damp)
+				rNorm := mat64.Norm(r, 2)
+				if m <= n && damp == 0 {
+					// The system is consistent.
+					if rNorm > 1e-10*mat64.Norm(b, 2) {
+						t.Errorf("%s %v: residual %v too large", solver.name, dims, rNorm)
+					}
+				} else if gNorm > 1e-10*result.ANorm*rNorm {
+					t.Errorf("%s %v, damp %v: normal equations residual %v too large", solver.name, dims, damp, gNorm)
+				}
+				if math.Abs(result.RNorm-rNorm) > 1e-8*mat64.Norm(b, 2) {
+					t.Errorf("%s %v, damp %v: want RNorm %v, got %v", solver.name, dims, damp, rNorm, result.RNorm)
+				}
+				if xNorm := mat64.Norm(result.X, 2); math.Abs(result.XNorm-xNorm) > 1e-8*xNorm {
+					t.Errorf("%s %v, damp %v: want XNorm %v, got %v", solver.name, dims, damp, xNorm, result.XNorm)
+				}
+				if len(result.History) != result.Stats.Iterations {
+					t.Errorf("%s %v, damp %v: want %v history entries, got %v", solver.name, dims, damp, result.Stats.Iterations, len(result.History))
+				}
+				xs = append(xs, result.X)
+			}
+			if len(xs) == 2 {
+				d := mat64.NewVector(n, nil)
+				d.SubVec(xs[0], xs[1])
+				if mat64.Norm(d, 2) > 1e-8*mat64.Norm(xs[0], 2) {
+					t.Errorf("%v, damp %v: LSQR and LSMR solutions differ by %v", dims, damp, mat64.Norm(d, 2))
+				}
+			}
+		}
+	}
+}
+
+func TestLeastSquaresDiagonal(t *testing.T) {
+	// The singular values are known, so the estimates of the norm and the
+	// condition number and the damped solution can be checked.
+	const n = 40
+	d := make([]float64, n)
+	b := mat64.NewVector(n, nil)
+	for i := range d {
+		d[i] = float64(i + 1)
+		b.SetVec(i, 1)
+	}
+	a := diagMatrix(d)
+	for _, damp := range []float64{0, 0.5, 3} {
+		var fro, froInv float64
+		for _, v := range d {
+			s2 := v*v + damp*damp
+			fro += s2
+			froInv += 1 / s2
+		}
+		fro = math.Sqrt(fro)
+		condFro := fro * math.Sqrt(froInv)
+		sMax := math.Hypot(d[n-1], damp)
+		cond2 := sMax / math.Hypot(d[0], damp)
+		for _, solver := range lsSolvers {
+			settings := DefaultSettings(n)
+			settings.Tolerance = 1e-12
+			result, err := solver.solve(a, b, nil, settings, &LeastSquares{Damp: damp})
+			if err != nil {
+				t.Errorf("%s, damp %v: unexpected error: %v", solver.name, damp, err)
+				continue
+			}
+			for i, v := range d {
+				want := v / (v*v + damp*damp)
+				if got := result.X.At(i, 0); math.Abs(got-want) > 1e-10*want {
+					t.Errorf("%s, damp %v: want x[%d] = %v, got %v", solver.name, damp, i, want, got)
+					break
+				}
+			}
+			// ANorm estimates the Frobenius norm from the
+			// bidiagonalization, which exceeds the spectral norm and
+			// grows beyond the Frobenius norm only through the loss
+			// of orthogonality.
+			if result.ANorm < sMax || result.ANorm > 2*fro {
+				t.Errorf("%s, damp %v: ANorm %v not in [%v, %v]", solver.name, damp, result.ANorm, sMax, 2*fro)
+			}
+			// LSQR estimates ‖A‖_F ‖A⁺‖_F, LSMR estimates cond₂ from
+			// below.
+			lo, hi := cond2, 2*condFro
+			if solver.name == "LSMR" {
+				lo, hi = cond2/10, cond2*(1+1e-10)
+			}
+			if result.ACond < lo || result.ACond > hi {
+				t.Errorf("%s, damp %v: ACond %v not in [%v, %v]", solver.name, damp, result.ACond, lo, hi)
+			}
+		}
+	}
+}
+
+func TestLeastSquaresTolerances(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, test := range []struct {
+		name string
+		m, n int
+		// consistent specifies whether b is in the range of A.
+		consistent bool
+		atol, btol float64
+	}{
+		// The iteration stops by the test on ‖r‖.
+		{"BTol", 100, 100, true, 1e-14, 1e-6},
+		// The iteration stops by the test on ‖Aᵀ r‖.
+		{"ATol", 300, 100, false, 1e-6, 1e-14},
+	} {
+		a := randRect(test.m, test.n, rnd)
+		b := mat64.NewVector(test.m, nil)
+		if test.consistent {
+			x := mat64.NewVector(test.n, nil)
+			for i := 0; i < test.n; i++ {
+				x.SetVec(i, rnd.NormFloat64())
+			}
+			sparse.MulMatVec(b, 1, false, a, x)
+		} else {
+			for i := 0; i < test.m; i++ {
+				b.SetVec(i, rnd.NormFloat64())
+			}
+		}
+		for _, solver := range lsSolvers {
+			name := solver.name + "/" + test.name
+			settings := DefaultSettings(test.n)
+			tight, err := solver.solve(a, b, nil, settings, &LeastSquares{ATol: 1e-14, BTol: 1e-14})
+			if err != nil {
+				t.Errorf("%s: unexpected error with tight tolerances: %v", name, err)
+				continue
+			}
+			result, err := solver.solve(a, b, nil, settings, &LeastSquares{ATol: test.atol, BTol: test.btol})
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", name, err)
+				continue
+			}
+			if result.Stats.Iterations >= tight.Stats.Iterations {
+				t.Errorf("%s: want fewer than %v iterations, got %v", name, tight.Stats.Iterations, result.Stats.Iterations)
+			}
+			r, gNorm := lsResiduals(a, b, result.X, 0)
+			rNorm := mat64.Norm(r, 2)
+			if test.consistent {
+				bound := test.btol*mat64.Norm(b, 2) + test.atol*result.ANorm*mat64.Norm(result.X, 2)
+				if rNorm > 1.1*bound {
+					t.Errorf("%s: residual %v above %v", name, rNorm, bound)
+				}
+			} else if bound := test.atol * result.ANorm * rNorm; gNorm > 1.1*bound {
+				t.Errorf("%s: normal equations residual %v above %v", name, gNorm, bound)
+			}
+		}
+	}
+}
+
+func TestLeastSquaresConditionLimit(t *testing.T) {
+	const n = 30
+	d := make([]float64, n)
+	b := mat64.NewVector(n, nil)
+	for i := range d {
+		d[i] = math.Pow(10, -float64(i)/2)
+		b.SetVec(i, 1)
+	}
+	a := diagMatrix(d)
+	for _, solver := range lsSolvers {
+		result, err := solver.solve(a, b, nil, nil, &LeastSquares{ConLim: 1e4})
+		if !errors.Is(err, ErrConditionLimit) {
+			t.Errorf("%s: want %v, got %v", solver.name, ErrConditionLimit, err)
+		}
+		if result.ACond < 1e4 {
+			t.Errorf("%s: stopped with ACond %v below the limit", solver.name, result.ACond)
+		}
+	}
+}
+
+func TestLeastSquaresTrivial(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	a := randRect(40, 20, rnd)
+	x0 := mat64.NewVector(20, nil)
+	for i := 0; i < 20; i++ {
+		x0.SetVec(i, 1)
+	}
+	b := mat64.NewVector(40, nil)
+	sparse.MulMatVec(b, 1, false, a, x0)
+	for _, solver := range lsSolvers {
+		result, err := solver.solve(a, mat64.NewVector(40, nil), nil, nil, nil)
+		if err != nil || mat64.Norm(result.X, 2) != 0 {
+			t.Errorf("%s: zero right-hand side: want zero solution, got %v (error %v)", solver.name, mat64.Norm(result.X, 2), err)
+		}
+		result, err = solver.solve(a, b, x0, nil, nil)
+		if err != nil || result.Stats.Iterations != 0 {
+			t.Errorf("%s: exact initial guess: want no iterations, got %v (error %v)", solver.name, result.Stats.Iterations, err)
+		}
+	}
+}
+
+// lsReference returns the solution of the damped least squares problem
+// computed by LSQR to high accuracy, which is the minimum norm one if there
+// are many.
+func lsReference(t *testing.T, a sparse.Matrix, b *mat64.Vector, damp float64) *mat64.Vector {
+	_, n := a.Dims()
+	settings := DefaultSettings(n)
+	settings.Tolerance = 1e-14
+	result, err := LSQR(a, b, nil, settings, &LeastSquares{Damp: damp})
+	if err != nil {
+		t.Fatalf("LSQR reference: %v", err)
+	}
+	return result.X
+}
+
+// squaredNorms returns the squared norms of the rows and of the columns of
+// a plus damp².
+func squaredNorms(a *sparse.CSR, damp float64) (rows, cols diagPrecon) {
+	m, n := a.Dims()
+	rows = make(diagPrecon, m)
+	cols = make(diagPrecon, n)
+	for i := range rows {
+		rows[i] = damp * damp
+	}
+	for j := range cols {
+		cols[j] = damp * damp
+	}
+	a.DoNonZero(func(i, j int, v float64) {
+		rows[i] += v * v
+		cols[j] += v * v
+	})
+	return rows, cols
+}
+
+// relDiff returns ‖x - y‖ / ‖y‖.
+func relDiff(x, y *mat64.Vector) float64 {
+	d := mat64.NewVector(x.Len(), nil)
+	d.SubVec(x, y)
+	return mat64.Norm(d, 2) / mat64.Norm(y, 2)
+}
+
+func TestLeastSquaresCanceled(t *testing.T) {
+	a := randRect(30, 20, rand.New(rand.NewSource(1)))
+	b := mat64.NewVector(30, nil)
+	for i := 0; i < 30; i++ {
+		b.SetVec(i, 1)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, test := range []struct {
+		name  string
+		solve func() (Stats, error)
+	}{
+		{"CGLS", func() (Stats, error) {
+			r, err := CGLSContext(ctx, a, b, nil, nil, 0)
+			return r.Stats, err
+		}},
+		{"CGNE", func() (Stats, error) {
+			r, err := CGNEContext(ctx, a, b, nil, nil, 0)
+			return r.Stats, err
+		}},
+		{"LSQR", func() (Stats, error) {
+			r, err := LSQRContext(ctx, a, b, nil, nil, nil)
+			return r.Stats, err
+		}},
+		{"LSMR", func() (Stats, error) {
+			r, err := LSMRContext(ctx, a, b, nil, nil, nil)
+			return r.Stats, err
+		}},
+	} {
+		stats, err := test.solve()
+		if !errors.Is(err, ErrCanceled) {
+			t.Errorf("%s: want %v, got %v", test.name, ErrCanceled, err)
+		}
+		if stats.Iterations != 1 {
+			t.Errorf("%s: want to stop after the first iteration, got %v", test.name, stats.Iterations)
+		}
+	}
+}
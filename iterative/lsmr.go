@@ -0,0 +1,188 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+// LSMR solves the damped least squares problem described by ls for
+// a rectangular matrix A by the method of Fong and Saunders, which is
+// equivalent to MINRES applied to the normal equations. Unlike with LSQR,
+// ‖Aᵀ r‖ decreases monotonically, so LSMR can be stopped earlier when the
+// least squares test decides. Every iteration requires one product with A
+// and one with Aᵀ.
+//
+// The arguments and the returned errors are the same as for LSQR.
+func LSMR(a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, ls *LeastSquares) (result LeastSquaresResult, err error) {
+	return LSMRContext(context.Background(), a, b, xInit, settings, ls)
+}
+
+// LSMRContext is like LSMR but stops with an error wrapping ErrCanceled
+// when ctx is done. The context is checked after every iteration.
+func LSMRContext(ctx context.Context, a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, ls *LeastSquares) (result LeastSquaresResult, err error) {
+	stats := Stats{
+		StartTime: time.Now(),
+	}
+	x, settings, p := lsSetup(a, b, xInit, settings, ls)
+	m, n := a.Dims()
+
+	var history []float64
+	defer func() {
+		result.X = x
+		result.Stats = stats
+		result.Runtime = time.Since(stats.StartTime)
+		result.History = history
+	}()
+	if err = initRecorder(settings); err != nil {
+		return result, err
+	}
+
+	bNorm := mat64.Norm(b, 2)
+	if bNorm == 0 {
+		bNorm = 1
+	}
+
+	// β u = b - A x
+	u := mat64.NewVector(m, nil)
+	u.CopyVec(b)
+	if xInit != nil {
+		sparse.MulMatVec(u, -1, false, a, x)
+		stats.MatVecMultiplies++
+	}
+	beta := mat64.Norm(u, 2)
+	// α v = Aᵀ u
+	v := mat64.NewVector(n, nil)
+	var alpha float64
+	if beta > 0 {
+		u.ScaleVec(1/beta, u)
+		sparse.MulMatVec(v, 1, true, a, u)
+		stats.MatVecMultiplies++
+		alpha = mat64.Norm(v, 2)
+	}
+	if alpha > 0 {
+		v.ScaleVec(1/alpha, v)
+	}
+
+	zetabar := alpha * beta
+	alphabar := alpha
+	rho, rhobar, cbar, sbar := 1.0, 1.0, 1.0, 0.0
+	h := mat64.NewVector(n, nil)
+	h.CopyVec(v)
+	hbar := mat64.NewVector(n, nil)
+
+	// Variables for the estimate of ‖r‖.
+	betadd := beta
+	var betad, tautildeold, thetatilde, zeta, d float64
+	rhodold := 1.0
+
+	// Variables for the estimates of ‖A‖ and cond(A).
+	normA2 := alpha * alpha
+	maxrbar, minrbar := 0.0, math.MaxFloat64
+
+	result.RNorm = beta
+	result.ARNorm = alpha * beta
+	stats.Residual = beta / bNorm
+	if result.ARNorm == 0 {
+		// x is the solution.
+		return result, nil
+	}
+
+	for {
+		// β u = A v - α u
+		u.ScaleVec(-alpha, u)
+		sparse.MulMatVec(u, 1, false, a, v)
+		beta = mat64.Norm(u, 2)
+		stats.MatVecMultiplies++
+		if beta > 0 {
+			u.ScaleVec(1/beta, u)
+			// α v = Aᵀ u - β v
+			v.ScaleVec(-beta, v)
+			sparse.MulMatVec(v, 1, true, a, u)
+			stats.MatVecMultiplies++
+			alpha = mat64.Norm(v, 2)
+			if alpha > 0 {
+				v.ScaleVec(1/alpha, v)
+			}
+		}
+
+		// Eliminate the damping parameter.
+		chat, shat, alphahat := symOrtho(alphabar, p.Damp)
+
+		// Eliminate the subdiagonal element of the lower bidiagonal matrix.
+		rhoold := rho
+		c, s, rho1 := symOrtho(alphahat, beta)
+		rho = rho1
+		thetanew := s * alpha
+		alphabar = c * alpha
+
+		// Eliminate the superdiagonal element of the upper bidiagonal
+		// matrix.
+		rhobarold := rhobar
+		zetaold := zeta
+		thetabar := sbar * rho
+		rhotemp := cbar * rho
+		cbar, sbar, rhobar = symOrtho(cbar*rho, thetanew)
+		zeta = cbar * zetabar
+		zetabar = -sbar * zetabar
+
+		// hbar = h - (θbar ρ / (ρ_old ρbar_old)) hbar
+		hbar.AddScaledVec(h, -thetabar*rho/(rhoold*rhobarold), hbar)
+		// x = x + (ζ / (ρ ρbar)) hbar
+		x.AddScaledVec(x, zeta/(rho*rhobar), hbar)
+		// h = v - (θ_new / ρ) h
+		h.AddScaledVec(v, -thetanew/rho, h)
+
+		// Estimate ‖r‖.
+		betaacute := chat * betadd
+		betacheck := -shat * betadd
+		betahat := c * betaacute
+		betadd = -s * betaacute
+		thetatildeold := thetatilde
+		ctildeold, stildeold, rhotildeold := symOrtho(rhodold, thetabar)
+		thetatilde = stildeold * rhobar
+		rhodold = ctildeold * rhobar
+		betad = -stildeold*betad + ctildeold*betahat
+		tautildeold = (zetaold - thetatildeold*tautildeold) / rhotildeold
+		taud := (zeta - thetatilde*tautildeold) / rhodold
+		d += betacheck * betacheck
+		// ‖[r; λ x]‖
+		rNorm := math.Sqrt(d + (betad-taud)*(betad-taud) + betadd*betadd)
+
+		// Estimate ‖A‖.
+		normA2 += beta * beta
+		result.ANorm = math.Sqrt(normA2)
+		normA2 += alpha * alpha
+
+		// Estimate cond(A).
+		maxrbar = math.Max(maxrbar, rhobarold)
+		if stats.Iterations > 0 {
+			minrbar = math.Min(minrbar, rhobarold)
+		}
+		result.ACond = math.Max(maxrbar, rhotemp) / math.Min(minrbar, rhotemp)
+
+		result.ARNorm = math.Abs(zetabar)
+		result.XNorm = mat64.Norm(x, 2)
+		r1sq := rNorm*rNorm - p.Damp*p.Damp*result.XNorm*result.XNorm
+		result.RNorm = math.Sqrt(math.Abs(r1sq))
+
+		stats.Iterations++
+		stats.Residual = result.RNorm / bNorm
+		if err = record(settings, &stats, &history); err != nil {
+			return result, err
+		}
+		// The tests use the norm of the residual of the damped problem.
+		check := result
+		check.RNorm = rNorm
+		if done, err := p.check(ctx, settings, &stats, &check, bNorm); done {
+			return result, err
+		}
+	}
+}
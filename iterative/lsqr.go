@@ -0,0 +1,172 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+// LSQR solves the damped least squares problem described by ls for
+// a rectangular matrix A by the method of Paige and Saunders, which is
+// equivalent to CG applied to the normal equations but numerically more
+// reliable. Every iteration requires one product with A and one with Aᵀ.
+//
+// If xInit is not nil, the problem is solved for the correction x - xInit,
+// so the damping applies to the correction. settings.Iterations limits the
+// number of iterations, settings.MaxRuntime their duration, and the
+// recorder in settings is called after every iteration. If settings is nil,
+// DefaultSettings is used with the number of columns of A. If ls is nil,
+// λ is zero and the other parameters have their default values.
+//
+// LSQR returns ErrIterationLimit or ErrTimeLimit if a limit is reached and
+// ErrConditionLimit if A appears to be too ill-conditioned.
+func LSQR(a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, ls *LeastSquares) (result LeastSquaresResult, err error) {
+	return LSQRContext(context.Background(), a, b, xInit, settings, ls)
+}
+
+// LSQRContext is like LSQR but stops with an error wrapping ErrCanceled
+// when ctx is done. The context is checked after every iteration.
+func LSQRContext(ctx context.Context, a sparse.Matrix, b, xInit *mat64.Vector, settings *Settings, ls *LeastSquares) (result LeastSquaresResult, err error) {
+	stats := Stats{
+		StartTime: time.Now(),
+	}
+	x, settings, p := lsSetup(a, b, xInit, settings, ls)
+	m, n := a.Dims()
+
+	var history []float64
+	defer func() {
+		result.X = x
+		result.Stats = stats
+		result.Runtime = time.Since(stats.StartTime)
+		result.History = history
+	}()
+	if err = initRecorder(settings); err != nil {
+		return result, err
+	}
+
+	bNorm := mat64.Norm(b, 2)
+	if bNorm == 0 {
+		bNorm = 1
+	}
+	dampsq := p.Damp * p.Damp
+
+	// β u = b - A x
+	u := mat64.NewVector(m, nil)
+	u.CopyVec(b)
+	if xInit != nil {
+		sparse.MulMatVec(u, -1, false, a, x)
+		stats.MatVecMultiplies++
+	}
+	beta := mat64.Norm(u, 2)
+	// α v = Aᵀ u
+	v := mat64.NewVector(n, nil)
+	var alpha float64
+	if beta > 0 {
+		u.ScaleVec(1/beta, u)
+		sparse.MulMatVec(v, 1, true, a, u)
+		stats.MatVecMultiplies++
+		alpha = mat64.Norm(v, 2)
+	}
+	if alpha > 0 {
+		v.ScaleVec(1/alpha, v)
+	}
+	w := mat64.NewVector(n, nil)
+	w.CopyVec(v)
+
+	rhobar := alpha
+	phibar := beta
+	var (
+		ddnorm, res2, xxnorm, z float64
+		cs2, sn2                float64 = -1, 0
+	)
+	result.RNorm = beta
+	result.ARNorm = alpha * beta
+	stats.Residual = beta / bNorm
+	if result.ARNorm == 0 {
+		// x is the solution.
+		return result, nil
+	}
+
+	for {
+		// β u = A v - α u
+		u.ScaleVec(-alpha, u)
+		sparse.MulMatVec(u, 1, false, a, v)
+		beta = mat64.Norm(u, 2)
+		stats.MatVecMultiplies++
+		if beta > 0 {
+			u.ScaleVec(1/beta, u)
+			result.ANorm = math.Sqrt(result.ANorm*result.ANorm + alpha*alpha + beta*beta + dampsq)
+			// α v = Aᵀ u - β v
+			v.ScaleVec(-beta, v)
+			sparse.MulMatVec(v, 1, true, a, u)
+			stats.MatVecMultiplies++
+			alpha = mat64.Norm(v, 2)
+			if alpha > 0 {
+				v.ScaleVec(1/alpha, v)
+			}
+		}
+
+		// Eliminate the damping parameter.
+		rhobar1 := rhobar
+		var psi float64
+		if p.Damp > 0 {
+			rhobar1 = math.Hypot(rhobar, p.Damp)
+			cs1 := rhobar / rhobar1
+			sn1 := p.Damp / rhobar1
+			psi = sn1 * phibar
+			phibar *= cs1
+		}
+
+		// Eliminate the subdiagonal element of the lower bidiagonal matrix.
+		cs, sn, rho := symOrtho(rhobar1, beta)
+		theta := sn * alpha
+		rhobar = -cs * alpha
+		phi := cs * phibar
+		phibar *= sn
+		tau := sn * phi
+
+		// x = x + (φ / ρ) w, w = v - (θ / ρ) w
+		ddnorm += mat64.Dot(w, w) / (rho * rho)
+		x.AddScaledVec(x, phi/rho, w)
+		w.AddScaledVec(v, -theta/rho, w)
+
+		// Estimate the norm of x by another plane rotation.
+		delta := sn2 * rho
+		gambar := -cs2 * rho
+		rhs := phi - delta*z
+		zbar := rhs / gambar
+		result.XNorm = math.Sqrt(xxnorm + zbar*zbar)
+		gamma := math.Hypot(gambar, theta)
+		cs2 = gambar / gamma
+		sn2 = theta / gamma
+		z = rhs / gamma
+		xxnorm += z * z
+
+		result.ACond = result.ANorm * math.Sqrt(ddnorm)
+		res2 += psi * psi
+		// ‖[r; λ x]‖ and ‖r‖.
+		rNorm := math.Sqrt(phibar*phibar + res2)
+		r1sq := rNorm*rNorm - dampsq*xxnorm
+		result.RNorm = math.Sqrt(math.Abs(r1sq))
+		result.ARNorm = alpha * math.Abs(tau)
+
+		stats.Iterations++
+		stats.Residual = result.RNorm / bNorm
+		if err = record(settings, &stats, &history); err != nil {
+			return result, err
+		}
+		// The tests use the norm of the residual of the damped problem.
+		check := result
+		check.RNorm = rNorm
+		if done, err := p.check(ctx, settings, &stats, &check, bNorm); done {
+			return result, err
+		}
+	}
+}
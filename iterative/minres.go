@@ -0,0 +1,145 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// MINRES implements the Minimum Residual method of Paige and Saunders for
+// solving the linear system Ax = b with a symmetric, possibly indefinite
+// matrix A. The preconditioner must be symmetric positive definite.
+//
+// MINRES does not update the residual vector, it reports the Lanczos-based
+// estimate of the residual norm through Context.ResidualNorm. With
+// a preconditioner M, the estimate is of the norm ‖r‖_M⁻¹ = √(rᵀ M⁻¹ r) that
+// MINRES minimizes. Solve confirms convergence with the true residual. If
+// M turns out not to be positive definite, the iteration stops with
+// a *BreakdownError.
+type MINRES struct {
+	resume int
+	first  bool
+
+	alpha, beta, oldb float64
+	dbar, epsln       float64
+	phibar            float64
+	cs, sn            float64
+	r1, r2, y, v      *mat64.Vector
+	w, w1, w2         *mat64.Vector
+}
+
+func (m *MINRES) Init(ctx *Context) Operation {
+	dim := ctx.X.Len()
+	m.r1 = reuseVector(m.r1, dim)
+	m.r2 = reuseVector(m.r2, dim)
+	m.y = reuseVector(m.y, dim)
+	m.v = reuseVector(m.v, dim)
+	m.w = reuseVector(m.w, dim)
+	m.w1 = reuseVector(m.w1, dim)
+	m.w2 = reuseVector(m.w2, dim)
+	ctx.P = reuseVector(ctx.P, dim)
+	ctx.Ap = reuseVector(ctx.Ap, dim)
+	ctx.Q = reuseVector(ctx.Q, dim)
+	ctx.Z = reuseVector(ctx.Z, dim)
+
+	m.resume = 1
+	return SolvePreconditioner
+	// Solve M y = r_0
+}
+
+func (m *MINRES) Iterate(ctx *Context) Operation {
+	switch m.resume {
+	case 1:
+		// r_1 = r_2 = r_0, y = M⁻¹ r_0
+		m.r1.CopyVec(ctx.Residual)
+		m.r2.CopyVec(ctx.Residual)
+		m.y.CopyVec(ctx.Z)
+		// β_1 = √(r_0ᵀ M⁻¹ r_0)
+		beta1 := mat64.Dot(ctx.Residual, m.y)
+		if beta1 < 0 {
+			// The preconditioner is not positive definite.
+			ctx.Err = &BreakdownError{Method: "MINRES", Quantity: "r_0ᵀ M⁻¹ r_0", Value: beta1}
+			m.resume = 0
+			return CheckConvergence
+		}
+		m.beta = math.Sqrt(beta1)
+		m.oldb = 0
+		m.dbar = 0
+		m.epsln = 0
+		m.phibar = m.beta
+		m.cs = -1
+		m.sn = 0
+		m.w.ScaleVec(0, m.w)
+		m.w2.ScaleVec(0, m.w2)
+		m.first = true
+		fallthrough
+	case 4:
+		// v = y / β
+		m.v.ScaleVec(1/m.beta, m.y)
+		ctx.P.CopyVec(m.v)
+		m.resume = 2
+		return ComputeAp
+		// Compute A v
+	case 2:
+		// y = A v - (β / β_old) r_1
+		m.y.CopyVec(ctx.Ap)
+		if !m.first {
+			m.y.AddScaledVec(m.y, -m.beta/m.oldb, m.r1)
+		}
+		m.first = false
+		// α = vᵀ y
+		alpha := mat64.Dot(m.v, m.y)
+		// y = y - (α / β) r_2
+		m.y.AddScaledVec(m.y, -alpha/m.beta, m.r2)
+		// r_1 = r_2, r_2 = y
+		m.r1, m.r2 = m.r2, m.r1
+		m.r2.CopyVec(m.y)
+		m.alpha = alpha
+
+		ctx.Q.CopyVec(m.r2)
+		m.resume = 3
+		return SolvePreconditionerQ
+		// Solve M y = r_2
+	case 3:
+		m.y.CopyVec(ctx.Z)
+		m.oldb = m.beta
+		beta2 := mat64.Dot(m.r2, m.y)
+		if beta2 < 0 {
+			// The preconditioner is not positive definite.
+			ctx.Err = &BreakdownError{Method: "MINRES", Quantity: "r_2ᵀ M⁻¹ r_2", Value: beta2}
+			m.resume = 0
+			return CheckConvergence
+		}
+		m.beta = math.Sqrt(beta2)
+
+		// Apply the previous rotation and compute the next one.
+		oldeps := m.epsln
+		delta := m.cs*m.dbar + m.sn*m.alpha
+		gbar := m.sn*m.dbar - m.cs*m.alpha
+		m.epsln = m.sn * m.beta
+		m.dbar = -m.cs * m.beta
+		gamma := math.Max(math.Hypot(gbar, m.beta), dlamchE)
+		m.cs = gbar / gamma
+		m.sn = m.beta / gamma
+		phi := m.cs * m.phibar
+		m.phibar *= m.sn
+
+		// w = (v - ε_old w_1 - δ w_2) / γ with w_1 = w_2, w_2 = w.
+		m.w1, m.w2, m.w = m.w2, m.w, m.w1
+		m.w.ScaleVec(1/gamma, m.v)
+		m.w.AddScaledVec(m.w, -oldeps/gamma, m.w1)
+		m.w.AddScaledVec(m.w, -delta/gamma, m.w2)
+		// x = x + φ w
+		ctx.X.AddScaledVec(ctx.X, phi, m.w)
+
+		ctx.ResidualNorm = m.phibar
+		m.resume = 4
+		return CheckConvergence
+	default:
+		panic("unreachable")
+	}
+}
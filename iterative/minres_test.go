@@ -0,0 +1,13 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import "testing"
+
+func TestMINRESIndefinite(t *testing.T) {
+	for _, p := range indefiniteProblems() {
+		testSolve(t, "MINRES", p, &MINRES{}, 1e-10)
+	}
+}
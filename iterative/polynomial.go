@@ -0,0 +1,221 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math/rand"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+// The polynomial preconditioners in this file approximate A⁻¹ by
+// a polynomial p(A). Applying them requires only products with A and no
+// inner products, so they are suitable when inner products are
+// a synchronization bottleneck. They use internal storage, so a single
+// value must not be used by concurrent solves.
+
+// ChebyshevPolynomial is the preconditioner M⁻¹ = p(A) where p of the given
+// degree is the polynomial of the Chebyshev iteration for the eigenvalue
+// bounds min and max. Applying it requires degree products with A. It is
+// symmetric positive definite if A is and the spectrum of A lies in
+// (0, max], so it can precondition CG.
+type ChebyshevPolynomial struct {
+	a      sparse.LinearOperator
+	degree int
+
+	theta, delta, sigma float64
+
+	r, d, ad []float64
+}
+
+// NewChebyshevPolynomial returns the Chebyshev polynomial preconditioner of
+// the given degree for the square operator a whose eigenvalues are real and
+// lie in [min, max]. If max is zero, the estimate by maxEigenvalueEstimate is
+// used. If min is zero, max/30 is used, which damps mainly the upper part of
+// the spectrum as is usual for smoothers. It panics if the degree is negative
+// or the bounds are invalid.
+func NewChebyshevPolynomial(a sparse.LinearOperator, degree int, min, max float64) *ChebyshevPolynomial {
+	if degree < 0 {
+		panic("iterative: negative polynomial degree")
+	}
+	if max == 0 {
+		max = maxEigenvalueEstimate(a)
+	}
+	if min == 0 {
+		min = max / 30
+	}
+	if !(0 < min && min < max) {
+		panic("iterative: invalid eigenvalue bounds")
+	}
+	n, _ := a.Dims()
+	theta := (max + min) / 2
+	delta := (max - min) / 2
+	return &ChebyshevPolynomial{
+		a:      a,
+		degree: degree,
+		theta:  theta,
+		delta:  delta,
+		sigma:  theta / delta,
+		r:      make([]float64, n),
+		d:      make([]float64, n),
+		ad:     make([]float64, n),
+	}
+}
+
+// PreconSolve computes dst = p(A) r by degree+1 steps of the Chebyshev
+// iteration for A dst = r started from zero.
+func (p *ChebyshevPolynomial) PreconSolve(dst, r []float64) {
+	copy(p.r, r)
+	// d = r / θ, dst = d
+	for i, v := range r {
+		p.d[i] = v / p.theta
+		dst[i] = p.d[i]
+	}
+	rho := 1 / p.sigma
+	for k := 0; k < p.degree; k++ {
+		// r = r - A d
+		p.a.MulVecTo(p.ad, false, p.d)
+		rho1 := 1 / (2*p.sigma - rho)
+		for i := range p.r {
+			p.r[i] -= p.ad[i]
+			// d = ρ_new ρ d + 2 ρ_new / δ r
+			p.d[i] = rho1*rho*p.d[i] + 2*rho1/p.delta*p.r[i]
+			dst[i] += p.d[i]
+		}
+		rho = rho1
+	}
+}
+
+// Neumann is the preconditioner given by the truncated Neumann series
+//
+//  M⁻¹ = ω Σ_{k=0}^{degree} (I - ω A)^k
+//
+// which approximates A⁻¹ if the spectral radius of I - ω A is smaller than
+// one. Applying it requires degree products with A.
+type Neumann struct {
+	a      sparse.LinearOperator
+	degree int
+	omega  float64
+
+	ay []float64
+}
+
+// NewNeumann returns the Neumann series preconditioner of the given degree
+// for the square operator a with the scaling omega. If omega is zero, the
+// reciprocal of the estimate by maxEigenvalueEstimate is used, which is
+// suitable when the eigenvalues of a are real and positive. It panics if the
+// degree is negative.
+func NewNeumann(a sparse.LinearOperator, degree int, omega float64) *Neumann {
+	if degree < 0 {
+		panic("iterative: negative polynomial degree")
+	}
+	if omega == 0 {
+		omega = 1 / maxEigenvalueEstimate(a)
+	}
+	n, _ := a.Dims()
+	return &Neumann{
+		a:      a,
+		degree: degree,
+		omega:  omega,
+		ay:     make([]float64, n),
+	}
+}
+
+// PreconSolve computes dst = M⁻¹ r by the Horner scheme, which is degree
+// steps of the Richardson iteration for A dst = r started from ω r.
+func (p *Neumann) PreconSolve(dst, r []float64) {
+	for i, v := range r {
+		dst[i] = p.omega * v
+	}
+	for k := 0; k < p.degree; k++ {
+		// dst = dst + ω (r - A dst)
+		p.a.MulVecTo(p.ay, false, dst)
+		for i, v := range r {
+			dst[i] += p.omega * (v - p.ay[i])
+		}
+	}
+}
+
+// maxEigenvalueEstimate returns an estimate from above of the largest
+// eigenvalue of the symmetric operator a. If a is a sparse.Matrix and a
+// sparse.NonZeroDoer, the estimate is the maximum absolute row sum, which is
+// an upper bound by the Gershgorin theorem. Otherwise it is θ + β where θ is
+// the largest Ritz value after 20 steps of the Lanczos method and β the norm
+// of the next Lanczos vector before normalization. Unlike the estimate by
+// the power method, which approaches the largest eigenvalue from below,
+// θ + β usually lies above it, but it is not guaranteed to.
+func maxEigenvalueEstimate(a sparse.LinearOperator) float64 {
+	if m, ok := a.(sparse.Matrix); ok {
+		if _, ok := a.(sparse.NonZeroDoer); ok {
+			return normInf(m)
+		}
+	}
+	n, c := a.Dims()
+	if n != c {
+		panic("iterative: matrix is not square")
+	}
+	steps := 20
+	if steps > n {
+		steps = n
+	}
+	rnd := rand.New(rand.NewSource(1))
+	q := mat64.NewVector(n, nil)
+	for i := 0; i < n; i++ {
+		q.SetVec(i, rnd.Float64()-0.5)
+	}
+	q.ScaleVec(1/mat64.Norm(q, 2), q)
+	qPrev := mat64.NewVector(n, nil)
+	w := mat64.NewVector(n, nil)
+	var alpha, beta []float64
+	for k := 0; k < steps; k++ {
+		// w = A q - α q - β q_prev
+		mulVec(a, w, q)
+		alpha = append(alpha, mat64.Dot(q, w))
+		w.AddScaledVec(w, -alpha[k], q)
+		if k > 0 {
+			w.AddScaledVec(w, -beta[k-1], qPrev)
+		}
+		beta = append(beta, mat64.Norm(w, 2))
+		if beta[k] == 0 {
+			// The Krylov subspace is invariant and the Ritz values are
+			// eigenvalues.
+			break
+		}
+		qPrev, q, w = q, w, qPrev
+		q.ScaleVec(1/beta[k], q)
+	}
+	k := len(alpha)
+	_, theta := tridiagBounds(alpha, beta[:k-1])
+	return theta + beta[k-1]
+}
+
+// EstimateMaxEigenvalue estimates the spectral radius of the square operator
+// a by the given number of iterations of the power method started from
+// a fixed pseudo-random vector. The estimate approaches the spectral radius
+// from below when a is symmetric.
+func EstimateMaxEigenvalue(a sparse.LinearOperator, iterations int) float64 {
+	n, c := a.Dims()
+	if n != c {
+		panic("iterative: matrix is not square")
+	}
+	rnd := rand.New(rand.NewSource(1))
+	x := mat64.NewVector(n, nil)
+	for i := 0; i < n; i++ {
+		x.SetVec(i, rnd.Float64()-0.5)
+	}
+	x.ScaleVec(1/mat64.Norm(x, 2), x)
+	ax := mat64.NewVector(n, nil)
+	var lambda float64
+	for k := 0; k < iterations; k++ {
+		mulVec(a, ax, x)
+		lambda = mat64.Norm(ax, 2)
+		if lambda == 0 {
+			break
+		}
+		x.ScaleVec(1/lambda, ax)
+	}
+	return lambda
+}
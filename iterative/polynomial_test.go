@@ -0,0 +1,134 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math"
+	"testing"
+
+	"github.com/vladimir-ch/sparse"
+)
+
+// operator hides all methods of a except those of sparse.LinearOperator.
+type operator struct {
+	a sparse.LinearOperator
+}
+
+func (op operator) Dims() (r, c int) {
+	return op.a.Dims()
+}
+
+func (op operator) MulVecTo(dst []float64, trans bool, x []float64) {
+	op.a.MulVecTo(dst, trans, x)
+}
+
+// lap2DMax returns the largest eigenvalue of lap2D(n).
+func lap2DMax(n int) float64 {
+	h := math.Pi / float64(n+1)
+	return 8 * math.Pow(math.Cos(h/2), 2)
+}
+
+// lap2DMin returns the smallest eigenvalue of lap2D(n).
+func lap2DMin(n int) float64 {
+	h := math.Pi / float64(n+1)
+	return 8 * math.Pow(math.Sin(h/2), 2)
+}
+
+func TestMaxEigenvalueEstimate(t *testing.T) {
+	nos7 := readMatrix(t, "nos7.mtx.gz")
+	for _, test := range []struct {
+		name string
+		a    *sparse.CSR
+		// max is the largest eigenvalue of a rounded down.
+		max float64
+	}{
+		{"lap2D(3)", lap2D(3), lap2DMax(3) * (1 - 1e-12)},
+		{"lap2D(10)", lap2D(10), lap2DMax(10) * (1 - 1e-12)},
+		// The power method with 20 iterations estimates the largest
+		// eigenvalue of nos7 as 8.23e6.
+		{"nos7", nos7, 9.86e6},
+	} {
+		for _, op := range []struct {
+			name string
+			a    sparse.LinearOperator
+		}{
+			{"Gershgorin", test.a},
+			{"Lanczos", operator{test.a}},
+		} {
+			est := maxEigenvalueEstimate(op.a)
+			if est < test.max || est > 2*test.max {
+				t.Errorf("%s/%s: estimate %v not in [%v, %v]", test.name, op.name, est, test.max, 2*test.max)
+			}
+		}
+	}
+}
+
+func TestEstimateMaxEigenvalue(t *testing.T) {
+	a := lap2D(10)
+	max := lap2DMax(10)
+	est := EstimateMaxEigenvalue(a, 50)
+	if est > max*(1+1e-12) || est < 0.9*max {
+		t.Errorf("want estimate in [%v, %v], got %v", 0.9*max, max, est)
+	}
+}
+
+func TestPolynomialPreconditioners(t *testing.T) {
+	const n = 10
+	a := lap2D(n)
+	nos7 := readMatrix(t, "nos7.mtx.gz")
+	for _, test := range []struct {
+		name   string
+		a      *sparse.CSR
+		method Method
+		precon Preconditioner
+	}{
+		{"CG/ChebyshevPolynomial(0)", a, &CG{}, NewChebyshevPolynomial(a, 0, 0, 0)},
+		{"CG/ChebyshevPolynomial(4)", a, &CG{}, NewChebyshevPolynomial(a, 4, 0, 0)},
+		{"CG/ChebyshevPolynomial(4)/Lanczos", a, &CG{}, NewChebyshevPolynomial(operator{a}, 4, 0, 0)},
+		{"CG/ChebyshevPolynomial(6)/exact", a, &CG{}, NewChebyshevPolynomial(a, 6, lap2DMin(n), lap2DMax(n))},
+		{"CG/Neumann(3)", a, &CG{}, NewNeumann(a, 3, 0)},
+		{"BiCGStab(2)/Neumann(5)", a, &BiCGStabL{}, NewNeumann(a, 5, 0.2)},
+		{"Chebyshev/ChebyshevPolynomial(3)", a, &Chebyshev{}, NewChebyshevPolynomial(a, 3, 0, 0)},
+		// The polynomial is positive definite only if its upper bound
+		// is above the spectrum of nos7.
+		{"nos7/CG/ChebyshevPolynomial(4)", nos7, &CG{}, NewChebyshevPolynomial(nos7, 4, 0, 0)},
+		{"nos7/MINRES/ChebyshevPolynomial(4)", nos7, &MINRES{}, NewChebyshevPolynomial(nos7, 4, 0, 0)},
+		{"nos7/MINRES/ChebyshevPolynomial(4)/Lanczos", nos7, &MINRES{}, NewChebyshevPolynomial(operator{nos7}, 4, 0, 0)},
+	} {
+		b, _ := rhs(test.a)
+		settings := DefaultSettings(b.Len())
+		settings.Tolerance = 1e-8
+		settings.Preconditioner = test.precon
+		result, err := Solve(test.a, b, nil, settings, test.method)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if res := trueResidual(test.a, b, result.X); res >= 1e-8 {
+			t.Errorf("%s: true relative residual %v not below 1e-8", test.name, res)
+		}
+	}
+}
+
+func TestPolynomialPanics(t *testing.T) {
+	a := lap2D(3)
+	for _, test := range []struct {
+		name string
+		fn   func()
+	}{
+		{"ChebyshevPolynomial/degree", func() { NewChebyshevPolynomial(a, -1, 0, 0) }},
+		{"ChebyshevPolynomial/bounds", func() { NewChebyshevPolynomial(a, 2, 2, 1) }},
+		{"Neumann/degree", func() { NewNeumann(a, -1, 0) }},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", test.name)
+				}
+			}()
+			test.fn()
+		}()
+	}
+}
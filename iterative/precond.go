@@ -0,0 +1,13 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+// Preconditioner is a preconditioner M for an iterative method. Methods that
+// require a symmetric positive definite preconditioner, such as CG and
+// MINRES, assume that M is so.
+type Preconditioner interface {
+	// PreconSolve solves M * dst = r. The slices do not overlap.
+	PreconSolve(dst, r []float64)
+}
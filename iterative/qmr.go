@@ -0,0 +1,192 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// QMR implements the Quasi-Minimal Residual method of Freund and Nachtigal
+// without look-ahead for solving the linear system Ax = b with a nonsymmetric
+// matrix A. It is based on the two-sided Lanczos process, so unlike CGS and
+// TFQMR it needs products with Aᵀ, which it requests by ComputeATq. The
+// preconditioner is applied from the left and must be symmetric because QMR
+// also applies its transpose.
+//
+// Without look-ahead, QMR breaks down when the Lanczos vectors become
+// orthogonal, that is, when δ = zᵀ y or ε = qᵀ A p is negligible compared to
+// the norms of the vectors. Negligible means less than BreakdownTolerance
+// times their product. If BreakdownTolerance is zero, machine epsilon is
+// used.
+type QMR struct {
+	BreakdownTolerance float64
+
+	resume int
+	first  bool
+
+	rho, rho1, xi     float64
+	delta, eps, beta  float64
+	gamma, theta, eta float64
+
+	vt, wt *mat64.Vector // Unnormalized Lanczos vectors ṽ and w̃.
+	v, w   *mat64.Vector
+	y, z   *mat64.Vector
+	p, q   *mat64.Vector
+	d, s   *mat64.Vector
+}
+
+func (qmr *QMR) Init(ctx *Context) Operation {
+	if qmr.BreakdownTolerance == 0 {
+		qmr.BreakdownTolerance = dlamchE
+	}
+	qmr.first = true
+
+	dim := ctx.X.Len()
+	qmr.vt = reuseVector(qmr.vt, dim)
+	qmr.wt = reuseVector(qmr.wt, dim)
+	qmr.v = reuseVector(qmr.v, dim)
+	qmr.w = reuseVector(qmr.w, dim)
+	qmr.y = reuseVector(qmr.y, dim)
+	qmr.z = reuseVector(qmr.z, dim)
+	qmr.p = reuseVector(qmr.p, dim)
+	qmr.q = reuseVector(qmr.q, dim)
+	qmr.d = reuseVector(qmr.d, dim)
+	qmr.s = reuseVector(qmr.s, dim)
+	ctx.P = reuseVector(ctx.P, dim)
+	ctx.Ap = reuseVector(ctx.Ap, dim)
+	ctx.Q = reuseVector(ctx.Q, dim)
+	ctx.Aq = reuseVector(ctx.Aq, dim)
+	ctx.Z = reuseVector(ctx.Z, dim)
+
+	qmr.resume = 1
+	return SolvePreconditioner
+	// Solve M y = r_0
+}
+
+func (qmr *QMR) Iterate(ctx *Context) Operation {
+	switch qmr.resume {
+	case 1:
+		// ṽ = r_0, ρ = ‖y‖
+		qmr.vt.CopyVec(ctx.Residual)
+		qmr.y.CopyVec(ctx.Z)
+		qmr.rho = mat64.Norm(qmr.y, 2)
+		// w̃ = z = r_0, ξ = ‖z‖
+		qmr.wt.CopyVec(ctx.Residual)
+		qmr.z.CopyVec(qmr.wt)
+		qmr.xi = mat64.Norm(qmr.z, 2)
+		qmr.theta = 0
+		qmr.gamma = 1
+		qmr.eta = -1
+		fallthrough
+	case 2:
+		if qmr.rho == 0 || qmr.xi == 0 {
+			ctx.Err = &BreakdownError{Method: "QMR", Quantity: "ρ ξ", Value: qmr.rho * qmr.xi}
+			qmr.resume = 0
+			return CheckConvergence
+		}
+		// v = ṽ / ρ, y = y / ρ
+		qmr.v.ScaleVec(1/qmr.rho, qmr.vt)
+		qmr.y.ScaleVec(1/qmr.rho, qmr.y)
+		// w = w̃ / ξ, z = z / ξ
+		qmr.w.ScaleVec(1/qmr.xi, qmr.wt)
+		qmr.z.ScaleVec(1/qmr.xi, qmr.z)
+		// δ = z · y
+		qmr.delta = mat64.Dot(qmr.z, qmr.y)
+		if negligible(qmr.delta, qmr.z, qmr.y, qmr.BreakdownTolerance) {
+			ctx.Err = &BreakdownError{Method: "QMR", Quantity: "δ", Value: qmr.delta}
+			qmr.resume = 0
+			return CheckConvergence
+		}
+
+		ctx.Q.CopyVec(qmr.z)
+		qmr.resume = 3
+		return SolvePreconditionerQ
+		// Solve Mᵀ z̃ = z
+	case 3:
+		if qmr.first {
+			// p = y, q = z̃
+			qmr.p.CopyVec(qmr.y)
+			qmr.q.CopyVec(ctx.Z)
+		} else {
+			// p = y - (ξ δ / ε) p
+			qmr.p.AddScaledVec(qmr.y, -qmr.xi*qmr.delta/qmr.eps, qmr.p)
+			// q = z̃ - (ρ δ / ε) q
+			qmr.q.AddScaledVec(ctx.Z, -qmr.rho*qmr.delta/qmr.eps, qmr.q)
+		}
+
+		ctx.P.CopyVec(qmr.p)
+		qmr.resume = 4
+		return ComputeAp
+		// Compute p̃ = A p
+	case 4:
+		// ε = q · p̃
+		qmr.eps = mat64.Dot(qmr.q, ctx.Ap)
+		if negligible(qmr.eps, qmr.q, ctx.Ap, qmr.BreakdownTolerance) {
+			ctx.Err = &BreakdownError{Method: "QMR", Quantity: "ε", Value: qmr.eps}
+			qmr.resume = 0
+			return CheckConvergence
+		}
+		// β = ε / δ
+		qmr.beta = qmr.eps / qmr.delta
+		// ṽ = p̃ - β v
+		qmr.vt.AddScaledVec(ctx.Ap, -qmr.beta, qmr.v)
+
+		ctx.Q.CopyVec(qmr.vt)
+		qmr.resume = 5
+		return SolvePreconditionerQ
+		// Solve M y = ṽ
+	case 5:
+		qmr.y.CopyVec(ctx.Z)
+		qmr.rho1 = qmr.rho
+		qmr.rho = mat64.Norm(qmr.y, 2)
+
+		ctx.Q.CopyVec(qmr.q)
+		qmr.resume = 6
+		return ComputeATq
+		// Compute Aᵀ q
+	case 6:
+		// w̃ = Aᵀ q - β w, z = w̃
+		qmr.wt.AddScaledVec(ctx.Aq, -qmr.beta, qmr.w)
+		qmr.z.CopyVec(qmr.wt)
+		qmr.xi = mat64.Norm(qmr.z, 2)
+
+		// θ = ρ / (γ_old |β|), γ = 1 / √(1 + θ²)
+		theta1, gamma1 := qmr.theta, qmr.gamma
+		qmr.theta = qmr.rho / (gamma1 * math.Abs(qmr.beta))
+		qmr.gamma = 1 / math.Sqrt(1+qmr.theta*qmr.theta)
+		if qmr.gamma == 0 {
+			ctx.Err = &BreakdownError{Method: "QMR", Quantity: "γ", Value: qmr.gamma}
+			qmr.resume = 0
+			return CheckConvergence
+		}
+		// η = -η ρ_old γ² / (β γ_old²)
+		qmr.eta *= -qmr.rho1 * qmr.gamma * qmr.gamma / (qmr.beta * gamma1 * gamma1)
+
+		if qmr.first {
+			// d = η p, s = η p̃
+			qmr.d.ScaleVec(qmr.eta, qmr.p)
+			qmr.s.ScaleVec(qmr.eta, ctx.Ap)
+		} else {
+			// d = η p + (θ_old γ)² d, s = η p̃ + (θ_old γ)² s
+			c := theta1 * qmr.gamma * theta1 * qmr.gamma
+			qmr.d.ScaleVec(c, qmr.d)
+			qmr.d.AddScaledVec(qmr.d, qmr.eta, qmr.p)
+			qmr.s.ScaleVec(c, qmr.s)
+			qmr.s.AddScaledVec(qmr.s, qmr.eta, ctx.Ap)
+		}
+		qmr.first = false
+
+		// x = x + d, r = r - s
+		ctx.X.AddVec(ctx.X, qmr.d)
+		ctx.Residual.SubVec(ctx.Residual, qmr.s)
+
+		qmr.resume = 2
+		return CheckConvergence
+	default:
+		panic("unreachable")
+	}
+}
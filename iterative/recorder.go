@@ -0,0 +1,103 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"log"
+	"time"
+)
+
+// Recorder observes the progress of an iterative method. Init is called once
+// before the iteration starts and Record after every convergence check with
+// the current statistics. If either returns an error, the solve is stopped
+// and the error is returned.
+type Recorder interface {
+	Init() error
+	Record(Stats) error
+}
+
+// SilentRecorder is a Recorder that discards all records. It is equivalent
+// to a nil Recorder in Settings.
+type SilentRecorder struct{}
+
+func (SilentRecorder) Init() error        { return nil }
+func (SilentRecorder) Record(Stats) error { return nil }
+
+// LogRecorder is a Recorder that writes a line with the iteration number,
+// the relative residual, the elapsed time and the operation counts to
+// Logger. If Logger is nil, the standard logger is used.
+type LogRecorder struct {
+	Logger *log.Logger
+}
+
+func (r *LogRecorder) Init() error {
+	return nil
+}
+
+func (r *LogRecorder) Record(s Stats) error {
+	const format = "iter %d: residual %.6e, elapsed %v, matvec %d, precond %d"
+	args := []interface{}{s.Iterations, s.Residual, s.Runtime, s.MatVecMultiplies, s.PrecondionerSolves}
+	if r.Logger == nil {
+		log.Printf(format, args...)
+	} else {
+		r.Logger.Printf(format, args...)
+	}
+	return nil
+}
+
+// HistoryRecorder is a Recorder that keeps the statistics of all iterations
+// in memory.
+type HistoryRecorder struct {
+	History []Stats
+}
+
+func (r *HistoryRecorder) Init() error {
+	r.History = r.History[:0]
+	return nil
+}
+
+func (r *HistoryRecorder) Record(s Stats) error {
+	r.History = append(r.History, s)
+	return nil
+}
+
+// Residuals returns the relative residuals of the recorded iterations.
+func (r *HistoryRecorder) Residuals() []float64 {
+	res := make([]float64, len(r.History))
+	for i, s := range r.History {
+		res[i] = s.Residual
+	}
+	return res
+}
+
+// Runtimes returns the elapsed times of the recorded iterations.
+func (r *HistoryRecorder) Runtimes() []time.Duration {
+	rt := make([]time.Duration, len(r.History))
+	for i, s := range r.History {
+		rt[i] = s.Runtime
+	}
+	return rt
+}
+
+// initRecorder calls Init on the recorder in settings, if any.
+func initRecorder(settings *Settings) error {
+	if settings.Recorder == nil {
+		return nil
+	}
+	return settings.Recorder.Init()
+}
+
+// record updates the elapsed time in stats, appends the residual to history
+// if requested by settings and passes stats to the recorder.
+func record(settings *Settings, stats *Stats, history *[]float64) error {
+	stats.Runtime = time.Since(stats.StartTime)
+	if settings.History {
+		*history = append(*history, stats.Residual)
+	}
+	if settings.Recorder == nil {
+		return nil
+	}
+	return settings.Recorder.Record(*stats)
+}
@@ -0,0 +1,115 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+// failRecorder fails at the given iteration.
+type failRecorder struct {
+	iter int
+}
+
+func (r *failRecorder) Init() error { return nil }
+
+func (r *failRecorder) Record(s Stats) error {
+	if s.Iterations == r.iter {
+		return errors.New("recorder failed")
+	}
+	return nil
+}
+
+func TestRecorder(t *testing.T) {
+	a := lap2D(10)
+	b, _ := rhs(a)
+	n := b.Len()
+
+	var buf bytes.Buffer
+	hist := &HistoryRecorder{}
+	for _, test := range []struct {
+		name string
+		rec  Recorder
+	}{
+		{"nil", nil},
+		{"SilentRecorder", SilentRecorder{}},
+		{"LogRecorder", &LogRecorder{Logger: log.New(&buf, "", 0)}},
+		{"HistoryRecorder", hist},
+	} {
+		settings := DefaultSettings(n)
+		settings.Recorder = test.rec
+		settings.History = true
+		result, err := Solve(a, b, nil, settings, &CG{})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if len(result.History) != result.Stats.Iterations {
+			t.Errorf("%s: want %v history entries, got %v", test.name, result.Stats.Iterations, len(result.History))
+		}
+		if len(result.History) > 0 && result.History[len(result.History)-1] != result.Stats.Residual {
+			t.Errorf("%s: want last history entry %v, got %v", test.name, result.Stats.Residual, result.History[len(result.History)-1])
+		}
+		if result.Runtime <= 0 {
+			t.Errorf("%s: want positive runtime, got %v", test.name, result.Runtime)
+		}
+	}
+
+	if len(hist.History) == 0 {
+		t.Fatal("HistoryRecorder: no statistics recorded")
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(hist.History) {
+		t.Errorf("LogRecorder: want %v lines, got %v", len(hist.History), lines)
+	}
+	if !strings.HasPrefix(buf.String(), "iter 1: residual ") {
+		t.Errorf("LogRecorder: unexpected output %q", buf.String())
+	}
+	res := hist.Residuals()
+	rt := hist.Runtimes()
+	for i, s := range hist.History {
+		if rt[i] != s.Runtime || s.Runtime <= 0 {
+			t.Errorf("HistoryRecorder: want positive runtime %v, got %v", s.Runtime, rt[i])
+		}
+		if i > 0 && s.Runtime < hist.History[i-1].Runtime {
+			t.Errorf("HistoryRecorder: runtime decreased from %v to %v", hist.History[i-1].Runtime, s.Runtime)
+		}
+		if s.Iterations != i+1 {
+			t.Errorf("HistoryRecorder: want iteration %v, got %v", i+1, s.Iterations)
+		}
+		if res[i] != s.Residual {
+			t.Errorf("HistoryRecorder: want residual %v, got %v", s.Residual, res[i])
+		}
+	}
+	if last := res[len(res)-1]; last >= 1e-6 {
+		t.Errorf("HistoryRecorder: last residual %v not below tolerance", last)
+	}
+
+	// Init must reset the history.
+	settings := DefaultSettings(n)
+	settings.Recorder = hist
+	settings.Iterations = 2
+	Solve(a, b, nil, settings, &CG{})
+	if len(hist.History) != 2 {
+		t.Errorf("HistoryRecorder: want 2 records after Init, got %v", len(hist.History))
+	}
+}
+
+func TestRecorderError(t *testing.T) {
+	a := lap2D(10)
+	b, _ := rhs(a)
+	settings := DefaultSettings(b.Len())
+	settings.Recorder = &failRecorder{iter: 3}
+	result, err := Solve(a, b, nil, settings, &CG{})
+	if err == nil || err.Error() != "recorder failed" {
+		t.Errorf("want recorder error, got %v", err)
+	}
+	if result.Stats.Iterations != 3 {
+		t.Errorf("want 3 iterations, got %v", result.Stats.Iterations)
+	}
+}
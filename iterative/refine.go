@@ -0,0 +1,362 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/gonum/blas"
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+// Corrector solves the correction equation A * d = r of iterative refinement
+// in single precision. It may be backed by a factorization of A computed in
+// single precision or by an inner Krylov method, and the solution needs to be
+// only approximate.
+type Corrector interface {
+	SolveCorrection(d, r []float32) error
+}
+
+// RefineResult is the result of Refine.
+type RefineResult struct {
+	Result
+
+	// RefinementSteps is the number of corrections applied to X.
+	RefinementSteps int
+	// BackwardError is the normwise backward error of X.
+	BackwardError float64
+}
+
+// Refine solves the linear system A * x = b by mixed-precision iterative
+// refinement. In every step, the residual r = b - A*x is computed in double
+// precision, the correction equation A * d = r is solved in single precision
+// by c and the solution is updated as x = x + d.
+//
+// Refine stops when the normwise backward error
+//
+//  ‖b - A*x‖_∞ / (‖A‖_∞ ‖x‖_∞ + ‖b‖_∞)
+//
+// is less than settings.Tolerance, or when settings.Stop reports convergence
+// if it is not nil. If A is a sparse.Matrix, ‖A‖_∞ is computed from its
+// entries, otherwise it is estimated from products with A and Aᵀ. The
+// ConvergenceState of settings.Stop holds the Euclidean norms of the residual
+// and of b, and the preconditioned residual is the residual itself. Every
+// refinement step counts as an iteration in Stats, so settings.Iterations
+// limits the number of refinement steps. settings.MaxRuntime limits their
+// duration and the recorder in settings is called after every step.
+// settings.Preconditioner is not used, c takes its role. If settings is nil,
+// the tolerance is 1e-14 and the number of steps is limited to 30.
+//
+// An error is returned if a limit is reached, if settings.Stop returns one,
+// or ErrStagnation if the backward error stops decreasing. RefineResult then
+// holds the iterate with the smallest backward error.
+func Refine(a sparse.LinearOperator, b, xInit *mat64.Vector, settings *Settings, c Corrector) (result RefineResult, err error) {
+	stats := Stats{
+		StartTime: time.Now(),
+	}
+
+	dim, cols := a.Dims()
+	if dim != cols {
+		panic("iterative: matrix is not square")
+	}
+	if xInit != nil && dim != xInit.Len() {
+		panic("iterative: mismatched size of the initial guess")
+	}
+	if b.Len() != dim {
+		panic("iterative: mismatched size of the right-hand side vector")
+	}
+
+	if settings == nil {
+		settings = &Settings{
+			Tolerance:  1e-14,
+			Iterations: 30,
+		}
+	}
+
+	x := mat64.NewVector(dim, nil)
+	if xInit != nil {
+		x.CopyVec(xInit)
+	}
+	r := mat64.NewVector(dim, nil)
+	r32 := make([]float32, dim)
+	d32 := make([]float32, dim)
+
+	var aNorm float64
+	if m, ok := a.(sparse.Matrix); ok {
+		aNorm = normInf(m)
+	} else {
+		aNorm = estimateNormInf(a)
+	}
+	bNorm := mat64.Norm(b, math.Inf(1))
+	bNorm2 := mat64.Norm(b, 2)
+	if bNorm2 == 0 {
+		bNorm2 = 1
+	}
+	var history []float64
+	if err = initRecorder(settings); err != nil {
+		return RefineResult{Result: Result{X: x, Stats: stats}}, err
+	}
+	state := ConvergenceState{
+		RHSNorm: mat64.Norm(b, 2),
+		solutionNorm: func() float64 {
+			return mat64.Norm(x, 2)
+		},
+		precResidualNorm: func() float64 {
+			return mat64.Norm(r, 2)
+		},
+	}
+	var berr float64
+	best := &bestIterate[float64]{residual: math.Inf(1)}
+	var bestResidual float64
+	prevBerr := math.Inf(1)
+	for {
+		// r = b - A*x
+		mulVec(a, r, x)
+		r.SubVec(b, r)
+		stats.MatVecMultiplies++
+
+		rNorm := mat64.Norm(r, math.Inf(1))
+		rNorm2 := mat64.Norm(r, 2)
+		stats.Residual = rNorm2 / bNorm2
+		denom := aNorm*mat64.Norm(x, math.Inf(1)) + bNorm
+		if denom == 0 {
+			// Both A and b are zero, any x is a solution.
+			denom = 1
+		}
+		berr = rNorm / denom
+		if berr < best.residual {
+			best.update(berr, x.RawVector().Data[:dim])
+			bestResidual = stats.Residual
+		}
+		if err = record(settings, &stats, &history); err != nil {
+			break
+		}
+		converged := berr < settings.Tolerance
+		if settings.Stop != nil {
+			state.next(stats.Iterations, rNorm2)
+			if stats.Iterations == 0 {
+				settings.Stop.Init(&state)
+			}
+			converged, err = settings.Stop.Check(&state)
+			if err != nil {
+				break
+			}
+		}
+		if converged {
+			break
+		}
+		if settings.MaxRuntime > 0 && time.Since(stats.StartTime) >= settings.MaxRuntime {
+			err = ErrTimeLimit
+			break
+		}
+		if stats.Iterations == settings.Iterations {
+			err = ErrIterationLimit
+			break
+		}
+		if berr > prevBerr/2 {
+			err = ErrStagnation
+			break
+		}
+		prevBerr = berr
+
+		// Scale the residual so that it does not underflow in single
+		// precision.
+		raw := r.RawVector()
+		for i := range r32 {
+			r32[i] = float32(raw.Data[i*raw.Inc] / rNorm)
+		}
+		for i := range d32 {
+			d32[i] = 0
+		}
+		if err = c.SolveCorrection(d32, r32); err != nil {
+			break
+		}
+		stats.PrecondionerSolves++
+
+		raw = x.RawVector()
+		for i, v := range d32 {
+			raw.Data[i*raw.Inc] += rNorm * float64(v)
+		}
+		stats.Iterations++
+	}
+
+	if err != nil && best.better(berr) {
+		x = mat64.NewVector(dim, best.x)
+		berr = best.residual
+		stats.Residual = bestResidual
+	}
+	result = RefineResult{
+		Result: Result{
+			X:       x,
+			Stats:   stats,
+			Runtime: time.Since(stats.StartTime),
+			History: history,
+		},
+		RefinementSteps: stats.Iterations,
+		BackwardError:   berr,
+	}
+	return result, err
+}
+
+// normInf returns the maximum absolute row sum of a.
+func normInf(a sparse.Matrix) float64 {
+	r, c := a.Dims()
+	sum := make([]float64, r)
+	if nz, ok := a.(sparse.NonZeroDoer); ok {
+		nz.DoNonZero(func(i, _ int, v float64) {
+			sum[i] += math.Abs(v)
+		})
+	} else {
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				sum[i] += math.Abs(a.At(i, j))
+			}
+		}
+	}
+	var norm float64
+	for _, s := range sum {
+		norm = math.Max(norm, s)
+	}
+	return norm
+}
+
+// estimateNormInf returns an estimate of the maximum absolute row sum of a
+// computed by Hager's method from products with a and its transpose. The
+// estimate is a lower bound that is usually exact or close to ‖A‖_∞.
+func estimateNormInf(a sparse.LinearOperator) float64 {
+	n, _ := a.Dims()
+	if n == 0 {
+		return 0
+	}
+	// ‖A‖_∞ = ‖Aᵀ‖_1, which is estimated by maximizing ‖Aᵀ x‖_1 over the
+	// unit ball of the 1-norm.
+	x := make([]float64, n)
+	y := make([]float64, n)
+	z := make([]float64, n)
+	for i := range x {
+		x[i] = 1 / float64(n)
+	}
+	var est float64
+	for k := 0; k < 5; k++ {
+		// y = Aᵀ x
+		a.MulVecTo(y, true, x)
+		var yNorm float64
+		for i, v := range y {
+			yNorm += math.Abs(v)
+			// ξ = sign(y)
+			y[i] = 1
+			if v < 0 {
+				y[i] = -1
+			}
+		}
+		if k > 0 && yNorm <= est {
+			break
+		}
+		est = yNorm
+		// z = A ξ
+		a.MulVecTo(z, false, y)
+		var zx float64
+		j := 0
+		for i, v := range z {
+			zx += v * x[i]
+			if math.Abs(v) > math.Abs(z[j]) {
+				j = i
+			}
+		}
+		if math.Abs(z[j]) <= zx {
+			break
+		}
+		// x = e_j
+		for i := range x {
+			x[i] = 0
+		}
+		x[j] = 1
+	}
+	return est
+}
+
+// CG32 is a Corrector that solves the correction equation by the conjugate
+// gradient method in single precision. A must be symmetric positive
+// definite, for example a single precision copy of the matrix of the system
+// obtained by sparse.ConvertCSR.
+type CG32 struct {
+	A *sparse.CSROf[float32]
+
+	// Tolerance is the relative residual at which the inner iteration
+	// stops. If it is zero, 1e-4 is used.
+	Tolerance float64
+	// Iterations is the maximum number of inner iterations. If it is
+	// zero, the number is not limited beyond the dimension of A.
+	Iterations int
+
+	p, ap, res []float32
+}
+
+func (cg *CG32) SolveCorrection(d, r []float32) error {
+	n, _ := cg.A.Dims()
+	if len(d) != n || len(r) != n {
+		panic("iterative: dimension mismatch")
+	}
+	tol := cg.Tolerance
+	if tol == 0 {
+		tol = 1e-4
+	}
+	maxIter := cg.Iterations
+	if maxIter <= 0 {
+		maxIter = n
+	}
+	if len(cg.p) != n {
+		cg.p = make([]float32, n)
+		cg.ap = make([]float32, n)
+		cg.res = make([]float32, n)
+	}
+
+	// d = 0, res = r, p = r
+	for i := range d {
+		d[i] = 0
+	}
+	copy(cg.res, r)
+	copy(cg.p, r)
+	rho := dot32(cg.res, cg.res)
+	rNorm := math.Sqrt(float64(rho))
+	if rNorm == 0 {
+		return nil
+	}
+	for k := 0; k < maxIter; k++ {
+		for i := range cg.ap {
+			cg.ap[i] = 0
+		}
+		sparse.MulMatVecOf(cg.ap, 1, blas.NoTrans, cg.A, cg.p)
+		pap := dot32(cg.p, cg.ap)
+		if pap <= 0 {
+			return errors.New("iterative: matrix not positive definite")
+		}
+		alpha := rho / pap
+		for i := range d {
+			d[i] += alpha * cg.p[i]
+			cg.res[i] -= alpha * cg.ap[i]
+		}
+		rho1 := rho
+		rho = dot32(cg.res, cg.res)
+		if math.Sqrt(float64(rho)) < tol*rNorm {
+			break
+		}
+		beta := rho / rho1
+		for i := range cg.p {
+			cg.p[i] = cg.res[i] + beta*cg.p[i]
+		}
+	}
+	return nil
+}
+
+func dot32(x, y []float32) (dot float32) {
+	for i, v := range x {
+		dot += v * y[i]
+	}
+	return dot
+}
@@ -0,0 +1,205 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+// backwardError returns ‖b - A*x‖_∞ / (‖A‖_∞ ‖x‖_∞ + ‖b‖_∞).
+func backwardError(a *sparse.CSR, b, x *mat64.Vector) float64 {
+	n, _ := a.Dims()
+	var aNorm float64
+	for i := 0; i < n; i++ {
+		var sum float64
+		for j := 0; j < n; j++ {
+			sum += math.Abs(a.At(i, j))
+		}
+		aNorm = math.Max(aNorm, sum)
+	}
+	r := mat64.NewVector(n, nil)
+	r.CopyVec(b)
+	sparse.MulMatVec(r, -1, false, a, x)
+	inf := math.Inf(1)
+	return mat64.Norm(r, inf) / (aNorm*mat64.Norm(x, inf) + mat64.Norm(b, inf))
+}
+
+func TestRefine(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// berr bounds the backward error of the result.
+		berr float64
+		err  error
+	}{
+		{"gr_30_30.mtx.gz", 1e-14, nil},
+		// The condition number of nos7 is about 4e9, which exceeds
+		// the reciprocal of the float32 machine epsilon, so the
+		// corrections computed by CG32 do not contract and the
+		// refinement stagnates far from the working precision.
+		{"nos7.mtx.gz", 1e-4, ErrStagnation},
+	} {
+		a := readMatrix(t, test.name)
+		b, _ := rhs(a)
+		settings := &Settings{
+			Tolerance:  1e-14,
+			Iterations: 30,
+			History:    true,
+		}
+		c := &CG32{A: sparse.ConvertCSR[float32](a)}
+		result, err := Refine(a, b, nil, settings, c)
+		if err != test.err {
+			t.Errorf("%s: want error %v, got %v", test.name, test.err, err)
+			continue
+		}
+		berr := backwardError(a, b, result.X)
+		if berr >= test.berr {
+			t.Errorf("%s: backward error %v not below %v", test.name, berr, test.berr)
+		}
+		if math.Abs(result.BackwardError-berr) > 1e-3*berr {
+			t.Errorf("%s: want reported backward error %v, got %v", test.name, berr, result.BackwardError)
+		}
+		if result.Stats.Iterations != result.RefinementSteps {
+			t.Errorf("%s: want %v iterations, got %v", test.name, result.RefinementSteps, result.Stats.Iterations)
+		}
+		if res := trueResidual(a, b, result.X); math.Abs(result.Stats.Residual-res) > 1e-6*res {
+			t.Errorf("%s: want reported residual %v of the returned iterate, got %v", test.name, res, result.Stats.Residual)
+		}
+		if len(result.History) != result.Stats.Iterations+1 {
+			t.Errorf("%s: want %v history entries, got %v", test.name, result.Stats.Iterations+1, len(result.History))
+		}
+	}
+}
+
+func TestRefineSettings(t *testing.T) {
+	a := readMatrix(t, "gr_30_30.mtx.gz")
+	b, _ := rhs(a)
+	c := &CG32{A: sparse.ConvertCSR[float32](a)}
+
+	// Settings.Iterations limits the number of refinement steps.
+	result, err := Refine(a, b, nil, &Settings{Tolerance: 1e-300, Iterations: 1}, c)
+	if err != ErrIterationLimit {
+		t.Errorf("want %v, got %v", ErrIterationLimit, err)
+	}
+	if result.Stats.Iterations != 1 {
+		t.Errorf("want 1 iteration, got %v", result.Stats.Iterations)
+	}
+
+	// Settings.Stop replaces the backward error test.
+	settings := &Settings{
+		Tolerance:  1e-300,
+		Iterations: 30,
+		Stop:       RelativeResidual{Tolerance: 1e-10},
+	}
+	result, err = Refine(a, b, nil, settings, c)
+	if err != nil {
+		t.Fatalf("unexpected error with Settings.Stop: %v", err)
+	}
+	if res := trueResidual(a, b, result.X); res >= 1e-10 {
+		t.Errorf("true relative residual %v not below 1e-10", res)
+	}
+}
+
+// lu32 is a Corrector that solves the correction equation with a dense LU
+// factorization with partial pivoting computed in single precision. Unlike
+// CG32 it does not need a symmetric matrix.
+type lu32 struct {
+	n    int
+	lu   []float32
+	perm []int
+}
+
+func newLU32(a *sparse.CSR) *lu32 {
+	n, _ := a.Dims()
+	f := &lu32{n: n, lu: make([]float32, n*n), perm: make([]int, n)}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			f.lu[i*n+j] = float32(a.At(i, j))
+		}
+	}
+	lu := f.lu
+	for k := 0; k < n; k++ {
+		p := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(float64(lu[i*n+k])) > math.Abs(float64(lu[p*n+k])) {
+				p = i
+			}
+		}
+		f.perm[k] = p
+		for j := 0; j < n; j++ {
+			lu[k*n+j], lu[p*n+j] = lu[p*n+j], lu[k*n+j]
+		}
+		for i := k + 1; i < n; i++ {
+			lu[i*n+k] /= lu[k*n+k]
+			for j := k + 1; j < n; j++ {
+				lu[i*n+j] -= lu[i*n+k] * lu[k*n+j]
+			}
+		}
+	}
+	return f
+}
+
+func (f *lu32) SolveCorrection(d, r []float32) error {
+	n, lu := f.n, f.lu
+	copy(d, r)
+	for k, p := range f.perm {
+		d[k], d[p] = d[p], d[k]
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < i; j++ {
+			d[i] -= lu[i*n+j] * d[j]
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := i + 1; j < n; j++ {
+			d[i] -= lu[i*n+j] * d[j]
+		}
+		d[i] /= lu[i*n+i]
+	}
+	return nil
+}
+
+func TestRefineNonsymmetric(t *testing.T) {
+	a := convDiff(10, 20)
+	b, _ := rhs(a)
+	c := newLU32(a)
+	for _, test := range []struct {
+		name string
+		a    sparse.LinearOperator
+	}{
+		{"matrix", a},
+		// An operator without entries makes Refine estimate ‖A‖_∞.
+		{"operator", operator{a}},
+	} {
+		result, err := Refine(test.a, b, nil, nil, c)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		berr := backwardError(a, b, result.X)
+		if berr >= 1e-14 {
+			t.Errorf("%s: backward error %v not below 1e-14", test.name, berr)
+		}
+		if math.Abs(result.BackwardError-berr) > 1e-3*berr {
+			t.Errorf("%s: want reported backward error %v, got %v", test.name, berr, result.BackwardError)
+		}
+		if result.RefinementSteps == 0 || result.RefinementSteps > 5 {
+			t.Errorf("%s: unexpected number of refinement steps %v", test.name, result.RefinementSteps)
+		}
+	}
+}
+
+func TestEstimateNormInf(t *testing.T) {
+	for _, a := range []*sparse.CSR{lap2D(5), convDiff(6, 20), convDiff(4, -3)} {
+		want := normInf(a)
+		got := estimateNormInf(operator{a})
+		if got > want*(1+1e-14) || got < want/2 {
+			t.Errorf("want estimate of ‖A‖_∞ = %v, got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"github.com/vladimir-ch/sparse"
+)
+
+// The stationary methods in this file are defined by a splitting A = M - N
+// and iterate
+//
+//  x_{k+1} = x_k + M⁻¹ (b - A x_k).
+//
+// Each of them is a Method that can be passed to Solve with the matrix A,
+// and a Preconditioner that applies M⁻¹, for example as a smoother or
+// a preconditioner for a Krylov method. When used as a Method, the method
+// itself replaces the preconditioner in Settings, so its solves are counted
+// in Stats.PrecondionerSolves.
+
+// Sweep is the direction of a Gauss-Seidel or SOR sweep.
+type Sweep int
+
+const (
+	// Forward sweeps the unknowns in increasing order.
+	Forward Sweep = iota
+	// Backward sweeps the unknowns in decreasing order.
+	Backward
+	// Symmetric performs a forward sweep followed by a backward sweep.
+	Symmetric
+)
+
+// Jacobi is the Jacobi method with the splitting M = D where D is the
+// diagonal of A.
+type Jacobi struct {
+	diag   []float64
+	resume int
+}
+
+// NewJacobi returns the Jacobi method for the square matrix a. It panics if
+// a diagonal entry of a is zero.
+func NewJacobi(a *sparse.CSR) *Jacobi {
+	return &Jacobi{diag: diagonal(a)}
+}
+
+func (j *Jacobi) PreconSolve(dst, r []float64) {
+	for i, v := range r {
+		dst[i] = v / j.diag[i]
+	}
+}
+
+func (j *Jacobi) Init(ctx *Context) Operation {
+	return stationaryInit(ctx, &j.resume)
+}
+
+func (j *Jacobi) Iterate(ctx *Context) Operation {
+	return stationaryIterate(ctx, &j.resume)
+}
+
+// GaussSeidel is the Gauss-Seidel method. A forward sweep corresponds to
+// the splitting M = D + L and a backward sweep to M = D + U where D, L and
+// U are the diagonal, strictly lower and strictly upper triangular parts of
+// A. A symmetric sweep corresponds to M = (D + L) D⁻¹ (D + U) which is
+// symmetric positive definite if A is.
+type GaussSeidel struct {
+	relaxation
+}
+
+// NewGaussSeidel returns the Gauss-Seidel method for the square matrix a
+// with the given sweep direction. It panics if a diagonal entry of a is
+// zero.
+func NewGaussSeidel(a *sparse.CSR, sweep Sweep) *GaussSeidel {
+	return &GaussSeidel{newRelaxation(a, 1, sweep)}
+}
+
+// SOR is the Successive Over-Relaxation method with the splitting
+// M = D/ω + L where D and L are the diagonal and strictly lower triangular
+// parts of A. For ω = 1 it is the Gauss-Seidel method with the forward
+// sweep.
+type SOR struct {
+	relaxation
+}
+
+// NewSOR returns the SOR method for the square matrix a with the relaxation
+// parameter omega which must be in the interval (0, 2). It panics if
+// a diagonal entry of a is zero.
+func NewSOR(a *sparse.CSR, omega float64) *SOR {
+	return &SOR{newRelaxation(a, omega, Forward)}
+}
+
+// SSOR is the Symmetric Successive Over-Relaxation method with the
+// splitting
+//
+//  M = ω/(2-ω) (D/ω + L) (D/ω)⁻¹ (D/ω + U)
+//
+// where D, L and U are the diagonal, strictly lower and strictly upper
+// triangular parts of A. M is symmetric positive definite if A is, so SSOR
+// can precondition CG.
+type SSOR struct {
+	relaxation
+}
+
+// NewSSOR returns the SSOR method for the square matrix a with the
+// relaxation parameter omega which must be in the interval (0, 2). It
+// panics if a diagonal entry of a is zero.
+func NewSSOR(a *sparse.CSR, omega float64) *SSOR {
+	return &SSOR{newRelaxation(a, omega, Symmetric)}
+}
+
+// relaxation implements Gauss-Seidel, SOR and SSOR.
+type relaxation struct {
+	a      *sparse.CSR
+	diag   []float64 // Diagonal of A divided by ω.
+	omega  float64
+	sweep  Sweep
+	resume int
+}
+
+func newRelaxation(a *sparse.CSR, omega float64, sweep Sweep) relaxation {
+	if omega <= 0 || omega >= 2 {
+		panic("iterative: relaxation parameter out of range")
+	}
+	diag := diagonal(a)
+	for i := range diag {
+		diag[i] /= omega
+	}
+	return relaxation{
+		a:     a,
+		diag:  diag,
+		omega: omega,
+		sweep: sweep,
+	}
+}
+
+func (s *relaxation) PreconSolve(dst, r []float64) {
+	switch s.sweep {
+	case Forward:
+		s.forward(dst, r)
+	case Backward:
+		copy(dst, r)
+		s.backward(dst)
+	case Symmetric:
+		// dst = (2-ω)/ω (D/ω + U)⁻¹ (D/ω) (D/ω + L)⁻¹ r
+		s.forward(dst, r)
+		for i, d := range s.diag {
+			dst[i] *= d
+		}
+		s.backward(dst)
+		if s.omega != 1 {
+			scale := (2 - s.omega) / s.omega
+			for i := range dst {
+				dst[i] *= scale
+			}
+		}
+	default:
+		panic("iterative: unknown sweep")
+	}
+}
+
+// forward solves (D/ω + L) dst = r.
+func (s *relaxation) forward(dst, r []float64) {
+	for i, d := range s.diag {
+		sum := r[i]
+		s.a.DoRowNonZero(i, func(_, j int, v float64) {
+			if j < i {
+				sum -= v * dst[j]
+			}
+		})
+		dst[i] = sum / d
+	}
+}
+
+// backward solves (D/ω + U) x = dst in place.
+func (s *relaxation) backward(dst []float64) {
+	for i := len(s.diag) - 1; i >= 0; i-- {
+		sum := dst[i]
+		s.a.DoRowNonZero(i, func(_, j int, v float64) {
+			if j > i {
+				sum -= v * dst[j]
+			}
+		})
+		dst[i] = sum / s.diag[i]
+	}
+}
+
+func (s *relaxation) Init(ctx *Context) Operation {
+	return stationaryInit(ctx, &s.resume)
+}
+
+func (s *relaxation) Iterate(ctx *Context) Operation {
+	return stationaryIterate(ctx, &s.resume)
+}
+
+// diagonal returns the diagonal of the square matrix a. It panics if
+// a diagonal entry is zero.
+func diagonal(a *sparse.CSR) []float64 {
+	n, c := a.Dims()
+	if n != c {
+		panic("iterative: matrix is not square")
+	}
+	diag := make([]float64, n)
+	for i := range diag {
+		a.DoRowNonZero(i, func(_, j int, v float64) {
+			if j == i {
+				diag[i] = v
+			}
+		})
+		if diag[i] == 0 {
+			panic("iterative: zero diagonal entry")
+		}
+	}
+	return diag
+}
+
+func stationaryInit(ctx *Context, resume *int) Operation {
+	dim := ctx.X.Len()
+	ctx.P = reuseVector(ctx.P, dim)
+	ctx.Ap = reuseVector(ctx.Ap, dim)
+	ctx.Z = reuseVector(ctx.Z, dim)
+
+	*resume = 1
+	return SolvePreconditioner
+	// Solve M z = r
+}
+
+// stationaryIterate performs the iteration of a stationary method. The
+// solve with M is requested from Solve, which applies the method itself as
+// the preconditioner.
+func stationaryIterate(ctx *Context, resume *int) Operation {
+	switch *resume {
+	case 1:
+		// p = M⁻¹ r
+		ctx.P.CopyVec(ctx.Z)
+		// x = x + p
+		ctx.X.AddVec(ctx.X, ctx.P)
+		*resume = 2
+		return ComputeAp
+		// Compute Ap
+	case 2:
+		// r = r - Ap
+		ctx.Residual.SubVec(ctx.Residual, ctx.Ap)
+		*resume = 3
+		return CheckConvergence
+	case 3:
+		*resume = 1
+		return SolvePreconditioner
+		// Solve M z = r
+	default:
+		panic("unreachable")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/vladimir-ch/sparse"
+)
+
+// stationary is a stationary method, which is also a preconditioner.
+type stationary interface {
+	Method
+	Preconditioner
+}
+
+// stationaryMethods returns the stationary methods for a with every sweep.
+func stationaryMethods(a *sparse.CSR) []struct {
+	name   string
+	method stationary
+} {
+	return []struct {
+		name   string
+		method stationary
+	}{
+		{"Jacobi", NewJacobi(a)},
+		{"GaussSeidel/Forward", NewGaussSeidel(a, Forward)},
+		{"GaussSeidel/Backward", NewGaussSeidel(a, Backward)},
+		{"GaussSeidel/Symmetric", NewGaussSeidel(a, Symmetric)},
+		{"SOR(0.8)", NewSOR(a, 0.8)},
+		{"SOR(1.5)", NewSOR(a, 1.5)},
+		{"SSOR(0.7)", NewSSOR(a, 0.7)},
+		{"SSOR(1.3)", NewSSOR(a, 1.3)},
+	}
+}
+
+func TestStationary(t *testing.T) {
+	for _, n := range []int{4, 12} {
+		// Both matrices are irreducibly diagonally dominant.
+		for _, p := range []struct {
+			name string
+			a    *sparse.CSR
+		}{
+			{fmt.Sprintf("lap2D(%d)", n), lap2D(n)},
+			{fmt.Sprintf("convDiff(%d)", n), convDiff(n, 0.6)},
+		} {
+			b, want := rhs(p.a)
+			for _, test := range stationaryMethods(p.a) {
+				name := test.name + "/" + p.name
+				settings := DefaultSettings(b.Len())
+				settings.Tolerance = 1e-10
+				settings.Iterations = 5000
+				result, err := Solve(p.a, b, nil, settings, test.method)
+				if err != nil {
+					t.Errorf("%s: unexpected error: %v", name, err)
+					continue
+				}
+				if res := trueResidual(p.a, b, result.X); res >= 1e-10 {
+					t.Errorf("%s: true relative residual %v not below 1e-10", name, res)
+				}
+				if result.Stats.PrecondionerSolves != result.Stats.Iterations {
+					t.Errorf("%s: want %v preconditioner solves, got %v", name, result.Stats.Iterations, result.Stats.PrecondionerSolves)
+				}
+				for i := 0; i < b.Len(); i++ {
+					if math.Abs(result.X.At(i, 0)-want.At(i, 0)) > 1e-7 {
+						t.Errorf("%s: unexpected solution at %d: want %v, got %v", name, i, want.At(i, 0), result.X.At(i, 0))
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestStationaryPreconditioner(t *testing.T) {
+	// The symmetric methods precondition CG on the symmetric matrix, all
+	// of them precondition BiCGStab(ℓ) on the nonsymmetric one.
+	sym := lap2D(12)
+	nonsym := convDiff(12, 0.6)
+	for _, p := range []struct {
+		name   string
+		a      *sparse.CSR
+		method func() Method
+	}{
+		{"lap2D", sym, func() Method { return &CG{} }},
+		{"convDiff", nonsym, func() Method { return &BiCGStabL{} }},
+	} {
+		b, _ := rhs(p.a)
+		for _, test := range stationaryMethods(p.a) {
+			if p.name == "lap2D" && !isSymmetricSweep(test.method) {
+				continue
+			}
+			name := test.name + "/" + p.name
+			settings := DefaultSettings(b.Len())
+			settings.Tolerance = 1e-10
+			settings.Preconditioner = test.method
+			result, err := Solve(p.a, b, nil, settings, p.method())
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", name, err)
+				continue
+			}
+			if res := trueResidual(p.a, b, result.X); res >= 1e-10 {
+				t.Errorf("%s: true relative residual %v not below 1e-10", name, res)
+			}
+		}
+	}
+}
+
+// isSymmetricSweep reports whether m is a stationary method with a symmetric
+// splitting.
+func isSymmetricSweep(m Preconditioner) bool {
+	switch m := m.(type) {
+	case *Jacobi, *SSOR:
+		return true
+	case *GaussSeidel:
+		return m.sweep == Symmetric
+	}
+	return false
+}
+
+func TestStationaryPreconditionerSolves(t *testing.T) {
+	// The preconditioner in settings is replaced by the method.
+	a := lap2D(4)
+	b, _ := rhs(a)
+	settings := DefaultSettings(b.Len())
+	settings.Preconditioner = diagPrecon(make([]float64, b.Len()))
+	result, err := Solve(a, b, nil, settings, NewJacobi(a))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Stats.PrecondionerSolves != result.Stats.Iterations {
+		t.Errorf("want %v preconditioner solves, got %v", result.Stats.Iterations, result.Stats.PrecondionerSolves)
+	}
+	if _, ok := settings.Preconditioner.(diagPrecon); !ok {
+		t.Errorf("settings modified")
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrStagnation is returned when the residual stops decreasing.
+	ErrStagnation = errors.New("iterative: iteration stagnated")
+	// ErrDivergence is returned when the residual grows too large.
+	ErrDivergence = errors.New("iterative: iteration diverged")
+)
+
+// ConvergenceState describes the iteration at a convergence check. The norms
+// are Euclidean norms.
+type ConvergenceState struct {
+	// Iteration is the number of completed iterations. It is zero for
+	// the check of the initial guess.
+	Iteration int
+	// ResidualNorm is the norm of the residual b - A*x.
+	ResidualNorm float64
+	// RHSNorm is the norm of the right-hand side b.
+	RHSNorm float64
+
+	solutionNorm     func() float64
+	precResidualNorm func() float64
+	xNorm, zNorm     float64
+}
+
+// SolutionNorm returns the norm of the current iterate x.
+func (s *ConvergenceState) SolutionNorm() float64 {
+	if math.IsNaN(s.xNorm) {
+		s.xNorm = s.solutionNorm()
+	}
+	return s.xNorm
+}
+
+// PreconditionedResidualNorm returns the norm of M⁻¹ r where M is the
+// preconditioner and r the residual. It costs a preconditioner solve the
+// first time it is called in an iteration.
+func (s *ConvergenceState) PreconditionedResidualNorm() float64 {
+	if math.IsNaN(s.zNorm) {
+		s.zNorm = s.precResidualNorm()
+	}
+	return s.zNorm
+}
+
+// next prepares s for the check of the given iteration.
+func (s *ConvergenceState) next(iteration int, rNorm float64) {
+	s.Iteration = iteration
+	s.ResidualNorm = rNorm
+	s.xNorm = math.NaN()
+	s.zNorm = math.NaN()
+}
+
+// StoppingCriterion decides when an iterative method stops. Init is called
+// with the state of the initial guess and Check after every iteration and
+// also for the initial guess. Check returns true if the iteration has
+// converged. If Check returns a non-nil error, the iteration stops without
+// convergence.
+type StoppingCriterion interface {
+	Init(*ConvergenceState)
+	Check(*ConvergenceState) (bool, error)
+}
+
+// ConvergenceTester is implemented by stopping criteria that can tell
+// whether a state satisfies their convergence test without updating any
+// state that they keep across iterations. When a method reports an estimate
+// of the residual norm, Solve asks Converged whether the estimate is small
+// enough to be worth confirming and, if it is, replaces it with the norm of
+// the true residual before Check is called. For a criterion that does not
+// implement ConvergenceTester, the true residual is computed in every
+// iteration in which the method reports an estimate. In either case, Check
+// is called once per iteration.
+type ConvergenceTester interface {
+	Converged(*ConvergenceState) bool
+}
+
+// mayConverge reports whether stop may report convergence for the state s.
+func mayConverge(stop StoppingCriterion, s *ConvergenceState) bool {
+	if t, ok := stop.(ConvergenceTester); ok {
+		return t.Converged(s)
+	}
+	return true
+}
+
+// AbsoluteResidual stops when ‖r‖ < Tolerance.
+type AbsoluteResidual struct {
+	Tolerance float64
+}
+
+func (AbsoluteResidual) Init(*ConvergenceState) {}
+
+func (c AbsoluteResidual) Check(s *ConvergenceState) (bool, error) {
+	return c.Converged(s), nil
+}
+
+func (c AbsoluteResidual) Converged(s *ConvergenceState) bool {
+	return s.ResidualNorm < c.Tolerance
+}
+
+// RelativeResidual stops when ‖r‖ < Tolerance ‖b‖. If b is zero, it is the
+// same as AbsoluteResidual. It is the default criterion.
+type RelativeResidual struct {
+	Tolerance float64
+}
+
+func (RelativeResidual) Init(*ConvergenceState) {}
+
+func (c RelativeResidual) Check(s *ConvergenceState) (bool, error) {
+	return c.Converged(s), nil
+}
+
+func (c RelativeResidual) Converged(s *ConvergenceState) bool {
+	bNorm := s.RHSNorm
+	if bNorm == 0 {
+		bNorm = 1
+	}
+	return s.ResidualNorm < c.Tolerance*bNorm
+}
+
+// InitialResidual stops when ‖r‖ ≤ Tolerance ‖r₀‖ where r₀ is the initial
+// residual. The comparison is not strict so that a zero initial residual
+// is accepted.
+type InitialResidual struct {
+	Tolerance float64
+
+	r0Norm float64
+}
+
+func (c *InitialResidual) Init(s *ConvergenceState) {
+	c.r0Norm = s.ResidualNorm
+}
+
+func (c *InitialResidual) Check(s *ConvergenceState) (bool, error) {
+	return c.Converged(s), nil
+}
+
+func (c *InitialResidual) Converged(s *ConvergenceState) bool {
+	return s.ResidualNorm <= c.Tolerance*c.r0Norm
+}
+
+// BackwardError stops when the normwise backward error
+//
+//  ‖r‖ / (‖A‖ ‖x‖ + ‖b‖)
+//
+// is less than Tolerance. ANorm is an estimate of ‖A‖ provided by the
+// caller, for example the Frobenius norm.
+type BackwardError struct {
+	Tolerance float64
+	ANorm     float64
+}
+
+func (BackwardError) Init(*ConvergenceState) {}
+
+func (c BackwardError) Check(s *ConvergenceState) (bool, error) {
+	return c.Converged(s), nil
+}
+
+func (c BackwardError) Converged(s *ConvergenceState) bool {
+	denom := c.ANorm*s.SolutionNorm() + s.RHSNorm
+	if denom == 0 {
+		return s.ResidualNorm == 0
+	}
+	return s.ResidualNorm/denom < c.Tolerance
+}
+
+// PreconditionedResidual stops when ‖M⁻¹ r‖ ≤ Tolerance ‖M⁻¹ r₀‖ where M is
+// the preconditioner and r₀ the initial residual. The comparison is not
+// strict so that a zero initial residual is accepted.
+type PreconditionedResidual struct {
+	Tolerance float64
+
+	z0Norm float64
+}
+
+func (c *PreconditionedResidual) Init(s *ConvergenceState) {
+	c.z0Norm = s.PreconditionedResidualNorm()
+}
+
+func (c *PreconditionedResidual) Check(s *ConvergenceState) (bool, error) {
+	return c.Converged(s), nil
+}
+
+func (c *PreconditionedResidual) Converged(s *ConvergenceState) bool {
+	return s.PreconditionedResidualNorm() <= c.Tolerance*c.z0Norm
+}
+
+// Stagnation returns ErrStagnation when the residual norm has not dropped
+// below Factor times the smallest residual norm seen so far for Window
+// iterations. If Window is zero, 10 is used and if Factor is zero, 0.99 is
+// used. Stagnation never reports convergence, so it is meant to be combined
+// with other criteria by Or.
+type Stagnation struct {
+	Window int
+	Factor float64
+
+	best float64 // Smallest residual norm seen so far.
+	last int     // Iteration of the last sufficient decrease.
+}
+
+func (c *Stagnation) Init(s *ConvergenceState) {
+	c.best = s.ResidualNorm
+	c.last = s.Iteration
+}
+
+func (c *Stagnation) Check(s *ConvergenceState) (bool, error) {
+	window := c.Window
+	if window <= 0 {
+		window = 10
+	}
+	factor := c.Factor
+	if factor == 0 {
+		factor = 0.99
+	}
+
+	if s.ResidualNorm < factor*c.best {
+		c.best = s.ResidualNorm
+		c.last = s.Iteration
+	}
+	if s.Iteration-c.last >= window {
+		return false, ErrStagnation
+	}
+	return false, nil
+}
+
+func (c *Stagnation) Converged(*ConvergenceState) bool { return false }
+
+// Divergence returns ErrDivergence when ‖r‖ > Factor ‖r₀‖ where r₀ is the
+// initial residual, or when the residual norm is infinite. If Factor is
+// zero, 1e5 is used. Divergence never reports convergence, so it is meant
+// to be combined with other criteria by Or.
+type Divergence struct {
+	Factor float64
+
+	r0Norm float64
+}
+
+func (c *Divergence) Init(s *ConvergenceState) {
+	c.r0Norm = s.ResidualNorm
+}
+
+func (c *Divergence) Check(s *ConvergenceState) (bool, error) {
+	factor := c.Factor
+	if factor == 0 {
+		factor = 1e5
+	}
+	if math.IsInf(s.ResidualNorm, 0) || s.ResidualNorm > factor*c.r0Norm {
+		return false, ErrDivergence
+	}
+	return false, nil
+}
+
+func (c *Divergence) Converged(*ConvergenceState) bool { return false }
+
+// And returns a criterion that reports convergence when all the given
+// criteria do. All criteria are checked in every iteration and the iteration
+// stops with the first error returned by any of them.
+func And(criteria ...StoppingCriterion) StoppingCriterion {
+	return combined{criteria: criteria, all: true}
+}
+
+// Or returns a criterion that reports convergence when any of the given
+// criteria does. All criteria are checked in every iteration and the
+// iteration stops with the first error returned by any of them.
+func Or(criteria ...StoppingCriterion) StoppingCriterion {
+	return combined{criteria: criteria}
+}
+
+type combined struct {
+	criteria []StoppingCriterion
+	all      bool
+}
+
+func (c combined) Init(s *ConvergenceState) {
+	for _, sc := range c.criteria {
+		sc.Init(s)
+	}
+}
+
+func (c combined) Check(s *ConvergenceState) (bool, error) {
+	converged := c.all
+	var first error
+	for _, sc := range c.criteria {
+		// All criteria are checked even after an error so that the
+		// stateful ones see every iteration.
+		ok, err := sc.Check(s)
+		if err != nil && first == nil {
+			first = err
+		}
+		if c.all {
+			converged = converged && ok
+		} else {
+			converged = converged || ok
+		}
+	}
+	if first != nil {
+		return false, first
+	}
+	return converged, nil
+}
+
+func (c combined) Converged(s *ConvergenceState) bool {
+	for _, sc := range c.criteria {
+		ok := mayConverge(sc, s)
+		if ok != c.all {
+			return ok
+		}
+	}
+	return c.all
+}
@@ -0,0 +1,175 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// scaleMethod does not change x and multiplies the residual by factor in
+// every iteration after the first one.
+type scaleMethod struct {
+	factor float64
+}
+
+func (m scaleMethod) Init(ctx *Context) Operation {
+	return CheckConvergence
+}
+
+func (m scaleMethod) Iterate(ctx *Context) Operation {
+	ctx.Residual.ScaleVec(m.factor, ctx.Residual)
+	return CheckConvergence
+}
+
+// infMethod does not change x and reports an infinite residual norm.
+type infMethod struct{}
+
+func (infMethod) Init(ctx *Context) Operation {
+	ctx.ResidualNorm = math.Inf(1)
+	return CheckConvergence
+}
+
+func (m infMethod) Iterate(ctx *Context) Operation {
+	return m.Init(ctx)
+}
+
+// countCriterion counts its checks and never stops the iteration.
+type countCriterion struct {
+	checks int
+}
+
+func (c *countCriterion) Init(*ConvergenceState) {}
+
+func (c *countCriterion) Check(*ConvergenceState) (bool, error) {
+	c.checks++
+	return false, nil
+}
+
+func TestStoppingCriterion(t *testing.T) {
+	a := lap2D(10)
+	b, _ := rhs(a)
+	n := b.Len()
+	for _, test := range []struct {
+		name   string
+		stop   StoppingCriterion
+		method Method
+		err    error
+		// iter is the expected number of iterations, or -1 if it is
+		// not checked.
+		iter int
+	}{
+		{"AbsoluteResidual", AbsoluteResidual{Tolerance: 1e-8}, &CG{}, nil, -1},
+		{"InitialResidual", &InitialResidual{Tolerance: 1e-8}, &CG{}, nil, -1},
+		{"BackwardError", BackwardError{Tolerance: 1e-12, ANorm: 8}, &CG{}, nil, -1},
+		{"PreconditionedResidual", &PreconditionedResidual{Tolerance: 1e-8}, &CG{}, nil, -1},
+		{"Stagnation", Or(RelativeResidual{Tolerance: 1e-8}, &Stagnation{Window: 5}), scaleMethod{1}, ErrStagnation, 5},
+		{"Divergence", Or(RelativeResidual{Tolerance: 1e-8}, &Divergence{Factor: 100}), scaleMethod{10}, ErrDivergence, 4},
+		{"Divergence/Inf", Or(RelativeResidual{Tolerance: 1e-8}, &Divergence{Factor: math.MaxFloat64}), infMethod{}, ErrDivergence, 1},
+		{"Inf", RelativeResidual{Tolerance: 1e-8}, infMethod{}, ErrBreakdown, 1},
+		{"NaN", Or(RelativeResidual{Tolerance: 1e-8}, &Divergence{}), scaleMethod{math.NaN()}, ErrBreakdown, 2},
+		{"And", And(AbsoluteResidual{Tolerance: 1e-3}, RelativeResidual{Tolerance: 1e-3}), scaleMethod{0.1}, nil, 6},
+		{"Or", Or(AbsoluteResidual{Tolerance: 1e-3}, RelativeResidual{Tolerance: 1e-3}), scaleMethod{0.1}, nil, 5},
+	} {
+		settings := DefaultSettings(n)
+		settings.Stop = test.stop
+		result, err := Solve(a, b, nil, settings, test.method)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: want error %v, got %v", test.name, test.err, err)
+		}
+		if test.iter >= 0 && result.Stats.Iterations != test.iter {
+			t.Errorf("%s: want %v iterations, got %v", test.name, test.iter, result.Stats.Iterations)
+		}
+		if _, ok := test.method.(*CG); ok && trueResidual(a, b, result.X) > 1e-3 {
+			t.Errorf("%s: no convergence", test.name)
+		}
+	}
+}
+
+func TestCombinedChecksAll(t *testing.T) {
+	a := lap2D(10)
+	b, _ := rhs(a)
+	for _, combine := range []func(...StoppingCriterion) StoppingCriterion{And, Or} {
+		count := &countCriterion{}
+		settings := DefaultSettings(b.Len())
+		settings.Stop = combine(&Stagnation{Window: 3}, count)
+		result, err := Solve(a, b, nil, settings, scaleMethod{1})
+		if err != ErrStagnation {
+			t.Errorf("want %v, got %v", ErrStagnation, err)
+		}
+		// The initial guess is checked too.
+		if want := result.Stats.Iterations + 1; count.checks != want {
+			t.Errorf("want %v checks after an error, got %v", want, count.checks)
+		}
+	}
+}
+
+func TestZeroInitialResidual(t *testing.T) {
+	a := lap2D(10)
+	b, x := rhs(a)
+	for _, test := range []struct {
+		name string
+		stop StoppingCriterion
+	}{
+		{"InitialResidual", &InitialResidual{Tolerance: 1e-8}},
+		{"PreconditionedResidual", &PreconditionedResidual{Tolerance: 1e-8}},
+	} {
+		settings := DefaultSettings(b.Len())
+		settings.Stop = test.stop
+		result, err := Solve(a, b, x, settings, &CG{})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if result.Stats.Iterations != 0 {
+			t.Errorf("%s: want 0 iterations, got %v", test.name, result.Stats.Iterations)
+		}
+	}
+}
+
+func TestCheckOncePerIteration(t *testing.T) {
+	a := lap2D(10)
+	b, _ := rhs(a)
+	for _, test := range []struct {
+		name   string
+		method func() Method
+	}{
+		{"CG", func() Method { return &CG{} }},
+		{"MINRES", func() Method { return &MINRES{} }},
+		{"SYMMLQ", func() Method { return &SYMMLQ{} }},
+		{"TFQMR", func() Method { return &TFQMR{} }},
+	} {
+		// countCriterion does not implement ConvergenceTester, so the
+		// estimates of MINRES, SYMMLQ and TFQMR are replaced by the true
+		// residual in every iteration.
+		count := &countCriterion{}
+		settings := DefaultSettings(b.Len())
+		settings.Tolerance = 1e-8
+		settings.Stop = Or(RelativeResidual{Tolerance: 1e-8}, count)
+		result, err := Solve(a, b, nil, settings, test.method())
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if want := result.Stats.Iterations + 1; count.checks != want {
+			t.Errorf("%s: want %v checks, got %v", test.name, want, count.checks)
+		}
+		if res := trueResidual(a, b, result.X); res >= 1e-8 {
+			t.Errorf("%s: true relative residual %v not below 1e-8", test.name, res)
+		}
+
+		// RelativeResidual implements ConvergenceTester, so the true
+		// residual is computed only to confirm convergence.
+		settings.Stop = nil
+		plain, err := Solve(a, b, nil, settings, test.method())
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if plain.Stats.Iterations == result.Stats.Iterations && plain.Stats.MatVecMultiplies > result.Stats.MatVecMultiplies {
+			t.Errorf("%s: want at most %v products, got %v", test.name, result.Stats.MatVecMultiplies, plain.Stats.MatVecMultiplies)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// SYMMLQ implements the Symmetric LQ method of Paige and Saunders for solving
+// the linear system Ax = b with a symmetric, possibly indefinite matrix A.
+// The preconditioner must be symmetric positive definite. The SYMMLQ iterate
+// minimizes the error in a Krylov subspace, in every iteration the method
+// reports either the SYMMLQ iterate or the CG iterate, whichever has the
+// smaller estimated residual norm.
+//
+// Like MINRES, SYMMLQ does not update the residual vector and reports the
+// Lanczos-based estimate of the residual norm, measured in the norm
+// ‖r‖_M⁻¹ = √(rᵀ M⁻¹ r) if a preconditioner M is used, through
+// Context.ResidualNorm. Solve confirms convergence with the true residual.
+type SYMMLQ struct {
+	resume int
+
+	alpha             float64
+	beta1, beta, oldb float64
+	gbar, dbar        float64
+	rhs1, rhs2        float64
+	bstep, snprod     float64
+	tnorm2            float64
+
+	x0     *mat64.Vector // Initial guess.
+	xl     *mat64.Vector // Update of the LQ iterate without the component along v_1.
+	y1     *mat64.Vector // M⁻¹ r_0, that is, β_1 v_1.
+	r1, r2 *mat64.Vector
+	y, v   *mat64.Vector
+	w      *mat64.Vector
+}
+
+func (s *SYMMLQ) Init(ctx *Context) Operation {
+	dim := ctx.X.Len()
+	s.x0 = reuseVector(s.x0, dim)
+	s.xl = reuseVector(s.xl, dim)
+	s.y1 = reuseVector(s.y1, dim)
+	s.r1 = reuseVector(s.r1, dim)
+	s.r2 = reuseVector(s.r2, dim)
+	s.y = reuseVector(s.y, dim)
+	s.v = reuseVector(s.v, dim)
+	s.w = reuseVector(s.w, dim)
+	ctx.P = reuseVector(ctx.P, dim)
+	ctx.Ap = reuseVector(ctx.Ap, dim)
+	ctx.Q = reuseVector(ctx.Q, dim)
+	ctx.Z = reuseVector(ctx.Z, dim)
+
+	s.x0.CopyVec(ctx.X)
+	s.resume = 1
+	return SolvePreconditioner
+	// Solve M y = r_0
+}
+
+func (s *SYMMLQ) Iterate(ctx *Context) Operation {
+	switch s.resume {
+	case 1:
+		s.r1.CopyVec(ctx.Residual)
+		s.y1.CopyVec(ctx.Z)
+		// β_1 = √(r_0ᵀ M⁻¹ r_0)
+		beta1 := mat64.Dot(s.r1, s.y1)
+		if beta1 < 0 {
+			// The preconditioner is not positive definite.
+			ctx.Err = &BreakdownError{Method: "SYMMLQ", Quantity: "r_0ᵀ M⁻¹ r_0", Value: beta1}
+			s.resume = 0
+			return CheckConvergence
+		}
+		s.beta1 = math.Sqrt(beta1)
+		// v_1 = y / β_1
+		s.v.ScaleVec(1/s.beta1, s.y1)
+		ctx.P.CopyVec(s.v)
+		s.resume = 2
+		return ComputeAp
+		// Compute A v_1
+	case 2:
+		s.y.CopyVec(ctx.Ap)
+		// α = v_1ᵀ y
+		alpha := mat64.Dot(s.v, s.y)
+		// y = y - (α / β_1) r_1
+		s.y.AddScaledVec(s.y, -alpha/s.beta1, s.r1)
+		// Make sure that r_2 will be orthogonal to v_1.
+		s.y.AddScaledVec(s.y, -mat64.Dot(s.v, s.y)/mat64.Dot(s.v, s.v), s.v)
+		s.r2.CopyVec(s.y)
+
+		s.gbar = alpha
+		s.rhs1 = s.beta1
+		s.rhs2 = 0
+		s.bstep = 0
+		s.snprod = 1
+		s.tnorm2 = alpha * alpha
+		s.xl.ScaleVec(0, s.xl)
+		s.w.ScaleVec(0, s.w)
+
+		ctx.Q.CopyVec(s.r2)
+		s.resume = 3
+		return SolvePreconditionerQ
+		// Solve M y = r_2
+	case 3:
+		s.y.CopyVec(ctx.Z)
+		s.oldb = s.beta1
+		if !s.setBeta(ctx) {
+			return CheckConvergence
+		}
+		s.dbar = s.beta
+		s.tnorm2 += s.beta * s.beta
+
+		s.report(ctx)
+		s.resume = 4
+		return CheckConvergence
+	case 4:
+		// v = y / β
+		s.v.ScaleVec(1/s.beta, s.y)
+		ctx.P.CopyVec(s.v)
+		s.resume = 5
+		return ComputeAp
+		// Compute A v
+	case 5:
+		// y = A v - (β / β_old) r_1
+		s.y.CopyVec(ctx.Ap)
+		s.y.AddScaledVec(s.y, -s.beta/s.oldb, s.r1)
+		// α = vᵀ y
+		s.alpha = mat64.Dot(s.v, s.y)
+		// y = y - (α / β) r_2
+		s.y.AddScaledVec(s.y, -s.alpha/s.beta, s.r2)
+		// r_1 = r_2, r_2 = y
+		s.r1, s.r2 = s.r2, s.r1
+		s.r2.CopyVec(s.y)
+
+		ctx.Q.CopyVec(s.r2)
+		s.resume = 6
+		return SolvePreconditionerQ
+		// Solve M y = r_2
+	case 6:
+		s.y.CopyVec(ctx.Z)
+		s.oldb = s.beta
+		if !s.setBeta(ctx) {
+			return CheckConvergence
+		}
+		s.tnorm2 += s.alpha*s.alpha + s.oldb*s.oldb + s.beta*s.beta
+
+		// Compute the next plane rotation.
+		gamma := math.Hypot(s.gbar, s.oldb)
+		cs := s.gbar / gamma
+		sn := s.oldb / gamma
+		delta := cs*s.dbar + sn*s.alpha
+		s.gbar = sn*s.dbar - cs*s.alpha
+		epsln := sn * s.beta
+		s.dbar = -cs * s.beta
+
+		// Update the LQ iterate.
+		z := s.rhs1 / gamma
+		s.xl.AddScaledVec(s.xl, z*cs, s.w)
+		s.xl.AddScaledVec(s.xl, z*sn, s.v)
+		s.w.ScaleVec(sn, s.w)
+		s.w.AddScaledVec(s.w, -cs, s.v)
+
+		// Accumulate the step along v_1.
+		s.bstep += s.snprod * cs * z
+		s.snprod *= sn
+		s.rhs1 = s.rhs2 - delta*z
+		s.rhs2 = -epsln * z
+
+		s.report(ctx)
+		s.resume = 4
+		return CheckConvergence
+	default:
+		panic("unreachable")
+	}
+}
+
+// setBeta computes β = √(r_2ᵀ M⁻¹ r_2). It returns false and sets ctx.Err
+// if the preconditioner is not positive definite.
+func (s *SYMMLQ) setBeta(ctx *Context) bool {
+	beta2 := mat64.Dot(s.r2, s.y)
+	if beta2 < 0 {
+		ctx.Err = &BreakdownError{Method: "SYMMLQ", Quantity: "r_2ᵀ M⁻¹ r_2", Value: beta2}
+		s.resume = 0
+		return false
+	}
+	s.beta = math.Sqrt(beta2)
+	return true
+}
+
+// report sets X to the LQ or the CG iterate, whichever has the smaller
+// estimated residual norm, and reports the estimate.
+func (s *SYMMLQ) report(ctx *Context) {
+	diag := s.gbar
+	if diag == 0 {
+		diag = math.Sqrt(s.tnorm2) * dlamchE
+	}
+	lqnorm := math.Hypot(s.rhs1, s.rhs2)
+	cgnorm := s.snprod * s.beta1 * s.beta / math.Abs(diag)
+
+	// x = x_0 + x_L + (bstep / β_1) y_1
+	ctx.X.CopyVec(s.x0)
+	ctx.X.AddScaledVec(ctx.X, 1, s.xl)
+	bstep := s.bstep
+	if cgnorm <= lqnorm {
+		// Move to the CG point.
+		zbar := s.rhs1 / diag
+		bstep += s.snprod * zbar
+		ctx.X.AddScaledVec(ctx.X, zbar, s.w)
+		ctx.ResidualNorm = cgnorm
+	} else {
+		ctx.ResidualNorm = lqnorm
+	}
+	ctx.X.AddScaledVec(ctx.X, bstep/s.beta1, s.y1)
+}
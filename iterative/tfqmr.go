@@ -0,0 +1,170 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// TFQMR implements the Transpose-Free Quasi-Minimal Residual method of
+// Freund with right preconditioning for solving the linear system Ax = b with
+// a nonsymmetric matrix A.
+//
+// Every TFQMR iteration consists of two half-steps, each of which is followed
+// by a convergence check, so Stats.Iterations counts the half-steps. TFQMR
+// does not update the residual vector, it reports the upper bound
+// τ √(m+1) on the residual norm after m half-steps through
+// Context.ResidualNorm.
+//
+// TFQMR breaks down when r̃ᵀ w or r̃ᵀ v is negligible compared to the norms
+// of the vectors, that is, less than BreakdownTolerance times their
+// product. If BreakdownTolerance is zero, machine epsilon is used.
+type TFQMR struct {
+	BreakdownTolerance float64
+
+	resume int
+	first  bool
+	m      int
+
+	rho, alpha, beta float64
+	tau, theta, eta  float64
+
+	rt     *mat64.Vector // Shadow residual r̃.
+	w, v   *mat64.Vector
+	y1, y2 *mat64.Vector
+	u1, u2 *mat64.Vector // A M⁻¹ y_1 and A M⁻¹ y_2.
+	d      *mat64.Vector
+}
+
+func (t *TFQMR) Init(ctx *Context) Operation {
+	if t.BreakdownTolerance == 0 {
+		t.BreakdownTolerance = dlamchE
+	}
+
+	dim := ctx.X.Len()
+	t.rt = reuseVector(t.rt, dim)
+	t.w = reuseVector(t.w, dim)
+	t.v = reuseVector(t.v, dim)
+	t.y1 = reuseVector(t.y1, dim)
+	t.y2 = reuseVector(t.y2, dim)
+	t.u1 = reuseVector(t.u1, dim)
+	t.u2 = reuseVector(t.u2, dim)
+	t.d = reuseVector(t.d, dim)
+	ctx.P = reuseVector(ctx.P, dim)
+	ctx.Ap = reuseVector(ctx.Ap, dim)
+	ctx.Q = reuseVector(ctx.Q, dim)
+	ctx.Z = reuseVector(ctx.Z, dim)
+
+	// r̃ = w = y_1 = r_0, d = 0
+	t.rt.CopyVec(ctx.Residual)
+	t.w.CopyVec(ctx.Residual)
+	t.y1.CopyVec(ctx.Residual)
+	t.d.ScaleVec(0, t.d)
+	t.tau = mat64.Norm(ctx.Residual, 2)
+	t.rho = t.tau * t.tau
+	t.theta = 0
+	t.eta = 0
+	t.m = 0
+	t.first = true
+
+	ctx.Q.CopyVec(t.y1)
+	t.resume = 1
+	return SolvePreconditionerQ
+	// Solve M ŷ_1 = y_1
+}
+
+func (t *TFQMR) Iterate(ctx *Context) Operation {
+	switch t.resume {
+	case 1:
+		ctx.P.CopyVec(ctx.Z)
+		t.resume = 2
+		return ComputeAp
+		// Compute u_1 = A ŷ_1
+	case 2:
+		t.u1.CopyVec(ctx.Ap)
+		if t.first {
+			// v = u_1
+			t.v.CopyVec(t.u1)
+		} else {
+			// v = u_1 + β (u_2 + β v)
+			t.v.AddScaledVec(t.u2, t.beta, t.v)
+			t.v.AddScaledVec(t.u1, t.beta, t.v)
+		}
+		t.first = false
+
+		// σ = r̃ · v
+		sigma := mat64.Dot(t.rt, t.v)
+		if negligible(sigma, t.rt, t.v, t.BreakdownTolerance) {
+			ctx.Err = &BreakdownError{Method: "TFQMR", Quantity: "σ", Value: sigma}
+			t.resume = 0
+			return CheckConvergence
+		}
+		// α = ρ / σ
+		t.alpha = t.rho / sigma
+
+		t.halfStep(ctx, t.u1, ctx.P)
+		t.resume = 3
+		return CheckConvergence
+	case 3:
+		// y_2 = y_1 - α v
+		t.y2.AddScaledVec(t.y1, -t.alpha, t.v)
+		ctx.Q.CopyVec(t.y2)
+		t.resume = 4
+		return SolvePreconditionerQ
+		// Solve M ŷ_2 = y_2
+	case 4:
+		ctx.P.CopyVec(ctx.Z)
+		t.resume = 5
+		return ComputeAp
+		// Compute u_2 = A ŷ_2
+	case 5:
+		t.u2.CopyVec(ctx.Ap)
+		t.halfStep(ctx, t.u2, ctx.P)
+		t.resume = 6
+		return CheckConvergence
+	case 6:
+		// ρ_new = r̃ · w
+		rho := mat64.Dot(t.rt, t.w)
+		if negligible(rho, t.rt, t.w, t.BreakdownTolerance) {
+			ctx.Err = &BreakdownError{Method: "TFQMR", Quantity: "ρ", Value: rho}
+			t.resume = 0
+			return CheckConvergence
+		}
+		// β = ρ_new / ρ
+		t.beta = rho / t.rho
+		t.rho = rho
+		// y_1 = w + β y_2
+		t.y1.AddScaledVec(t.w, t.beta, t.y2)
+
+		ctx.Q.CopyVec(t.y1)
+		t.resume = 1
+		return SolvePreconditionerQ
+		// Solve M ŷ_1 = y_1
+	default:
+		panic("unreachable")
+	}
+}
+
+// halfStep updates w, d and the iterate with u = A ŷ and the preconditioned
+// vector ŷ, and reports the estimate of the residual norm.
+func (t *TFQMR) halfStep(ctx *Context, u, yhat *mat64.Vector) {
+	// w = w - α u
+	t.w.AddScaledVec(t.w, -t.alpha, u)
+	// d = ŷ + (θ² η / α) d
+	t.d.AddScaledVec(yhat, t.theta*t.theta*t.eta/t.alpha, t.d)
+	// θ = ‖w‖ / τ, c = 1 / √(1 + θ²)
+	t.theta = mat64.Norm(t.w, 2) / t.tau
+	c := 1 / math.Sqrt(1+t.theta*t.theta)
+	// τ = τ θ c, η = c² α
+	t.tau *= t.theta * c
+	t.eta = c * c * t.alpha
+	// x = x + η d
+	ctx.X.AddScaledVec(ctx.X, t.eta, t.d)
+
+	t.m++
+	ctx.ResidualNorm = t.tau * math.Sqrt(float64(t.m+1))
+}
@@ -0,0 +1,69 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package iterative
+
+import (
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/vladimir-ch/sparse"
+)
+
+func TestTFQMR(t *testing.T) {
+	for _, p := range nonsymProblems(t) {
+		testSolve(t, "TFQMR", p, &TFQMR{}, 1e-10)
+	}
+	testBreakdown(t, "TFQMR", &TFQMR{})
+}
+
+// boundCheck wraps a method that reports ResidualNorm and records whether
+// the reported norm bounds the true residual norm at every convergence
+// check.
+type boundCheck struct {
+	Method
+	a    sparse.Matrix
+	b    *mat64.Vector
+	r    *mat64.Vector
+	fail int
+}
+
+func (c *boundCheck) Init(ctx *Context) Operation {
+	return c.check(ctx, c.Method.Init(ctx))
+}
+
+func (c *boundCheck) Iterate(ctx *Context) Operation {
+	return c.check(ctx, c.Method.Iterate(ctx))
+}
+
+func (c *boundCheck) check(ctx *Context, op Operation) Operation {
+	if op != CheckConvergence || ctx.Err != nil {
+		return op
+	}
+	c.r = reuseVector(c.r, c.b.Len())
+	c.r.CopyVec(c.b)
+	sparse.MulMatVec(c.r, -1, false, c.a, ctx.X)
+	if rNorm := mat64.Norm(c.r, 2); rNorm > ctx.ResidualNorm*(1+1e-8) {
+		c.fail++
+	}
+	return op
+}
+
+func TestTFQMRResidualBound(t *testing.T) {
+	for _, p := range nonsymProblems(t) {
+		b, _ := rhs(p.a)
+		method := &boundCheck{Method: &TFQMR{}, a: p.a, b: b}
+		settings := DefaultSettings(b.Len())
+		settings.Tolerance = 1e-10
+		if p.jacobi {
+			settings.Preconditioner = jacobi(p.a)
+		}
+		if _, err := Solve(p.a, b, nil, settings, method); err != nil {
+			t.Errorf("%s: unexpected error: %v", p.name, err)
+		}
+		if method.fail > 0 {
+			t.Errorf("%s: reported residual norm below the true one in %d iterations", p.name, method.fail)
+		}
+	}
+}
@@ -79,3 +79,90 @@ func Scatter(y *mat64.Vector, x *Vector) {
 		raw.Data[index*raw.Inc] = x.Data[i]
 	}
 }
+
+// DotOf computes the dot product of the sparse vector x with the dense vector
+// y without conjugation. The vectors must have the same dimension.
+func DotOf[T Scalar](x *VectorOf[T], y []T) (dot T) {
+	if x.N != len(y) {
+		panic("sparse: vector dimension mismatch")
+	}
+
+	for i, index := range x.Indices {
+		dot += x.Data[i] * y[index]
+	}
+	return
+}
+
+// DotcOf computes the dot product of the conjugate of the sparse vector x with
+// the dense vector y. For real types it is equal to DotOf. The vectors must
+// have the same dimension.
+func DotcOf[T Scalar](x *VectorOf[T], y []T) (dot T) {
+	conj := conjFunc[T]()
+	if conj == nil {
+		return DotOf(x, y)
+	}
+	if x.N != len(y) {
+		panic("sparse: vector dimension mismatch")
+	}
+
+	for i, index := range x.Indices {
+		dot += conj(x.Data[i]) * y[index]
+	}
+	return
+}
+
+// AxpyOf scales the sparse vector x by alpha and adds the result to the dense
+// vector y. If alpha is zero, y is not modified.
+func AxpyOf[T Scalar](y []T, alpha T, x *VectorOf[T]) {
+	if x.N != len(y) {
+		panic("sparse: vector dimension mismatch")
+	}
+
+	if alpha == 0 {
+		return
+	}
+	for i, index := range x.Indices {
+		y[index] += alpha * x.Data[i]
+	}
+}
+
+// GatherOf gathers entries given by indices of the dense vector y into the
+// sparse vector x. Indices must not be nil.
+func GatherOf[T Scalar](x *VectorOf[T], y []T, indices []int) {
+	if indices == nil {
+		panic("sparse: slice is nil")
+	}
+
+	x.reuseAs(len(y), len(indices))
+	copy(x.Indices, indices)
+	for i, index := range x.Indices {
+		x.Data[i] = y[index]
+	}
+}
+
+// GatherZeroOf gathers entries given by indices of the dense vector y into the
+// sparse vector x and sets the corresponding values of y to zero.
+func GatherZeroOf[T Scalar](x *VectorOf[T], y []T, indices []int) {
+	if indices == nil {
+		panic("sparse: slice is nil")
+	}
+
+	x.reuseAs(len(y), len(indices))
+	copy(x.Indices, indices)
+	for i, index := range x.Indices {
+		x.Data[i] = y[index]
+		y[index] = 0
+	}
+}
+
+// ScatterOf copies the values of x into the corresponding locations in the
+// dense vector y. Both vectors must have the same dimension.
+func ScatterOf[T Scalar](y []T, x *VectorOf[T]) {
+	if x.N != len(y) {
+		panic("sparse: vector dimension mismatch")
+	}
+
+	for i, index := range x.Indices {
+		y[index] = x.Data[i]
+	}
+}
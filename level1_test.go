@@ -189,3 +189,45 @@ func TestScatter(t *testing.T) {
 		}
 	}
 }
+
+func TestLevel1Of(t *testing.T) {
+	// NewVector keeps accepting untyped nil slices.
+	if v := NewVector(3, nil, nil); v.N != 3 || len(v.Data) != 0 {
+		t.Errorf("NewVector: unexpected vector %v", v)
+	}
+
+	x := NewVectorOf(4, []complex128{1 + 1i, 2 - 1i}, []int{0, 3})
+	y := []complex128{1i, 5, 6, 2}
+
+	if got, want := DotOf(x, y), complex128((1+1i)*1i+(2-1i)*2); got != want {
+		t.Errorf("DotOf: want %v, got %v", want, got)
+	}
+	if got, want := DotcOf(x, y), complex128((1-1i)*1i+(2+1i)*2); got != want {
+		t.Errorf("DotcOf: want %v, got %v", want, got)
+	}
+
+	AxpyOf(y, 2i, x)
+	if want := []complex128{1i + 2i*(1+1i), 5, 6, 2 + 2i*(2-1i)}; !reflect.DeepEqual(y, want) {
+		t.Errorf("AxpyOf: want %v, got %v", want, y)
+	}
+
+	xf := NewVectorOf(3, []float32{1, 2}, []int{0, 2})
+	yf := []float32{1, 2, 3}
+	if got := DotcOf(xf, yf); got != 7 {
+		t.Errorf("DotcOf for float32: want 7, got %v", got)
+	}
+
+	var g VectorOf[float32]
+	GatherZeroOf(&g, yf, []int{2, 1})
+	if !reflect.DeepEqual(g.Data, []float32{3, 2}) || !reflect.DeepEqual(yf, []float32{1, 0, 0}) {
+		t.Errorf("GatherZeroOf: unexpected result %v, %v", g.Data, yf)
+	}
+	ScatterOf(yf, &g)
+	if !reflect.DeepEqual(yf, []float32{1, 2, 3}) {
+		t.Errorf("ScatterOf: unexpected result %v", yf)
+	}
+	GatherOf(&g, yf, []int{0})
+	if g.N != 3 || !reflect.DeepEqual(g.Data, []float32{1}) {
+		t.Errorf("GatherOf: unexpected result %v", g.Data)
+	}
+}
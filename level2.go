@@ -4,7 +4,10 @@
 
 package sparse
 
-import "github.com/gonum/matrix/mat64"
+import (
+	"github.com/gonum/blas"
+	"github.com/gonum/matrix/mat64"
+)
 
 // MulMatVec multiplies the dense vector x by a sparse matrix A (or its
 // transpose) and adds the result to the dense vector y, i.e., it computes
@@ -16,9 +19,78 @@ func MulMatVec(y *mat64.Vector, alpha float64, transA bool, a Matrix, x *mat64.V
 	switch a := a.(type) {
 	case *CSR:
 		csrMulMatVec(y, alpha, transA, a, x)
+	case *CSR32:
+		csr32MulMatVec(y, alpha, transA, a, x)
 	case *DOK:
 		dokMulMatVec(y, alpha, transA, a, x)
+	case *BSR:
+		bsrMulMatVec(y, alpha, transA, a, x)
+	case *DIA:
+		diaMulMatVec(y, alpha, transA, a, x)
+	case *ELL:
+		ellMulMatVec(y, alpha, transA, a, x)
+	case *SELL:
+		sellMulMatVec(y, alpha, transA, a, x)
+	case *Skyline:
+		skylineMulMatVec(y, alpha, a, x)
+	case *SymCSR:
+		symCSRMulMatVec(y, alpha, a, x)
 	default:
 		panic("unsupported matrix type")
 	}
 }
+
+// MulMatVecOf multiplies the dense vector x by a sparse matrix A with element
+// type T, its transpose or its conjugate transpose and adds the result to the
+// dense vector y, i.e., it computes
+//
+//  y += alpha * op(A) * x,
+//
+// where op(A) is A, Aᵀ or Aᴴ if trans is blas.NoTrans, blas.Trans or
+// blas.ConjTrans, respectively. For real T, Aᴴ is equal to Aᵀ.
+//
+// CSROf and DOKOf are supported for every T. The formats that store only
+// float64 entries are supported when T is float64, the product is then
+// computed by MulMatVec.
+func MulMatVecOf[T Scalar](y []T, alpha T, trans blas.Transpose, a MatrixOf[T], x []T) {
+	switch trans {
+	case blas.NoTrans, blas.Trans, blas.ConjTrans:
+	default:
+		panic("sparse: bad transpose")
+	}
+
+	switch a := a.(type) {
+	case *CSROf[T]:
+		csrMulMatVecOf(y, alpha, trans, a, x)
+	case *DOKOf[T]:
+		dokMulMatVecOf(y, alpha, trans, a, x)
+	default:
+		a64, ok := any(a).(Matrix)
+		if !ok {
+			panic("sparse: unsupported matrix type")
+		}
+		y64 := any(y).([]float64)
+		x64 := any(x).([]float64)
+		MulMatVec(mat64.NewVector(len(y64), y64), any(alpha).(float64), trans != blas.NoTrans, a64, mat64.NewVector(len(x64), x64))
+	}
+}
+
+// mulVecTo computes dst = op(A) * x for a matrix supported by MulMatVec.
+func mulVecTo(dst []float64, trans bool, a Matrix, x []float64) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	MulMatVec(mat64.NewVector(len(dst), dst), 1, trans, a, mat64.NewVector(len(x), x))
+}
+
+// mulVecToOf computes dst = op(A) * x for a matrix supported by MulMatVecOf.
+func mulVecToOf[T Scalar](dst []T, trans bool, a MatrixOf[T], x []T) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	tr := blas.NoTrans
+	if trans {
+		tr = blas.ConjTrans
+	}
+	MulMatVecOf(dst, 1, tr, a, x)
+}
@@ -5,9 +5,11 @@
 package sparse
 
 import (
+	"math"
 	"reflect"
 	"testing"
 
+	"github.com/gonum/blas"
 	"github.com/gonum/matrix/mat64"
 )
 
@@ -145,3 +147,131 @@ func TestMulMatVec(t *testing.T) {
 		}
 	}
 }
+
+func TestMulMatVecOf(t *testing.T) {
+	//  1+i  0  2
+	//  0   -i  3-2i
+	dok := NewDOKOf[complex128](2, 3)
+	dok.InsertEntry(0, 0, 1+1i)
+	dok.InsertEntry(0, 2, 2)
+	dok.InsertEntry(1, 1, -1i)
+	dok.InsertEntry(1, 2, 3-2i)
+	csr := NewCSR(dok)
+
+	for _, test := range []struct {
+		trans blas.Transpose
+		x     []complex128
+		want  []complex128
+	}{
+		{
+			trans: blas.NoTrans,
+			x:     []complex128{1, 1i, 2},
+			want:  []complex128{1 + 2i*(5+1i), 1 + 2i*(1+6-4i)},
+		},
+		{
+			trans: blas.Trans,
+			x:     []complex128{1, 1i},
+			want:  []complex128{1 + 2i*(1+1i), 1 + 2i*1, 1 + 2i*(2+(3-2i)*1i)},
+		},
+		{
+			trans: blas.ConjTrans,
+			x:     []complex128{1, 1i},
+			want:  []complex128{1 + 2i*(1-1i), 1 + 2i*(-1), 1 + 2i*(2+(3+2i)*1i)},
+		},
+	} {
+		for _, a := range []MatrixOf[complex128]{dok, csr} {
+			y := make([]complex128, len(test.want))
+			for i := range y {
+				y[i] = 1
+			}
+			MulMatVecOf(y, 2i, test.trans, a, test.x)
+			if !reflect.DeepEqual(y, test.want) {
+				t.Errorf("%T, trans=%c: want %v, got %v", a, test.trans, test.want, y)
+			}
+		}
+	}
+
+	// The float32 result must agree with the float64 one.
+	dok64 := NewDOK(3, 3)
+	dok64.InsertEntry(0, 1, 1)
+	dok64.InsertEntry(1, 0, 2)
+	dok64.InsertEntry(1, 2, -4)
+	dok64.InsertEntry(2, 2, 3)
+	csr64 := NewCSR(dok64)
+	csr32 := ConvertCSR[float32](csr64)
+	for _, trans := range []bool{false, true} {
+		y64 := mat64.NewVector(3, nil)
+		MulMatVec(y64, 2, trans, csr64, mat64.NewVector(3, []float64{1, 2, 3}))
+		y32 := make([]float32, 3)
+		tr := blas.NoTrans
+		if trans {
+			tr = blas.ConjTrans
+		}
+		MulMatVecOf(y32, 2, tr, csr32, []float32{1, 2, 3})
+		for i, v := range y32 {
+			if float64(v) != y64.At(i, 0) {
+				t.Errorf("float32, trans=%t: want %v, got %v", trans, y64.RawVector().Data, y32)
+				break
+			}
+		}
+	}
+}
+
+func TestMulVecTo(t *testing.T) {
+	dok := laplacian2DDOK(4)
+	csr := NewCSR(dok)
+	dia, err := NewDIA(csr, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sym := NewSymCSRFromCSR(csr, blas.Lower)
+	n, _ := csr.Dims()
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = float64(i + 1)
+	}
+	for _, trans := range []bool{false, true} {
+		want := mat64.NewVector(n, nil)
+		MulMatVec(want, 1, trans, csr, mat64.NewVector(n, x))
+		for _, a := range []LinearOperator{
+			dok, csr, NewCSR32(dok), NewBSR(dok, 2), dia, NewELL(csr), NewSELL(csr, 4, 8), sym, NewSkyline(sym),
+		} {
+			got := make([]float64, n)
+			for i := range got {
+				got[i] = math.NaN()
+			}
+			a.MulVecTo(got, trans, x)
+			if !reflect.DeepEqual(got, want.RawVector().Data) {
+				t.Errorf("%T, trans=%t: want %v, got %v", a, trans, want.RawVector().Data, got)
+			}
+		}
+	}
+}
+
+func TestMulMatVecOfFloat64Formats(t *testing.T) {
+	dok := laplacian2DDOK(4)
+	csr := NewCSR(dok)
+	dia, err := NewDIA(csr, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sym := NewSymCSRFromCSR(csr, blas.Upper)
+	n, _ := csr.Dims()
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = float64(i + 1)
+	}
+	for _, trans := range []blas.Transpose{blas.NoTrans, blas.Trans} {
+		want := mat64.NewVector(n, nil)
+		MulMatVec(want, -2, trans != blas.NoTrans, csr, mat64.NewVector(n, x))
+		for _, a := range []MatrixOf[float64]{
+			NewCSR32(dok), NewBSR(dok, 2), dia, NewELL(csr), NewSELL(csr, 4, 8), sym, NewSkyline(sym),
+		} {
+			got := make([]float64, n)
+			MulMatVecOf(got, -2, trans, a, x)
+			if !reflect.DeepEqual(got, want.RawVector().Data) {
+				t.Errorf("%T, trans=%c: want %v, got %v", a, trans, want.RawVector().Data, got)
+			}
+		}
+	}
+}
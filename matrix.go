@@ -14,6 +14,46 @@ type Matrix interface {
 	At(r, c int) float64
 }
 
+// MatrixOf is a sparse matrix with element type T.
+type MatrixOf[T Scalar] interface {
+	// Dims returns the dimensions of the matrix.
+	Dims() (r, c int)
+
+	// At returns the value of the matrix entry at (r, c). It will panic if r
+	// or c are out of bounds for the matrix.
+	At(r, c int) T
+}
+
+// LinearOperatorOf is a linear operator with element type T that can be
+// applied to dense vectors without access to its entries, for example
+// a Jacobian-vector product or a composition of matrices.
+type LinearOperatorOf[T Scalar] interface {
+	// Dims returns the dimensions of the operator.
+	Dims() (r, c int)
+
+	// MulVecTo computes dst = A * x, or dst = Aᴴ * x if trans is true.
+	// For real T, Aᴴ is equal to Aᵀ.
+	MulVecTo(dst []T, trans bool, x []T)
+}
+
+// LinearOperator is a real linear operator.
+type LinearOperator = LinearOperatorOf[float64]
+
+// NonZeroDoer is a matrix that can iterate over its non-zero entries.
+type NonZeroDoer interface {
+	// DoNonZero calls fn for each stored entry of the matrix in no particular
+	// order. Explicitly stored zero values may be passed to fn.
+	DoNonZero(fn func(r, c int, v float64))
+}
+
+// PropertiesMatrix is a matrix that carries its MatrixProperties.
+type PropertiesMatrix interface {
+	Matrix
+
+	// Properties returns the properties of the matrix.
+	Properties() MatrixProperties
+}
+
 // MutableMatrix is a matrix that can modify its non-zero entries without
 // changing its sparsity structure.
 type MutableMatrix interface {
@@ -0,0 +1,167 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gonum/blas"
+)
+
+// ReadMatrixMarket reads a sparse matrix in the Matrix Market coordinate
+// format from r. Real, integer and pattern matrices with general, symmetric
+// and skew-symmetric structure are supported. Entries of pattern matrices are
+// set to one. Symmetric and skew-symmetric matrices are expanded so that the
+// returned DOK holds both triangles. The Symmetric property of the returned
+// matrix is set according to the header.
+func ReadMatrixMarket(r io.Reader) (*DOK, error) {
+	a, _, err := readMatrixMarket(r, true)
+	return a, err
+}
+
+// ReadSymMatrixMarket reads a symmetric sparse matrix in the Matrix Market
+// coordinate format from r. Only the lower triangle stored in the file is
+// kept in memory. ReadSymMatrixMarket returns an error if the header does not
+// declare the matrix symmetric.
+func ReadSymMatrixMarket(r io.Reader) (*SymCSR, error) {
+	a, symmetry, err := readMatrixMarket(r, false)
+	if err != nil {
+		return nil, err
+	}
+	if symmetry != "symmetric" {
+		return nil, errors.New("sparse: matrix not symmetric")
+	}
+	return NewSymCSR(a, blas.Lower), nil
+}
+
+// readMatrixMarket reads a sparse matrix in the Matrix Market coordinate
+// format from r and returns it together with the symmetry given in the
+// header. If expand is false, symmetric and skew-symmetric matrices are not
+// expanded and entries of symmetric matrices are stored in the lower
+// triangle.
+func readMatrixMarket(r io.Reader, expand bool) (*DOK, string, error) {
+	s := bufio.NewScanner(r)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, "", err
+		}
+		return nil, "", errors.New("sparse: missing Matrix Market header")
+	}
+	header := strings.Fields(strings.ToLower(s.Text()))
+	if len(header) != 5 || header[0] != "%%matrixmarket" || header[1] != "matrix" {
+		return nil, "", errors.New("sparse: invalid Matrix Market header")
+	}
+	if header[2] != "coordinate" {
+		return nil, "", errors.New("sparse: unsupported Matrix Market format " + header[2])
+	}
+	field := header[3]
+	switch field {
+	case "real", "integer", "pattern":
+	default:
+		return nil, "", errors.New("sparse: unsupported Matrix Market field " + field)
+	}
+	symmetry := header[4]
+	switch symmetry {
+	case "general", "symmetric", "skew-symmetric":
+	default:
+		return nil, "", errors.New("sparse: unsupported Matrix Market symmetry " + symmetry)
+	}
+
+	var line string
+	for s.Scan() {
+		line = s.Text()
+		if !strings.HasPrefix(line, "%") && strings.TrimSpace(line) != "" {
+			break
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, "", err
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return nil, "", errors.New("sparse: invalid Matrix Market size line")
+	}
+	rows, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, "", err
+	}
+	cols, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, "", err
+	}
+	if symmetry != "general" && rows != cols {
+		return nil, "", errors.New("sparse: symmetric matrix is not square")
+	}
+	nnz, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, "", err
+	}
+
+	a := NewDOK(rows, cols)
+	if symmetry == "symmetric" {
+		a.SetProperties(MatrixProperties{Symmetric: true})
+	}
+	var count int
+	for s.Scan() {
+		line = s.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if field == "pattern" && len(fields) != 2 || field != "pattern" && len(fields) != 3 {
+			return nil, "", errors.New("sparse: invalid Matrix Market entry")
+		}
+
+		i, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, "", err
+		}
+		j, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, "", err
+		}
+		if i < 1 || rows < i || j < 1 || cols < j {
+			return nil, "", errors.New("sparse: Matrix Market entry out of range")
+		}
+		v := 1.0
+		if field != "pattern" {
+			v, err = strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+
+		if !expand && symmetry == "symmetric" && i < j {
+			// Entries of symmetric matrices should be in the lower
+			// triangle, but we accept either.
+			i, j = j, i
+		}
+
+		a.InsertEntry(i-1, j-1, v)
+		if expand && i != j {
+			switch symmetry {
+			case "symmetric":
+				a.InsertEntry(j-1, i-1, v)
+			case "skew-symmetric":
+				a.InsertEntry(j-1, i-1, -v)
+			}
+		}
+		count++
+	}
+	if err := s.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if count != nnz {
+		return nil, "", errors.New("sparse: mismatched number of non-zeros")
+	}
+
+	return a, symmetry, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadMatrixMarket(t *testing.T) {
+	for i, test := range []struct {
+		data string
+		want [][]float64
+	}{
+		{
+			data: `%%MatrixMarket matrix coordinate real general
+% comment
+2 3 3
+1 1 1.5
+2 3 -2
+1 2 3
+`,
+			want: [][]float64{
+				{1.5, 3, 0},
+				{0, 0, -2},
+			},
+		},
+		{
+			data: `%%MatrixMarket matrix coordinate real symmetric
+3 3 4
+1 1 4
+2 1 -1
+3 2 -1
+3 3 4
+`,
+			want: [][]float64{
+				{4, -1, 0},
+				{-1, 0, -1},
+				{0, -1, 4},
+			},
+		},
+		{
+			data: `%%MatrixMarket matrix coordinate integer skew-symmetric
+2 2 1
+2 1 3
+`,
+			want: [][]float64{
+				{0, -3},
+				{3, 0},
+			},
+		},
+		{
+			data: `%%MatrixMarket matrix coordinate pattern general
+2 2 2
+1 2
+2 2
+`,
+			want: [][]float64{
+				{0, 1},
+				{0, 1},
+			},
+		},
+	} {
+		a, err := ReadMatrixMarket(strings.NewReader(test.data))
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		if sym := strings.Contains(test.data, " symmetric"); a.Properties().Symmetric != sym {
+			t.Errorf("test %d: unexpected Symmetric property, want %t", i, sym)
+		}
+		r, c := a.Dims()
+		if r != len(test.want) || c != len(test.want[0]) {
+			t.Errorf("test %d: unexpected dimensions %d×%d", i, r, c)
+			continue
+		}
+		for ii := range test.want {
+			for jj, v := range test.want[ii] {
+				if a.At(ii, jj) != v {
+					t.Errorf("test %d: entries not equal at (%d,%d): want %v, got %v", i, ii, jj, v, a.At(ii, jj))
+				}
+			}
+		}
+	}
+
+	for i, data := range []string{
+		"",
+		"%%MatrixMarket matrix array real general\n2 2\n1\n2\n3\n4\n",
+		"%%MatrixMarket matrix coordinate complex general\n1 1 1\n1 1 1 0\n",
+		"%%MatrixMarket matrix coordinate real symmetric\n2 3 0\n",
+		"%%MatrixMarket matrix coordinate real general\n2 2 2\n1 1 1\n",
+		"%%MatrixMarket matrix coordinate real general\n2 2 1\n3 1 1\n",
+	} {
+		if _, err := ReadMatrixMarket(strings.NewReader(data)); err == nil {
+			t.Errorf("test %d: expected error for invalid input", i)
+		}
+	}
+}
+
+func TestReadSymMatrixMarket(t *testing.T) {
+	data := `%%MatrixMarket matrix coordinate real symmetric
+3 3 4
+1 1 4
+2 1 -1
+2 3 -1
+3 3 4
+`
+	want := [][]float64{
+		{4, -1, 0},
+		{-1, 0, -1},
+		{0, -1, 4},
+	}
+
+	a, err := ReadSymMatrixMarket(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if nnz := a.Triangle().rowIndex[3]; nnz != 4 {
+		t.Errorf("unexpected number of stored entries: want 4, got %d", nnz)
+	}
+	for i := range want {
+		for j, v := range want[i] {
+			if a.At(i, j) != v {
+				t.Errorf("entries not equal at (%d,%d): want %v, got %v", i, j, v, a.At(i, j))
+			}
+		}
+	}
+
+	data = "%%MatrixMarket matrix coordinate real general\n1 1 1\n1 1 1\n"
+	if _, err := ReadSymMatrixMarket(strings.NewReader(data)); err == nil {
+		t.Errorf("expected error for general matrix")
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Class identifiers that start every object in the PETSc binary format.
+const (
+	petscMatClassID = 1211216
+	petscVecClassID = 1211214
+)
+
+// petscChunk is the number of elements read at once from PETSc binary data.
+// The sizes in the header are not trusted, so the data is read in chunks and
+// memory is only allocated for the elements actually present in the input.
+const petscChunk = 1 << 16
+
+// readInt32s reads n big-endian 32-bit integers from r.
+func readInt32s(r io.Reader, n int) ([]int32, error) {
+	s := make([]int32, 0, min(n, petscChunk))
+	for len(s) < n {
+		chunk := make([]int32, min(n-len(s), petscChunk))
+		if err := binary.Read(r, binary.BigEndian, chunk); err != nil {
+			return nil, err
+		}
+		s = append(s, chunk...)
+	}
+	return s, nil
+}
+
+// readFloat64s reads n big-endian 64-bit floats from r.
+func readFloat64s(r io.Reader, n int) ([]float64, error) {
+	s := make([]float64, 0, min(n, petscChunk))
+	for len(s) < n {
+		chunk := make([]float64, min(n-len(s), petscChunk))
+		if err := binary.Read(r, binary.BigEndian, chunk); err != nil {
+			return nil, err
+		}
+		s = append(s, chunk...)
+	}
+	return s, nil
+}
+
+// ReadPETScMatrix reads a sparse matrix stored in the PETSc binary format as
+// written by MatView with a binary viewer. The format is big-endian and
+// consists of the class identifier, the number of rows and columns, the total
+// number of non-zeros, the number of non-zeros in each row, the column
+// indices and finally the values, all indices being 32-bit integers and
+// values 64-bit floats. The column indices in each row must be sorted and
+// unique, otherwise an error is returned.
+//
+// ReadPETScMatrix does not read past the end of the matrix, so a vector
+// stored after it in the same file can be read by a subsequent call to
+// ReadPETScVector.
+func ReadPETScMatrix(r io.Reader) (*CSR, error) {
+	rows, cols, rowIndex, columns, values, err := readPETScMatrix(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &CSR{
+		rows:     rows,
+		cols:     cols,
+		values:   values,
+		columns:  make([]int, len(columns)),
+		rowIndex: make([]int, rows+1),
+	}
+	for k, j := range columns {
+		m.columns[k] = int(j)
+	}
+	for i, k := range rowIndex {
+		m.rowIndex[i] = int(k)
+	}
+	return m, nil
+}
+
+// ReadPETScMatrix32 reads a sparse matrix stored in the PETSc binary format
+// like ReadPETScMatrix, but returns a matrix with 32-bit column indices that
+// are used without conversion.
+func ReadPETScMatrix32(r io.Reader) (*CSR32, error) {
+	rows, cols, rowIndex, columns, values, err := readPETScMatrix(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CSR32{
+		rows:     rows,
+		cols:     cols,
+		values:   values,
+		columns:  columns,
+		rowIndex: rowIndex,
+	}, nil
+}
+
+func readPETScMatrix(r io.Reader) (rows, cols int, rowIndex []int64, columns []int32, values []float64, err error) {
+	var header [4]int32
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return 0, 0, nil, nil, nil, err
+	}
+	if header[0] != petscMatClassID {
+		return 0, 0, nil, nil, nil, errors.New("sparse: not a PETSc matrix")
+	}
+	rows, cols, nnz := int(header[1]), int(header[2]), int(header[3])
+	if rows < 0 || cols < 0 {
+		return 0, 0, nil, nil, nil, errors.New("sparse: negative PETSc matrix dimension")
+	}
+	if nnz < 0 {
+		return 0, 0, nil, nil, nil, errors.New("sparse: unsupported PETSc matrix format")
+	}
+
+	rowNnz, err := readInt32s(r, rows)
+	if err != nil {
+		return 0, 0, nil, nil, nil, err
+	}
+	rowIndex = make([]int64, rows+1)
+	for i, n := range rowNnz {
+		if n < 0 {
+			return 0, 0, nil, nil, nil, errors.New("sparse: negative PETSc row length")
+		}
+		rowIndex[i+1] = rowIndex[i] + int64(n)
+	}
+	if rowIndex[rows] != int64(nnz) {
+		return 0, 0, nil, nil, nil, errors.New("sparse: mismatched number of non-zeros")
+	}
+
+	columns, err = readInt32s(r, nnz)
+	if err != nil {
+		return 0, 0, nil, nil, nil, err
+	}
+	for i := 0; i < rows; i++ {
+		for k := rowIndex[i]; k < rowIndex[i+1]; k++ {
+			j := columns[k]
+			if j < 0 || int(j) >= cols {
+				return 0, 0, nil, nil, nil, errors.New("sparse: PETSc column index out of range")
+			}
+			if k > rowIndex[i] && j <= columns[k-1] {
+				return 0, 0, nil, nil, nil, errors.New("sparse: unsorted or duplicate PETSc column indices")
+			}
+		}
+	}
+
+	values, err = readFloat64s(r, nnz)
+	if err != nil {
+		return 0, 0, nil, nil, nil, err
+	}
+	return rows, cols, rowIndex, columns, values, nil
+}
+
+// WritePETScMatrix writes the matrix m to w in the PETSc binary format that
+// can be loaded by MatLoad.
+func WritePETScMatrix(w io.Writer, m *CSR) error {
+	nnz := m.rowIndex[m.rows]
+	if m.rows > math.MaxInt32 || m.cols > math.MaxInt32 || nnz > math.MaxInt32 {
+		return errors.New("sparse: matrix too large for the PETSc format")
+	}
+
+	rowNnz := make([]int32, m.rows)
+	for i := range rowNnz {
+		rowNnz[i] = int32(m.rowIndex[i+1] - m.rowIndex[i])
+	}
+	cols32 := make([]int32, nnz)
+	for k, j := range m.columns[:nnz] {
+		cols32[k] = int32(j)
+	}
+	return writePETScMatrix(w, m.rows, m.cols, rowNnz, cols32, m.values[:nnz])
+}
+
+// WritePETScMatrix32 writes the matrix m to w in the PETSc binary format that
+// can be loaded by MatLoad.
+func WritePETScMatrix32(w io.Writer, m *CSR32) error {
+	nnz := m.rowIndex[m.rows]
+	if m.rows > math.MaxInt32 || nnz > math.MaxInt32 {
+		return errors.New("sparse: matrix too large for the PETSc format")
+	}
+
+	rowNnz := make([]int32, m.rows)
+	for i := range rowNnz {
+		rowNnz[i] = int32(m.rowIndex[i+1] - m.rowIndex[i])
+	}
+	return writePETScMatrix(w, m.rows, m.cols, rowNnz, m.columns[:nnz], m.values[:nnz])
+}
+
+func writePETScMatrix(w io.Writer, rows, cols int, rowNnz, columns []int32, values []float64) error {
+	header := [4]int32{petscMatClassID, int32(rows), int32(cols), int32(len(values))}
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, rowNnz); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, columns); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, values)
+}
+
+// ReadPETScVector reads a dense vector stored in the PETSc binary format as
+// written by VecView with a binary viewer.
+func ReadPETScVector(r io.Reader) (*mat64.Vector, error) {
+	var header [2]int32
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	if header[0] != petscVecClassID {
+		return nil, errors.New("sparse: not a PETSc vector")
+	}
+	n := int(header[1])
+	if n < 0 {
+		return nil, errors.New("sparse: negative PETSc vector dimension")
+	}
+
+	data, err := readFloat64s(r, n)
+	if err != nil {
+		return nil, err
+	}
+	return mat64.NewVector(n, data), nil
+}
+
+// WritePETScVector writes the vector v to w in the PETSc binary format that
+// can be loaded by VecLoad.
+func WritePETScVector(w io.Writer, v *mat64.Vector) error {
+	n := v.Len()
+	if n > math.MaxInt32 {
+		return errors.New("sparse: vector too large for the PETSc format")
+	}
+
+	header := [2]int32{petscVecClassID, int32(n)}
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return err
+	}
+
+	data := make([]float64, n)
+	raw := v.RawVector()
+	for i := range data {
+		data[i] = raw.Data[i*raw.Inc]
+	}
+	return binary.Write(w, binary.BigEndian, data)
+}
@@ -0,0 +1,189 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestPETScMatrix(t *testing.T) {
+	// 2×3 matrix
+	//  1 0 2
+	//  0 3 0
+	// as written by MatView.
+	data := []byte{
+		0x00, 0x12, 0x7b, 0x50, // MAT_FILE_CLASSID
+		0x00, 0x00, 0x00, 0x02, // rows
+		0x00, 0x00, 0x00, 0x03, // columns
+		0x00, 0x00, 0x00, 0x03, // non-zeros
+		0x00, 0x00, 0x00, 0x02, // non-zeros in row 0
+		0x00, 0x00, 0x00, 0x01, // non-zeros in row 1
+		0x00, 0x00, 0x00, 0x00, // column indices
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x01,
+		0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // values
+		0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x40, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	want := [][]float64{
+		{1, 0, 2},
+		{0, 3, 0},
+	}
+
+	m, err := ReadPETScMatrix(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, c := m.Dims()
+	if r != 2 || c != 3 {
+		t.Fatalf("unexpected dimensions: want 2×3, got %d×%d", r, c)
+	}
+	for i := range want {
+		for j, v := range want[i] {
+			if m.At(i, j) != v {
+				t.Errorf("entries not equal at (%d,%d): want %v, got %v", i, j, v, m.At(i, j))
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WritePETScMatrix(&buf, m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("unexpected output of WritePETScMatrix:\nwant %x\ngot  %x", data, buf.Bytes())
+	}
+
+	m32, err := ReadPETScMatrix32(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(m32, NewCSR32FromCSR(m)) {
+		t.Errorf("ReadPETScMatrix32 and ReadPETScMatrix differ")
+	}
+	buf.Reset()
+	if err := WritePETScMatrix32(&buf, m32); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("unexpected output of WritePETScMatrix32:\nwant %x\ngot  %x", data, buf.Bytes())
+	}
+
+	if _, err := ReadPETScMatrix(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Errorf("expected error for truncated input")
+	}
+	if _, err := ReadPETScVector(bytes.NewReader(data)); err == nil {
+		t.Errorf("expected error for class identifier mismatch")
+	}
+}
+
+func TestPETScMatrixMalformed(t *testing.T) {
+	header := []byte{
+		0x00, 0x12, 0x7b, 0x50, // MAT_FILE_CLASSID
+		0x00, 0x00, 0x00, 0x01, // rows
+		0x00, 0x00, 0x00, 0x03, // columns
+		0x00, 0x00, 0x00, 0x02, // non-zeros
+		0x00, 0x00, 0x00, 0x02, // non-zeros in row 0
+	}
+	values := []byte{
+		0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	for _, test := range []struct {
+		name    string
+		columns []byte
+	}{
+		{"unsorted", []byte{0, 0, 0, 2, 0, 0, 0, 1}},
+		{"duplicate", []byte{0, 0, 0, 1, 0, 0, 0, 1}},
+		{"out of range", []byte{0, 0, 0, 1, 0, 0, 0, 3}},
+	} {
+		data := append(append(append([]byte{}, header...), test.columns...), values...)
+		if _, err := ReadPETScMatrix(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected error for column indices", test.name)
+		}
+	}
+
+	// The header claims 2^31-1 rows and non-zeros, but the input ends
+	// after it. The reader must fail without allocating for the claimed
+	// sizes.
+	huge := []byte{
+		0x00, 0x12, 0x7b, 0x50,
+		0x7f, 0xff, 0xff, 0xff,
+		0x7f, 0xff, 0xff, 0xff,
+		0x7f, 0xff, 0xff, 0xff,
+		0x00, 0x00, 0x00, 0x01,
+	}
+	if _, err := ReadPETScMatrix(bytes.NewReader(huge)); err == nil {
+		t.Errorf("expected error for truncated matrix with huge header")
+	}
+	hugeVec := []byte{
+		0x00, 0x12, 0x7b, 0x4e,
+		0x7f, 0xff, 0xff, 0xff,
+		0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	if _, err := ReadPETScVector(bytes.NewReader(hugeVec)); err == nil {
+		t.Errorf("expected error for truncated vector with huge header")
+	}
+}
+
+func TestPETScVector(t *testing.T) {
+	for _, test := range [][]float64{
+		{},
+		{1},
+		{1, -2, 3.5, 0},
+	} {
+		var buf bytes.Buffer
+		if err := WritePETScVector(&buf, mat64.NewVector(len(test), test)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if buf.Len() != 8+8*len(test) {
+			t.Errorf("unexpected output length: want %d, got %d", 8+8*len(test), buf.Len())
+		}
+		v, err := ReadPETScVector(&buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(v.RawVector().Data, test) {
+			t.Errorf("round trip failed: want %v, got %v", test, v.RawVector().Data)
+		}
+	}
+}
+
+func TestPETScSequential(t *testing.T) {
+	dok := NewDOK(3, 3)
+	dok.InsertEntry(0, 0, 4)
+	dok.InsertEntry(1, 1, 5)
+	dok.InsertEntry(2, 0, -1)
+	dok.InsertEntry(2, 2, 6)
+	a := NewCSR(dok)
+	b := mat64.NewVector(3, []float64{1, 2, 3})
+
+	var buf bytes.Buffer
+	if err := WritePETScMatrix(&buf, a); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := WritePETScVector(&buf, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	gotA, err := ReadPETScMatrix(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading matrix: %v", err)
+	}
+	gotB, err := ReadPETScVector(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading vector: %v", err)
+	}
+	if !reflect.DeepEqual(gotA, a) {
+		t.Errorf("matrix round trip failed: want %v, got %v", a, gotA)
+	}
+	if !reflect.DeepEqual(gotB.RawVector().Data, b.RawVector().Data) {
+		t.Errorf("vector round trip failed: want %v, got %v", b.RawVector().Data, gotB.RawVector().Data)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// SELL is a sparse matrix in the sliced ELLPACK format SELL-C-σ. Rows are
+// sorted by decreasing length within windows of σ consecutive rows and the
+// sorted rows are grouped into chunks of C rows. Each chunk is stored in the
+// ELLPACK format padded only to the length of its longest row, which reduces
+// padding compared to ELL for irregular matrices while keeping the inner loop
+// over C rows with unit stride.
+type SELL struct {
+	rows, cols int
+	c, sigma   int
+	nnz        int
+
+	perm    []int // perm[p] is the row of the matrix stored at position p.
+	invPerm []int // invPerm[i] is the position of row i of the matrix.
+
+	// Chunk k holds positions k*c to (k+1)*c-1 and its entries start at
+	// chunkIndex[k]. The l-th entry of the row at position k*c+p is stored
+	// at chunkIndex[k]+l*c+p. Padding entries, including entries of padding
+	// rows in the last chunk, have zero value and column index -1 and are
+	// skipped in products.
+	chunkIndex []int
+	values     []float64
+	columns    []int
+
+	props MatrixProperties
+}
+
+// NewSELL returns a new SELL-C-σ matrix with chunk size c and sorting window
+// sigma that holds the entries of a. If sigma is one, the rows are not
+// sorted. NewSELL will panic if c or sigma is not positive or if sigma is not
+// a multiple of c when greater than one.
+func NewSELL(a *CSR, c, sigma int) *SELL {
+	if c <= 0 {
+		panic("sparse: non-positive chunk size")
+	}
+	if sigma <= 0 {
+		panic("sparse: non-positive sorting window")
+	}
+	if sigma > 1 && sigma%c != 0 {
+		panic("sparse: sorting window not multiple of the chunk size")
+	}
+
+	rows := a.rows
+	rowLen := func(i int) int { return a.rowIndex[i+1] - a.rowIndex[i] }
+
+	perm := make([]int, rows)
+	for i := range perm {
+		perm[i] = i
+	}
+	if sigma > 1 {
+		for start := 0; start < rows; start += sigma {
+			end := start + sigma
+			if end > rows {
+				end = rows
+			}
+			window := perm[start:end]
+			sort.SliceStable(window, func(i, j int) bool {
+				return rowLen(window[i]) > rowLen(window[j])
+			})
+		}
+	}
+	invPerm := make([]int, rows)
+	for p, i := range perm {
+		invPerm[i] = p
+	}
+
+	chunks := (rows + c - 1) / c
+	chunkIndex := make([]int, chunks+1)
+	for k := 0; k < chunks; k++ {
+		var width int
+		for p := k * c; p < (k+1)*c && p < rows; p++ {
+			if n := rowLen(perm[p]); n > width {
+				width = n
+			}
+		}
+		chunkIndex[k+1] = chunkIndex[k] + width*c
+	}
+
+	m := &SELL{
+		rows:       rows,
+		cols:       a.cols,
+		c:          c,
+		sigma:      sigma,
+		nnz:        a.rowIndex[rows],
+		perm:       perm,
+		invPerm:    invPerm,
+		chunkIndex: chunkIndex,
+		values:     make([]float64, chunkIndex[chunks]),
+		columns:    make([]int, chunkIndex[chunks]),
+		props:      a.props,
+	}
+	for j := range m.columns {
+		m.columns[j] = -1
+	}
+	for p, i := range perm {
+		k, lane := p/c, p%c
+		for l, j := 0, a.rowIndex[i]; j < a.rowIndex[i+1]; l, j = l+1, j+1 {
+			m.values[chunkIndex[k]+l*c+lane] = a.values[j]
+			m.columns[chunkIndex[k]+l*c+lane] = a.columns[j]
+		}
+	}
+	return m
+}
+
+func (m *SELL) Dims() (r, c int) {
+	return m.rows, m.cols
+}
+
+func (m *SELL) MulVecTo(dst []float64, trans bool, x []float64) {
+	mulVecTo(dst, trans, m, x)
+}
+
+func (m *SELL) At(r, c int) float64 {
+	if r >= m.rows || r < 0 {
+		panic("sparse: row index out of range")
+	}
+	if c >= m.cols || c < 0 {
+		panic("sparse: column index out of range")
+	}
+
+	p := m.invPerm[r]
+	k, lane := p/m.c, p%m.c
+	for j := m.chunkIndex[k] + lane; j < m.chunkIndex[k+1]; j += m.c {
+		if m.columns[j] == c {
+			return m.values[j]
+		}
+	}
+	return 0
+}
+
+func (m *SELL) Properties() MatrixProperties {
+	return m.props
+}
+
+// ChunkSize returns the number of rows in a chunk.
+func (m *SELL) ChunkSize() int {
+	return m.c
+}
+
+// SortingWindow returns the number of consecutive rows within which the rows
+// are sorted by length.
+func (m *SELL) SortingWindow() int {
+	return m.sigma
+}
+
+// Padding returns statistics of the storage overhead due to padding.
+func (m *SELL) Padding() PaddingStats {
+	return PaddingStats{
+		NNZ:    m.nnz,
+		Stored: len(m.values),
+	}
+}
+
+func sellMulMatVec(y *mat64.Vector, alpha float64, transA bool, a *SELL, x *mat64.Vector) {
+	r, c := a.Dims()
+	if transA {
+		if r != x.Len() || c != y.Len() {
+			panic("sparse: dimension mismatch")
+		}
+	} else {
+		if r != y.Len() || c != x.Len() {
+			panic("sparse: dimension mismatch")
+		}
+	}
+
+	if alpha == 0 {
+		return
+	}
+
+	xRaw := x.RawVector()
+	yRaw := y.RawVector()
+	cs := a.c
+	for k := 0; k+1 < len(a.chunkIndex); k++ {
+		start := k * cs
+		lanes := cs
+		if start+lanes > r {
+			lanes = r - start
+		}
+		perm := a.perm[start : start+lanes]
+		for j := a.chunkIndex[k]; j < a.chunkIndex[k+1]; j += cs {
+			values := a.values[j : j+lanes]
+			columns := a.columns[j : j+lanes]
+			if transA {
+				for p, v := range values {
+					if columns[p] < 0 {
+						continue
+					}
+					yRaw.Data[columns[p]*yRaw.Inc] += alpha * v * xRaw.Data[perm[p]*xRaw.Inc]
+				}
+			} else {
+				for p, v := range values {
+					if columns[p] < 0 {
+						continue
+					}
+					yRaw.Data[perm[p]*yRaw.Inc] += alpha * v * xRaw.Data[columns[p]*xRaw.Inc]
+				}
+			}
+		}
+	}
+}
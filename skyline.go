@@ -0,0 +1,270 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"errors"
+
+	"github.com/gonum/blas"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Skyline is a symmetric sparse matrix in the skyline (variable band, profile)
+// format. For every row, the entries of the lower triangle from the first
+// non-zero entry up to and including the diagonal are stored contiguously.
+// The stored part of the matrix, called the envelope, is closed under the
+// LDLᵀ factorization, so the factors can be stored in place.
+type Skyline struct {
+	n int
+
+	// Row i holds the entries in columns i-(rowIndex[i+1]-rowIndex[i])+1
+	// to i stored at values[rowIndex[i]:rowIndex[i+1]]. The last entry of
+	// each row is the diagonal entry.
+	values   []float64
+	rowIndex []int
+
+	props MatrixProperties
+}
+
+// NewSkyline returns a new Skyline matrix that holds the entries of the
+// symmetric matrix a.
+func NewSkyline(a *SymCSR) *Skyline {
+	n, _ := a.Dims()
+	first := make([]int, n)
+	for i := range first {
+		first[i] = i
+	}
+	tri := a.Triangle()
+	lower := a.Uplo() == blas.Lower
+	tri.DoNonZero(func(i, j int, v float64) {
+		if !lower {
+			i, j = j, i
+		}
+		if j < first[i] {
+			first[i] = j
+		}
+	})
+
+	rowIndex := make([]int, n+1)
+	for i := 0; i < n; i++ {
+		rowIndex[i+1] = rowIndex[i] + i - first[i] + 1
+	}
+	m := &Skyline{
+		n:        n,
+		values:   make([]float64, rowIndex[n]),
+		rowIndex: rowIndex,
+		props:    a.Properties(),
+	}
+	tri.DoNonZero(func(i, j int, v float64) {
+		if !lower {
+			i, j = j, i
+		}
+		m.values[m.rowIndex[i+1]-1-(i-j)] = v
+	})
+	return m
+}
+
+func (m *Skyline) Dims() (r, c int) {
+	return m.n, m.n
+}
+
+func (m *Skyline) MulVecTo(dst []float64, trans bool, x []float64) {
+	mulVecTo(dst, trans, m, x)
+}
+
+func (m *Skyline) At(r, c int) float64 {
+	if r >= m.n || r < 0 {
+		panic("sparse: row index out of range")
+	}
+	if c >= m.n || c < 0 {
+		panic("sparse: column index out of range")
+	}
+
+	if r < c {
+		r, c = c, r
+	}
+	if r-c >= m.rowIndex[r+1]-m.rowIndex[r] {
+		return 0
+	}
+	return m.values[m.rowIndex[r+1]-1-(r-c)]
+}
+
+// Properties returns the properties of the matrix. The Symmetric property
+// is always set.
+func (m *Skyline) Properties() MatrixProperties {
+	return m.props
+}
+
+// Profile returns the number of stored entries, that is, the size of the
+// envelope of the lower triangle including the diagonal.
+func (m *Skyline) Profile() int {
+	return len(m.values)
+}
+
+// DoNonZero calls fn for each stored entry and for the mirror image of each
+// stored off-diagonal entry, that is, as if the full matrix were stored.
+// Zeros inside the envelope are passed to fn.
+func (m *Skyline) DoNonZero(fn func(r, c int, v float64)) {
+	for i := 0; i < m.n; i++ {
+		first := i - (m.rowIndex[i+1] - m.rowIndex[i]) + 1
+		for j, v := range m.values[m.rowIndex[i]:m.rowIndex[i+1]] {
+			fn(i, first+j, v)
+			if first+j != i {
+				fn(first+j, i, v)
+			}
+		}
+	}
+}
+
+func skylineMulMatVec(y *mat64.Vector, alpha float64, a *Skyline, x *mat64.Vector) {
+	n := a.n
+	if n != x.Len() || n != y.Len() {
+		panic("sparse: dimension mismatch")
+	}
+
+	if alpha == 0 {
+		return
+	}
+
+	xRaw := x.RawVector()
+	yRaw := y.RawVector()
+	for i := 0; i < n; i++ {
+		row := a.values[a.rowIndex[i]:a.rowIndex[i+1]]
+		first := i - len(row) + 1
+		xi := xRaw.Data[i*xRaw.Inc]
+		sum := row[len(row)-1] * xi
+		for k, v := range row[:len(row)-1] {
+			j := first + k
+			sum += v * xRaw.Data[j*xRaw.Inc]
+			yRaw.Data[j*yRaw.Inc] += alpha * v * xi
+		}
+		yRaw.Data[i*yRaw.Inc] += alpha * sum
+	}
+}
+
+// SkylineLDLT is the LDLᵀ factorization of a symmetric matrix in the skyline
+// format, where L is unit lower triangular and D is diagonal. L has the same
+// envelope as the factorized matrix. The factorization is computed without
+// pivoting, so it exists for symmetric positive definite matrices and for
+// those indefinite matrices whose leading principal minors are all non-zero.
+type SkylineLDLT struct {
+	// The strictly lower triangle of L and the diagonal of D are stored in
+	// place of the lower triangle of the matrix.
+	factors Skyline
+}
+
+// Factorize computes the LDLᵀ factorization of the matrix a. The matrix a is
+// not modified. Factorize returns an error if a zero pivot is encountered.
+func (f *SkylineLDLT) Factorize(a *Skyline) error {
+	f.factors = Skyline{
+		n:        a.n,
+		values:   make([]float64, len(a.values)),
+		rowIndex: a.rowIndex,
+		props:    a.props,
+	}
+	copy(f.factors.values, a.values)
+	return f.factors.factorize()
+}
+
+// FactorizeInPlace computes the LDLᵀ factorization of the matrix a in place
+// of a, which avoids allocating storage for the factors. The receiver takes
+// over the storage of a, so a must not be used after the call.
+// FactorizeInPlace returns an error if a zero pivot is encountered.
+func (f *SkylineLDLT) FactorizeInPlace(a *Skyline) error {
+	f.factors = *a
+	*a = Skyline{}
+	return f.factors.factorize()
+}
+
+// factorize overwrites the lower triangle of m by the factors L and D.
+func (m *Skyline) factorize() error {
+	for i := 0; i < m.n; i++ {
+		row := m.values[m.rowIndex[i]:m.rowIndex[i+1]]
+		fi := i - len(row) + 1
+		// Overwrite a_ij by w_ij = l_ij d_j = a_ij - Σ_k w_ik l_jk.
+		for j := fi; j < i; j++ {
+			rowj := m.values[m.rowIndex[j]:m.rowIndex[j+1]]
+			fj := j - len(rowj) + 1
+			k := fi
+			if fj > k {
+				k = fj
+			}
+			var sum float64
+			for ; k < j; k++ {
+				sum += row[k-fi] * rowj[k-fj]
+			}
+			row[j-fi] -= sum
+		}
+		// Compute l_ij = w_ij / d_j and d_i = a_ii - Σ_j w_ij l_ij.
+		d := row[len(row)-1]
+		for j := fi; j < i; j++ {
+			dj := m.values[m.rowIndex[j+1]-1]
+			w := row[j-fi]
+			row[j-fi] = w / dj
+			d -= w * row[j-fi]
+		}
+		if d == 0 {
+			return errors.New("sparse: zero pivot in LDLᵀ factorization")
+		}
+		row[len(row)-1] = d
+	}
+	return nil
+}
+
+// SolveVec solves A * x = b with the factorized matrix A and stores the
+// result in x. The vectors x and b may be the same.
+func (f *SkylineLDLT) SolveVec(x, b *mat64.Vector) {
+	m := &f.factors
+	if b.Len() != m.n || x.Len() != m.n {
+		panic("sparse: dimension mismatch")
+	}
+
+	if x != b {
+		x.CopyVec(b)
+	}
+	raw := x.RawVector()
+	// Solve L z = b.
+	for i := 0; i < m.n; i++ {
+		row := m.values[m.rowIndex[i] : m.rowIndex[i+1]-1]
+		first := i - len(row)
+		var sum float64
+		for k, l := range row {
+			sum += l * raw.Data[(first+k)*raw.Inc]
+		}
+		raw.Data[i*raw.Inc] -= sum
+	}
+	// Solve D y = z.
+	for i := 0; i < m.n; i++ {
+		raw.Data[i*raw.Inc] /= m.values[m.rowIndex[i+1]-1]
+	}
+	// Solve Lᵀ x = y.
+	for i := m.n - 1; i >= 0; i-- {
+		row := m.values[m.rowIndex[i] : m.rowIndex[i+1]-1]
+		first := i - len(row)
+		xi := raw.Data[i*raw.Inc]
+		for k, l := range row {
+			raw.Data[(first+k)*raw.Inc] -= l * xi
+		}
+	}
+}
+
+// D returns the i-th diagonal entry of D.
+func (f *SkylineLDLT) D(i int) float64 {
+	if i >= f.factors.n || i < 0 {
+		panic("sparse: index out of range")
+	}
+	return f.factors.values[f.factors.rowIndex[i+1]-1]
+}
+
+// L returns the entry of the unit lower triangular factor L at (r, c).
+func (f *SkylineLDLT) L(r, c int) float64 {
+	if r == c {
+		return 1
+	}
+	if r < c {
+		return 0
+	}
+	return f.factors.At(r, c)
+}
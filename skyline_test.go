@@ -0,0 +1,137 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gonum/blas"
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestSkyline(t *testing.T) {
+	//  4 -1  0  2
+	// -1  4  0  0
+	//  0  0  4 -1
+	//  2  0 -1  4
+	dok := NewDOK(4, 4)
+	for _, e := range []Triplet{
+		{0, 0, 4}, {0, 1, -1}, {0, 3, 2},
+		{1, 0, -1}, {1, 1, 4},
+		{2, 2, 4}, {2, 3, -1},
+		{3, 0, 2}, {3, 2, -1}, {3, 3, 4},
+	} {
+		dok.InsertEntry(e.Row, e.Col, e.Value)
+	}
+	csr := NewCSR(dok)
+
+	for _, uplo := range []blas.Uplo{blas.Upper, blas.Lower} {
+		a := NewSkyline(NewSymCSR(dok, uplo))
+		if a.Profile() != 8 {
+			t.Errorf("unexpected profile: want 8, got %d", a.Profile())
+		}
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				if a.At(i, j) != dok.At(i, j) {
+					t.Errorf("entries not equal at (%d,%d): want %v, got %v", i, j, dok.At(i, j), a.At(i, j))
+				}
+			}
+		}
+
+		x := mat64.NewVector(4, []float64{1, -2, 3, -4})
+		want := mat64.NewVector(4, []float64{1, 1, 1, 1})
+		got := mat64.NewVector(4, []float64{1, 1, 1, 1})
+		MulMatVec(want, 3, false, csr, x)
+		MulMatVec(got, 3, false, a, x)
+		for i := 0; i < 4; i++ {
+			if got.At(i, 0) != want.At(i, 0) {
+				t.Errorf("unexpected result of MulMatVec: want %v, got %v", want.RawVector().Data, got.RawVector().Data)
+				break
+			}
+		}
+	}
+}
+
+func TestSkylineLDLT(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	// Symmetric indefinite matrix with non-zero leading principal minors.
+	indef := NewDOK(5, 5)
+	for _, e := range []Triplet{
+		{0, 0, 1}, {1, 1, -2}, {2, 2, 3}, {3, 3, -1}, {4, 4, 3},
+		{1, 0, 1}, {0, 1, 1},
+		{3, 1, 2}, {1, 3, 2},
+		{4, 0, -1}, {0, 4, -1},
+	} {
+		indef.InsertEntry(e.Row, e.Col, e.Value)
+	}
+
+	for _, dok := range []*DOK{
+		laplacian2DDOK(6),
+		indef,
+	} {
+		n, _ := dok.Dims()
+		a := NewSkyline(NewSymCSR(dok, blas.Lower))
+
+		want := mat64.NewVector(n, nil)
+		for i := 0; i < n; i++ {
+			want.SetVec(i, rnd.NormFloat64())
+		}
+		b := mat64.NewVector(n, nil)
+		MulMatVec(b, 1, false, a, want)
+
+		var f SkylineLDLT
+		if err := f.Factorize(a); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Check that L D Lᵀ = A.
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				var ldl float64
+				for k := 0; k < n; k++ {
+					ldl += f.L(i, k) * f.D(k) * f.L(j, k)
+				}
+				if math.Abs(ldl-dok.At(i, j)) > 1e-12 {
+					t.Errorf("L D Lᵀ and A not equal at (%d,%d): want %v, got %v", i, j, dok.At(i, j), ldl)
+				}
+			}
+		}
+
+		x := mat64.NewVector(n, nil)
+		f.SolveVec(x, b)
+		f.SolveVec(b, b)
+		for i := 0; i < n; i++ {
+			if math.Abs(x.At(i, 0)-want.At(i, 0)) > 1e-12 {
+				t.Errorf("unexpected solution at %d: want %v, got %v", i, want.At(i, 0), x.At(i, 0))
+			}
+			if b.At(i, 0) != x.At(i, 0) {
+				t.Errorf("in-place solve differs at %d", i)
+			}
+		}
+
+		var g SkylineLDLT
+		if err := g.FactorizeInPlace(a); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i := 0; i < n; i++ {
+			if g.D(i) != f.D(i) {
+				t.Errorf("in-place factorization differs at %d", i)
+			}
+		}
+	}
+
+	// Zero leading principal minor.
+	dok := NewDOK(2, 2)
+	dok.InsertEntry(0, 1, 1)
+	dok.InsertEntry(1, 0, 1)
+	dok.InsertEntry(1, 1, 1)
+	var f SkylineLDLT
+	if err := f.Factorize(NewSkyline(NewSymCSR(dok, blas.Upper))); err == nil {
+		t.Errorf("expected error for zero pivot")
+	}
+}
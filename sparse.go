@@ -4,21 +4,120 @@
 
 package sparse
 
+import "math"
+
+// MatrixProperties describes structural and numerical properties of a matrix
+// that algorithms can exploit.
 type MatrixProperties struct {
-	Symmetric       bool
-	LowerTriangular bool
-	UpperTriangular bool
+	Symmetric        bool
+	LowerTriangular  bool
+	UpperTriangular  bool
+	Diagonal         bool // Both lower and upper triangular.
+	PositiveDiagonal bool // Square with all diagonal entries positive.
+}
+
+// DetectProperties returns the properties of the matrix a determined from its
+// entries. The matrix is considered symmetric if it is square and
+//
+//  |a_ij - a_ji| ≤ tol * max(|a_ij|, |a_ji|)
+//
+// for all i, j. If a is a NonZeroDoer, only its stored entries are visited,
+// otherwise all entries are queried with At.
+func DetectProperties(a Matrix, tol float64) MatrixProperties {
+	if tol < 0 {
+		panic("sparse: negative tolerance")
+	}
+
+	r, c := a.Dims()
+	props := MatrixProperties{
+		Symmetric:       r == c,
+		LowerTriangular: true,
+		UpperTriangular: true,
+	}
+	check := func(i, j int, v float64) {
+		if v == 0 {
+			return
+		}
+		if i < j {
+			props.LowerTriangular = false
+		}
+		if i > j {
+			props.UpperTriangular = false
+		}
+		if props.Symmetric && i != j {
+			vt := a.At(j, i)
+			if math.Abs(v-vt) > tol*math.Max(math.Abs(v), math.Abs(vt)) {
+				props.Symmetric = false
+			}
+		}
+	}
+	if nz, ok := a.(NonZeroDoer); ok {
+		nz.DoNonZero(check)
+	} else {
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				check(i, j, a.At(i, j))
+			}
+		}
+	}
+	props.Diagonal = props.LowerTriangular && props.UpperTriangular
+
+	props.PositiveDiagonal = r == c && r > 0
+	for i := 0; i < r && props.PositiveDiagonal; i++ {
+		props.PositiveDiagonal = a.At(i, i) > 0
+	}
+
+	return props
+}
+
+// Scalar is the set of types that can be used as entries of generic sparse
+// matrices and vectors.
+type Scalar interface {
+	float32 | float64 | complex64 | complex128
 }
 
-type Triplet struct {
+// Real is the set of real types that can be used as entries of generic sparse
+// matrices and vectors.
+type Real interface {
+	float32 | float64
+}
+
+// conjFunc returns a function that computes the complex conjugate of values
+// of type T, or nil if T is a real type. The element type is resolved once
+// when conjFunc is called, so generic kernels call it before their loops
+// instead of switching on the type of every element.
+func conjFunc[T Scalar]() func(T) T {
+	var v T
+	switch any(v).(type) {
+	case complex64:
+		return any(func(z complex64) complex64 { return complex(real(z), -imag(z)) }).(func(T) T)
+	case complex128:
+		return any(func(z complex128) complex128 { return complex(real(z), -imag(z)) }).(func(T) T)
+	}
+	return nil
+}
+
+// TripletOf is an entry of a sparse matrix with element type T.
+type TripletOf[T Scalar] struct {
 	Row, Col int
-	Value    float64
+	Value    T
 }
 
-type rowWise []Triplet
+// Triplet is an entry of a sparse matrix.
+type Triplet = TripletOf[float64]
 
-func (r rowWise) Len() int      { return len(r) }
-func (r rowWise) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
-func (r rowWise) Less(i, j int) bool {
+type rowWise[T Scalar] []TripletOf[T]
+
+func (r rowWise[T]) Len() int      { return len(r) }
+func (r rowWise[T]) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r rowWise[T]) Less(i, j int) bool {
 	return r[i].Row < r[j].Row || (r[i].Row == r[j].Row && r[i].Col < r[j].Col)
 }
+
+type indexRowWise []Index
+
+func (r indexRowWise) Len() int      { return len(r) }
+func (r indexRowWise) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r indexRowWise) Less(i, j int) bool {
+	return r[i][0] < r[j][0] || (r[i][0] == r[j][0] && r[i][1] < r[j][1])
+}
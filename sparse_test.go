@@ -0,0 +1,92 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import "testing"
+
+func TestDetectProperties(t *testing.T) {
+	for id, test := range []struct {
+		r, c int
+		i, j []int
+		v    []float64
+		tol  float64
+
+		want MatrixProperties
+	}{
+		{
+			r: 3,
+			c: 3,
+			i: []int{0, 1, 1, 2, 0}, //  4 -1 0
+			j: []int{0, 0, 1, 2, 1}, // -1  2 0
+			v: []float64{4, -1, 2, 1, -1},
+			//  0  0 1
+
+			want: MatrixProperties{Symmetric: true, PositiveDiagonal: true},
+		},
+		{
+			r:   2,
+			c:   2,
+			i:   []int{0, 0, 1, 1},
+			j:   []int{0, 1, 0, 1},
+			v:   []float64{1, 1, 1 + 1e-10, -1},
+			tol: 0,
+
+			want: MatrixProperties{},
+		},
+		{
+			r:   2,
+			c:   2,
+			i:   []int{0, 0, 1, 1},
+			j:   []int{0, 1, 0, 1},
+			v:   []float64{1, 1, 1 + 1e-10, -1},
+			tol: 1e-8,
+
+			want: MatrixProperties{Symmetric: true},
+		},
+		{
+			r: 3,
+			c: 3,
+			i: []int{0, 1, 2, 2},
+			j: []int{0, 1, 0, 2},
+			v: []float64{2, 3, -1, 1},
+
+			want: MatrixProperties{LowerTriangular: true, PositiveDiagonal: true},
+		},
+		{
+			r: 3,
+			c: 3,
+			i: []int{0, 1, 2, 1},
+			j: []int{0, 1, 2, 0}, // Explicit zero below the diagonal.
+			v: []float64{2, 3, -1, 0},
+
+			want: MatrixProperties{
+				Symmetric:       true,
+				LowerTriangular: true,
+				UpperTriangular: true,
+				Diagonal:        true,
+			},
+		},
+		{
+			r: 2,
+			c: 3,
+			i: []int{0, 1, 0},
+			j: []int{0, 1, 2},
+			v: []float64{1, 1, 1},
+
+			want: MatrixProperties{UpperTriangular: true},
+		},
+	} {
+		dok := NewDOK(test.r, test.c)
+		for i := 0; i < len(test.v); i++ {
+			dok.InsertEntry(test.i[i], test.j[i], test.v[i])
+		}
+		for _, a := range []Matrix{dok, NewCSR(dok)} {
+			got := DetectProperties(a, test.tol)
+			if got != test.want {
+				t.Errorf("test %d: unexpected properties of %T: want %+v, got %+v", id+1, a, test.want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/vladimir-ch/sparse"
+	"github.com/vladimir-ch/sparse/spy"
+)
+
+func main() {
+	size := flag.Int("size", 512, "maximum size of the image in pixels")
+	out := flag.String("o", "", "output file with .png or .svg extension (default: input name with .png)")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("missing file name")
+	}
+	if *size <= 0 {
+		log.Fatal("size must be positive")
+	}
+	name := flag.Args()[0]
+
+	f, err := os.Open(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader
+	if path.Ext(name) == ".gz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			log.Fatal(err)
+		}
+		name = strings.TrimSuffix(name, ".gz")
+		r = gz
+	} else {
+		r = f
+	}
+
+	var a *sparse.DOK
+	switch path.Ext(name) {
+	case ".mtx":
+		a, err = sparse.ReadMatrixMarket(r)
+	default:
+		log.Fatal("unknown file extension")
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *out == "" {
+		*out = path.Base(strings.TrimSuffix(name, path.Ext(name))) + ".png"
+	}
+	w, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch path.Ext(*out) {
+	case ".png":
+		err = spy.WritePNG(w, a, *size)
+	case ".svg":
+		err = spy.WriteSVG(w, a, *size)
+	default:
+		log.Fatal("unknown output file extension")
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
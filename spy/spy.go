@@ -0,0 +1,211 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package spy renders sparsity patterns of sparse matrices.
+package spy
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/vladimir-ch/sparse"
+)
+
+// minShade is the darkness of a cell that contains a single non-zero entry
+// when the pattern is downsampled. It keeps isolated entries visible next to
+// dense blocks.
+const minShade = 0.25
+
+// Pattern is the sparsity pattern of a matrix mapped onto a grid of cells.
+// When the matrix is larger than the grid, each cell covers a square block
+// of matrix entries and holds the number of non-zeros in the block.
+type Pattern struct {
+	Rows, Cols int // Dimensions of the matrix.
+
+	// Block is the number of matrix rows and columns covered by one cell.
+	Block int
+
+	// GridRows and GridCols are the dimensions of the grid of cells.
+	GridRows, GridCols int
+
+	// Count holds the number of non-zeros in each cell stored row-wise.
+	Count []int
+
+	// Max is the largest value in Count.
+	Max int
+}
+
+// NewPattern returns the sparsity pattern of a downsampled so that neither
+// dimension of the grid exceeds size. If a is a sparse.NonZeroDoer, only its
+// stored entries are visited, otherwise all entries are queried with At.
+func NewPattern(a sparse.Matrix, size int) *Pattern {
+	if size <= 0 {
+		panic("spy: non-positive size")
+	}
+
+	r, c := a.Dims()
+	n := r
+	if c > n {
+		n = c
+	}
+	block := 1
+	if n > size {
+		block = (n + size - 1) / size
+	}
+	p := &Pattern{
+		Rows:     r,
+		Cols:     c,
+		Block:    block,
+		GridRows: (r + block - 1) / block,
+		GridCols: (c + block - 1) / block,
+	}
+	p.Count = make([]int, p.GridRows*p.GridCols)
+
+	add := func(i, j int, v float64) {
+		if v == 0 {
+			return
+		}
+		k := (i/block)*p.GridCols + j/block
+		p.Count[k]++
+		if p.Count[k] > p.Max {
+			p.Max = p.Count[k]
+		}
+	}
+	if nz, ok := a.(sparse.NonZeroDoer); ok {
+		nz.DoNonZero(add)
+	} else {
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				add(i, j, a.At(i, j))
+			}
+		}
+	}
+	return p
+}
+
+// NNZ returns the number of non-zeros in the pattern.
+func (p *Pattern) NNZ() int {
+	var nnz int
+	for _, n := range p.Count {
+		nnz += n
+	}
+	return nnz
+}
+
+// Shade returns the darkness of the cell (i, j) in the range [0, 1]. Empty
+// cells have zero shade. If the pattern is not downsampled, all non-empty
+// cells have shade one, otherwise the shade of a non-empty cell grows with
+// the number of non-zeros it contains relative to the densest cell.
+func (p *Pattern) Shade(i, j int) float64 {
+	n := p.Count[i*p.GridCols+j]
+	if n == 0 {
+		return 0
+	}
+	if p.Block == 1 || p.Max == 1 {
+		return 1
+	}
+	return minShade + (1-minShade)*float64(n-1)/float64(p.Max-1)
+}
+
+// Image returns the pattern as a grayscale image with non-zeros drawn dark on
+// a white background. Each cell is drawn as a square of scale×scale pixels.
+func (p *Pattern) Image(scale int) *image.Gray {
+	if scale <= 0 {
+		panic("spy: non-positive scale")
+	}
+
+	img := image.NewGray(image.Rect(0, 0, p.GridCols*scale, p.GridRows*scale))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for i := 0; i < p.GridRows; i++ {
+		for j := 0; j < p.GridCols; j++ {
+			s := p.Shade(i, j)
+			if s == 0 {
+				continue
+			}
+			gray := color.Gray{Y: uint8(255 * (1 - s))}
+			for y := i * scale; y < (i+1)*scale; y++ {
+				for x := j * scale; x < (j+1)*scale; x++ {
+					img.SetGray(x, y, gray)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// WriteSVG writes the pattern to w as an SVG image whose larger dimension is
+// size pixels. Every non-empty cell is drawn as a square with opacity given
+// by its shade.
+func (p *Pattern) WriteSVG(w io.Writer, size int) error {
+	if size <= 0 {
+		panic("spy: non-positive size")
+	}
+
+	n := p.GridRows
+	if p.GridCols > n {
+		n = p.GridCols
+	}
+	width, height := size, size
+	if n > 0 {
+		width = size * p.GridCols / n
+		height = size * p.GridRows / n
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		width, height, p.GridCols, p.GridRows)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="white" stroke="black" stroke-width="%g"/>`+"\n",
+		p.GridCols, p.GridRows, float64(n)/float64(size))
+	for i := 0; i < p.GridRows; i++ {
+		for j := 0; j < p.GridCols; j++ {
+			s := p.Shade(i, j)
+			if s == 0 {
+				continue
+			}
+			if s == 1 {
+				fmt.Fprintf(bw, `<rect x="%d" y="%d" width="1" height="1"/>`+"\n", j, i)
+			} else {
+				fmt.Fprintf(bw, `<rect x="%d" y="%d" width="1" height="1" fill-opacity="%.3f"/>`+"\n", j, i, s)
+			}
+		}
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// Image returns the sparsity pattern of a as a grayscale image whose larger
+// dimension is at most size pixels. Matrices larger than size are
+// downsampled and shaded by density, smaller matrices are magnified so that
+// every entry covers the same number of pixels.
+func Image(a sparse.Matrix, size int) image.Image {
+	p := NewPattern(a, size)
+	n := p.GridRows
+	if p.GridCols > n {
+		n = p.GridCols
+	}
+	scale := 1
+	if n > 0 && size/n > 1 {
+		scale = size / n
+	}
+	return p.Image(scale)
+}
+
+// WritePNG writes the sparsity pattern of a to w as a PNG image whose larger
+// dimension is at most size pixels.
+func WritePNG(w io.Writer, a sparse.Matrix, size int) error {
+	return png.Encode(w, Image(a, size))
+}
+
+// WriteSVG writes the sparsity pattern of a to w as an SVG image whose larger
+// dimension is size pixels. Matrices larger than size are downsampled and
+// shaded by density.
+func WriteSVG(w io.Writer, a sparse.Matrix, size int) error {
+	return NewPattern(a, size).WriteSVG(w, size)
+}
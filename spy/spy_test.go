@@ -0,0 +1,111 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spy
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/vladimir-ch/sparse"
+)
+
+// dense is a sparse.Matrix that does not implement sparse.NonZeroDoer.
+type dense [][]float64
+
+func (m dense) Dims() (r, c int)    { return len(m), len(m[0]) }
+func (m dense) At(i, j int) float64 { return m[i][j] }
+
+func TestPattern(t *testing.T) {
+	dok := sparse.NewDOK(4, 6)
+	dok.InsertEntry(0, 0, 1)
+	dok.InsertEntry(0, 1, 1)
+	dok.InsertEntry(1, 0, 1)
+	dok.InsertEntry(1, 1, 1)
+	dok.InsertEntry(3, 5, 1)
+	dok.InsertEntry(2, 3, 0) // Explicit zero is not drawn.
+
+	for _, a := range []sparse.Matrix{
+		dok,
+		sparse.NewCSR(dok),
+		dense{
+			{1, 1, 0, 0, 0, 0},
+			{1, 1, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0, 0},
+			{0, 0, 0, 0, 0, 1},
+		},
+	} {
+		p := NewPattern(a, 10)
+		if p.Block != 1 || p.GridRows != 4 || p.GridCols != 6 {
+			t.Errorf("unexpected grid: block %d, %d×%d", p.Block, p.GridRows, p.GridCols)
+		}
+		if p.NNZ() != 5 {
+			t.Errorf("unexpected number of non-zeros: want 5, got %d", p.NNZ())
+		}
+		if p.Shade(3, 5) != 1 || p.Shade(2, 3) != 0 {
+			t.Errorf("unexpected shades of undownsampled pattern")
+		}
+
+		p = NewPattern(a, 3)
+		if p.Block != 2 || p.GridRows != 2 || p.GridCols != 3 {
+			t.Errorf("unexpected downsampled grid: block %d, %d×%d", p.Block, p.GridRows, p.GridCols)
+		}
+		want := []int{4, 0, 0, 0, 0, 1}
+		for k, n := range want {
+			if p.Count[k] != n {
+				t.Errorf("unexpected count in cell %d: want %d, got %d", k, n, p.Count[k])
+			}
+		}
+		if p.Max != 4 || p.Shade(0, 0) != 1 || p.Shade(1, 2) != minShade {
+			t.Errorf("unexpected shades of downsampled pattern")
+		}
+	}
+}
+
+func TestImage(t *testing.T) {
+	dok := sparse.NewDOK(3, 2)
+	dok.InsertEntry(0, 0, 1)
+	dok.InsertEntry(2, 1, -1)
+
+	var buf bytes.Buffer
+	if err := WritePNG(&buf, dok, 30); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error decoding PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 30 {
+		t.Fatalf("unexpected image size %v", b)
+	}
+	for _, test := range []struct {
+		x, y int
+		dark bool
+	}{
+		{0, 0, true},
+		{9, 9, true},
+		{10, 0, false},
+		{15, 25, true},
+		{5, 25, false},
+	} {
+		r, _, _, _ := img.At(test.x, test.y).RGBA()
+		if (r == 0) != test.dark {
+			t.Errorf("unexpected pixel at (%d,%d)", test.x, test.y)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteSVG(&buf, dok, 30); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svg := buf.String()
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>\n") {
+		t.Errorf("malformed SVG output:\n%s", svg)
+	}
+	if n := strings.Count(svg, `width="1"`); n != 2 {
+		t.Errorf("unexpected number of cells in SVG: want 2, got %d", n)
+	}
+}
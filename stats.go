@@ -0,0 +1,202 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import "math"
+
+// Statistics describes the structure and values of a sparse matrix. Only
+// entries with non-zero value are taken into account, explicitly stored zeros
+// are ignored.
+type Statistics struct {
+	Rows, Cols int
+
+	// NNZ is the number of non-zero entries.
+	NNZ int
+
+	// Statistics of the number of non-zeros per row. RowNNZStdDev is the
+	// population standard deviation.
+	RowNNZMin, RowNNZMax     int
+	RowNNZMean, RowNNZStdDev float64
+
+	// EmptyRows and EmptyCols are the numbers of rows and columns without
+	// any non-zero entry.
+	EmptyRows, EmptyCols int
+
+	// LowerBandwidth is the largest i-j and UpperBandwidth is the largest
+	// j-i over all non-zero entries (i,j). Bandwidth is the larger of the
+	// two.
+	LowerBandwidth, UpperBandwidth, Bandwidth int
+
+	// Profile is the number of entries in the lower envelope of the matrix,
+	// that is, the sum over all rows i of i-f_i where f_i is the column of
+	// the first non-zero entry in row i if it lies left of the diagonal and
+	// i otherwise.
+	Profile int
+
+	// DiagonalNNZ is the number of non-zero entries on the main diagonal.
+	// MissingDiagonal is the number of zero entries on the main diagonal.
+	DiagonalNNZ, MissingDiagonal int
+
+	// StructuralSymmetry is the fraction of off-diagonal non-zeros a_ij for
+	// which a_ji is also non-zero. NumericalSymmetry is the fraction of
+	// off-diagonal non-zeros for which a_ji == a_ij. Both are zero for
+	// rectangular matrices and one for square matrices without off-diagonal
+	// non-zeros.
+	StructuralSymmetry, NumericalSymmetry float64
+
+	// DiagonalDominance is the fraction of rows i of a square matrix for
+	// which |a_ii| ≥ Σ_{j≠i} |a_ij|.
+	DiagonalDominance float64
+
+	// Min and Max are the smallest and largest non-zero values. MinAbs and
+	// MaxAbs are the smallest and largest absolute values of non-zero
+	// entries. All four are zero if the matrix has no non-zeros.
+	Min, Max       float64
+	MinAbs, MaxAbs float64
+
+	// Properties holds the properties detected from the data with zero
+	// tolerance.
+	Properties MatrixProperties
+}
+
+// Analyze computes Statistics of the matrix a. If a is a NonZeroDoer, only its
+// stored entries are visited, otherwise all entries are queried with At.
+func Analyze(a Matrix) Statistics {
+	r, c := a.Dims()
+	s := Statistics{
+		Rows: r,
+		Cols: c,
+	}
+
+	entries := make(map[Index]float64)
+	add := func(i, j int, v float64) {
+		if v != 0 {
+			entries[Index{i, j}] = v
+		}
+	}
+	if nz, ok := a.(NonZeroDoer); ok {
+		nz.DoNonZero(add)
+	} else {
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				add(i, j, a.At(i, j))
+			}
+		}
+	}
+	s.NNZ = len(entries)
+
+	rowNNZ := make([]int, r)
+	colNNZ := make([]int, c)
+	first := make([]int, r) // Column of the first non-zero in the lower envelope of each row.
+	for i := range first {
+		first[i] = i
+	}
+	offDiag := make([]float64, r) // Sum of absolute values of off-diagonal entries in each row.
+	diag := make([]float64, r)
+	var offDiagNNZ, structSym, numSym int
+	s.Min = math.Inf(1)
+	s.Max = math.Inf(-1)
+	s.MinAbs = math.Inf(1)
+	for ij, v := range entries {
+		i, j := ij[0], ij[1]
+		rowNNZ[i]++
+		colNNZ[j]++
+
+		if i-j > s.LowerBandwidth {
+			s.LowerBandwidth = i - j
+		}
+		if j-i > s.UpperBandwidth {
+			s.UpperBandwidth = j - i
+		}
+		if j < first[i] {
+			first[i] = j
+		}
+
+		if i == j {
+			s.DiagonalNNZ++
+			diag[i] = math.Abs(v)
+		} else {
+			offDiag[i] += math.Abs(v)
+			offDiagNNZ++
+			if vt, ok := entries[Index{j, i}]; ok {
+				structSym++
+				if vt == v {
+					numSym++
+				}
+			}
+		}
+
+		s.Min = math.Min(s.Min, v)
+		s.Max = math.Max(s.Max, v)
+		s.MinAbs = math.Min(s.MinAbs, math.Abs(v))
+		s.MaxAbs = math.Max(s.MaxAbs, math.Abs(v))
+	}
+	if s.NNZ == 0 {
+		s.Min, s.Max, s.MinAbs = 0, 0, 0
+	}
+	s.Bandwidth = s.LowerBandwidth
+	if s.UpperBandwidth > s.Bandwidth {
+		s.Bandwidth = s.UpperBandwidth
+	}
+
+	if r > 0 {
+		s.RowNNZMin = rowNNZ[0]
+	}
+	for i, n := range rowNNZ {
+		if n < s.RowNNZMin {
+			s.RowNNZMin = n
+		}
+		if n > s.RowNNZMax {
+			s.RowNNZMax = n
+		}
+		if n == 0 {
+			s.EmptyRows++
+		}
+		s.Profile += i - first[i]
+	}
+	if r > 0 {
+		s.RowNNZMean = float64(s.NNZ) / float64(r)
+		var ss float64
+		for _, n := range rowNNZ {
+			d := float64(n) - s.RowNNZMean
+			ss += d * d
+		}
+		s.RowNNZStdDev = math.Sqrt(ss / float64(r))
+	}
+	for _, n := range colNNZ {
+		if n == 0 {
+			s.EmptyCols++
+		}
+	}
+
+	n := r
+	if c < n {
+		n = c
+	}
+	s.MissingDiagonal = n - s.DiagonalNNZ
+
+	if r == c {
+		if offDiagNNZ == 0 {
+			s.StructuralSymmetry = 1
+			s.NumericalSymmetry = 1
+		} else {
+			s.StructuralSymmetry = float64(structSym) / float64(offDiagNNZ)
+			s.NumericalSymmetry = float64(numSym) / float64(offDiagNNZ)
+		}
+		if r > 0 {
+			var dominant int
+			for i := range diag {
+				if diag[i] >= offDiag[i] {
+					dominant++
+				}
+			}
+			s.DiagonalDominance = float64(dominant) / float64(r)
+		}
+	}
+
+	s.Properties = DetectProperties(a, 0)
+
+	return s
+}
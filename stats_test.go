@@ -0,0 +1,132 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	for id, test := range []struct {
+		r, c int
+		i, j []int
+		v    []float64
+
+		want Statistics
+	}{
+		{
+			r: 4,
+			c: 4,
+			i: []int{0, 0, 1, 1, 1, 2, 2, 3}, //  4 -1  0  0
+			j: []int{0, 1, 0, 1, 2, 1, 2, 1}, // -1  4  2  0
+			v: []float64{4, -1, -1, 4, 2, 3, 0, 5},
+			//  0  3  0  0
+			//  0  5  0  0
+
+			want: Statistics{
+				Rows:               4,
+				Cols:               4,
+				NNZ:                7,
+				RowNNZMin:          1,
+				RowNNZMax:          3,
+				RowNNZMean:         1.75,
+				RowNNZStdDev:       math.Sqrt(0.6875),
+				EmptyRows:          0,
+				EmptyCols:          1,
+				LowerBandwidth:     2,
+				UpperBandwidth:     1,
+				Bandwidth:          2,
+				Profile:            4,
+				DiagonalNNZ:        2,
+				MissingDiagonal:    2,
+				StructuralSymmetry: 0.8,
+				NumericalSymmetry:  0.4,
+				DiagonalDominance:  0.5,
+				Min:                -1,
+				Max:                5,
+				MinAbs:             1,
+				MaxAbs:             5,
+			},
+		},
+		{
+			r: 3,
+			c: 3,
+			i: []int{0, 1, 2, 2}, // 2 0 0
+			j: []int{0, 1, 0, 2}, // 0 3 0
+			v: []float64{2, 3, -1, 1},
+			// -1 0 1
+
+			want: Statistics{
+				Rows:               3,
+				Cols:               3,
+				NNZ:                4,
+				RowNNZMin:          1,
+				RowNNZMax:          2,
+				RowNNZMean:         4.0 / 3,
+				RowNNZStdDev:       math.Sqrt(2.0 / 9),
+				LowerBandwidth:     2,
+				Bandwidth:          2,
+				Profile:            2,
+				DiagonalNNZ:        3,
+				StructuralSymmetry: 0,
+				NumericalSymmetry:  0,
+				DiagonalDominance:  1,
+				Min:                -1,
+				Max:                3,
+				MinAbs:             1,
+				MaxAbs:             3,
+				Properties: MatrixProperties{
+					LowerTriangular:  true,
+					PositiveDiagonal: true,
+				},
+			},
+		},
+		{
+			r: 2,
+			c: 3,
+			i: []int{0},
+			j: []int{2},
+			v: []float64{-7},
+
+			want: Statistics{
+				Rows:            2,
+				Cols:            3,
+				NNZ:             1,
+				RowNNZMin:       0,
+				RowNNZMax:       1,
+				RowNNZMean:      0.5,
+				RowNNZStdDev:    0.5,
+				EmptyRows:       1,
+				EmptyCols:       2,
+				UpperBandwidth:  2,
+				Bandwidth:       2,
+				MissingDiagonal: 2,
+				Min:             -7,
+				Max:             -7,
+				MinAbs:          7,
+				MaxAbs:          7,
+				Properties: MatrixProperties{
+					UpperTriangular: true,
+				},
+			},
+		},
+	} {
+		dok := NewDOK(test.r, test.c)
+		for i := 0; i < len(test.v); i++ {
+			dok.InsertEntry(test.i[i], test.j[i], test.v[i])
+		}
+		for _, a := range []Matrix{dok, NewCSR(dok)} {
+			got := Analyze(a)
+			if math.Abs(got.RowNNZStdDev-test.want.RowNNZStdDev) < 1e-14 {
+				got.RowNNZStdDev = test.want.RowNNZStdDev
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("test %d: unexpected statistics of %T:\nwant %+v\ngot  %+v", id+1, a, test.want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"github.com/gonum/blas"
+	"github.com/gonum/matrix/mat64"
+)
+
+// SymCSR is a symmetric sparse matrix in the CSR format that stores only the
+// diagonal and the upper or lower triangle. Entries of the other triangle are
+// obtained by symmetry.
+type SymCSR struct {
+	uplo  blas.Uplo
+	tri   *CSR
+	props MatrixProperties
+}
+
+// NewSymCSR returns a new symmetric matrix that stores the diagonal and the
+// triangle of dok given by uplo. Entries of dok in the other triangle are
+// ignored, so dok may hold either only the stored triangle or the full
+// symmetric matrix. NewSymCSR will panic if dok is not square.
+func NewSymCSR(dok *DOK, uplo blas.Uplo) *SymCSR {
+	r, c := dok.Dims()
+	if r != c {
+		panic("sparse: matrix is not square")
+	}
+	return newSymCSR(r, uplo, dok.Triplets(), dok.props)
+}
+
+// NewSymCSRFromCSR returns a new symmetric matrix that stores the diagonal
+// and the triangle of a given by uplo. Entries of a in the other triangle are
+// ignored. NewSymCSRFromCSR will panic if a is not square.
+func NewSymCSRFromCSR(a *CSR, uplo blas.Uplo) *SymCSR {
+	r, c := a.Dims()
+	if r != c {
+		panic("sparse: matrix is not square")
+	}
+	var triplets []Triplet
+	a.DoNonZero(func(i, j int, v float64) {
+		triplets = append(triplets, Triplet{i, j, v})
+	})
+	return newSymCSR(r, uplo, triplets, a.props)
+}
+
+func newSymCSR(n int, uplo blas.Uplo, triplets []Triplet, props MatrixProperties) *SymCSR {
+	if uplo != blas.Upper && uplo != blas.Lower {
+		panic("sparse: bad triangle")
+	}
+
+	var k int
+	diag := true
+	for _, t := range triplets {
+		if uplo == blas.Upper && t.Row <= t.Col || uplo == blas.Lower && t.Row >= t.Col {
+			triplets[k] = t
+			k++
+			if t.Row != t.Col && t.Value != 0 {
+				diag = false
+			}
+		}
+	}
+
+	tri := newCSR(n, n, triplets[:k])
+	// The stored triangle is symmetric only if it is diagonal.
+	tri.props = MatrixProperties{
+		Symmetric:        diag,
+		Diagonal:         diag,
+		LowerTriangular:  diag || uplo == blas.Lower,
+		UpperTriangular:  diag || uplo == blas.Upper,
+		PositiveDiagonal: props.PositiveDiagonal,
+	}
+	return &SymCSR{
+		uplo: uplo,
+		tri:  tri,
+		props: MatrixProperties{
+			Symmetric:        true,
+			Diagonal:         diag,
+			LowerTriangular:  diag,
+			UpperTriangular:  diag,
+			PositiveDiagonal: props.PositiveDiagonal,
+		},
+	}
+}
+
+func (m *SymCSR) Dims() (r, c int) {
+	return m.tri.Dims()
+}
+
+func (m *SymCSR) MulVecTo(dst []float64, trans bool, x []float64) {
+	mulVecTo(dst, trans, m, x)
+}
+
+func (m *SymCSR) At(r, c int) float64 {
+	if m.uplo == blas.Upper && r > c || m.uplo == blas.Lower && r < c {
+		r, c = c, r
+	}
+	return m.tri.At(r, c)
+}
+
+// Uplo returns the triangle that is stored.
+func (m *SymCSR) Uplo() blas.Uplo {
+	return m.uplo
+}
+
+// Properties returns the properties of the matrix. The Symmetric property
+// is always set.
+func (m *SymCSR) Properties() MatrixProperties {
+	return m.props
+}
+
+// DoNonZero calls fn for each stored entry and for the mirror image of each
+// stored off-diagonal entry, that is, as if the full matrix were stored.
+func (m *SymCSR) DoNonZero(fn func(r, c int, v float64)) {
+	m.tri.DoNonZero(func(i, j int, v float64) {
+		fn(i, j, v)
+		if i != j {
+			fn(j, i, v)
+		}
+	})
+}
+
+// Triangle returns the stored triangle including the diagonal as a CSR
+// matrix. The returned matrix shares the underlying data with m and its
+// properties describe the triangle, not the symmetric matrix.
+func (m *SymCSR) Triangle() *CSR {
+	return m.tri
+}
+
+// ToCSR returns the symmetric matrix in full CSR storage.
+func (m *SymCSR) ToCSR() *CSR {
+	n, _ := m.Dims()
+	var triplets []Triplet
+	m.DoNonZero(func(i, j int, v float64) {
+		triplets = append(triplets, Triplet{i, j, v})
+	})
+	full := newCSR(n, n, triplets)
+	full.props = m.props
+	return full
+}
+
+func symCSRMulMatVec(y *mat64.Vector, alpha float64, a *SymCSR, x *mat64.Vector) {
+	n, _ := a.Dims()
+	if n != x.Len() || n != y.Len() {
+		panic("sparse: dimension mismatch")
+	}
+
+	if alpha == 0 {
+		return
+	}
+
+	xRaw := x.RawVector()
+	yRaw := y.RawVector()
+	tri := a.tri
+	for i := 0; i < n; i++ {
+		xi := xRaw.Data[i*xRaw.Inc]
+		var sum float64
+		for k := tri.rowIndex[i]; k < tri.rowIndex[i+1]; k++ {
+			j := tri.columns[k]
+			aij := tri.values[k]
+			sum += aij * xRaw.Data[j*xRaw.Inc]
+			if j != i {
+				yRaw.Data[j*yRaw.Inc] += alpha * aij * xi
+			}
+		}
+		yRaw.Data[i*yRaw.Inc] += alpha * sum
+	}
+}
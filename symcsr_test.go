@@ -0,0 +1,101 @@
+// Copyright 2015 Vladimír Chalupecký. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sparse
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gonum/blas"
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestSymCSR(t *testing.T) {
+	//  4 -1  0  2
+	// -1  4 -1  0
+	//  0 -1  4  0
+	//  2  0  0  0
+	dok := NewDOK(4, 4)
+	for _, e := range []Triplet{
+		{0, 0, 4}, {0, 1, -1}, {0, 3, 2},
+		{1, 0, -1}, {1, 1, 4}, {1, 2, -1},
+		{2, 1, -1}, {2, 2, 4},
+		{3, 0, 2},
+	} {
+		dok.InsertEntry(e.Row, e.Col, e.Value)
+	}
+	full := NewCSR(dok)
+	x := mat64.NewVector(4, []float64{1, -2, 3, -4})
+
+	for _, uplo := range []blas.Uplo{blas.Upper, blas.Lower} {
+		for _, sym := range []*SymCSR{NewSymCSR(dok, uplo), NewSymCSRFromCSR(full, uplo)} {
+			if sym.Uplo() != uplo {
+				t.Errorf("unexpected triangle")
+			}
+			if !sym.Properties().Symmetric {
+				t.Errorf("Symmetric property not set")
+			}
+			wantTri := MatrixProperties{
+				LowerTriangular: uplo == blas.Lower,
+				UpperTriangular: uplo == blas.Upper,
+			}
+			if got := sym.Triangle().Properties(); got != wantTri {
+				t.Errorf("unexpected properties of the triangle: want %+v, got %+v", wantTri, got)
+			}
+			if got := sym.ToCSR().Properties(); got != sym.Properties() {
+				t.Errorf("properties not carried to full storage: want %+v, got %+v", sym.Properties(), got)
+			}
+			if nnz := sym.Triangle().rowIndex[4]; nnz != 6 {
+				t.Errorf("unexpected number of stored entries: want 6, got %d", nnz)
+			}
+			for i := 0; i < 4; i++ {
+				for j := 0; j < 4; j++ {
+					if sym.At(i, j) != dok.At(i, j) {
+						t.Errorf("entries not equal at (%d,%d): want %v, got %v", i, j, dok.At(i, j), sym.At(i, j))
+					}
+				}
+			}
+
+			back := sym.ToCSR()
+			if !reflect.DeepEqual(back.values, full.values) ||
+				!reflect.DeepEqual(back.columns, full.columns) ||
+				!reflect.DeepEqual(back.rowIndex, full.rowIndex) {
+				t.Errorf("conversion to full storage failed")
+			}
+
+			for _, trans := range []bool{false, true} {
+				want := mat64.NewVector(4, []float64{1, 1, 1, 1})
+				got := mat64.NewVector(4, []float64{1, 1, 1, 1})
+				MulMatVec(want, -2, trans, full, x)
+				MulMatVec(got, -2, trans, sym, x)
+				if !reflect.DeepEqual(got.RawVector().Data, want.RawVector().Data) {
+					t.Errorf("unexpected result of MulMatVec: want %v, got %v", want.RawVector().Data, got.RawVector().Data)
+				}
+			}
+		}
+	}
+}
+
+func TestSymCSRDiagonalProperties(t *testing.T) {
+	dok := NewDOK(3, 3)
+	for i := 0; i < 3; i++ {
+		dok.InsertEntry(i, i, float64(i+1))
+	}
+	for _, uplo := range []blas.Uplo{blas.Upper, blas.Lower} {
+		sym := NewSymCSR(dok, uplo)
+		want := MatrixProperties{
+			Symmetric:       true,
+			LowerTriangular: true,
+			UpperTriangular: true,
+			Diagonal:        true,
+		}
+		if got := sym.Properties(); got != want {
+			t.Errorf("unexpected properties: want %+v, got %+v", want, got)
+		}
+		if got := sym.Triangle().Properties(); got != want {
+			t.Errorf("unexpected properties of the triangle: want %+v, got %+v", want, got)
+		}
+	}
+}
@@ -4,26 +4,37 @@
 
 package sparse
 
+// VectorOf is a sparse vector with element type T represented by a slice of
+// non-zero values and a slice denoting their indices.
+type VectorOf[T Scalar] struct {
+	N       int   // Dimension of the vector.
+	Data    []T   // Non-zero values.
+	Indices []int // Indices of values in Data. Must be zero-based and unique.
+}
+
 // Vector is a sparse vector represented by a slice of non-zero values and a
 // slice denoting their indices.
-type Vector struct {
-	N       int       // Dimension of the vector.
-	Data    []float64 // Non-zero values.
-	Indices []int     // Indices of values in Data. Must be zero-based and unique.
-}
+type Vector = VectorOf[float64]
 
 // NewVector returns a new Vector of dimension n with non-zero elements given
 // by data and indices. Both data and indices must have the same length smaller
 // than n, otherwise NewVector will panic. Indices must be unique, although no
 // checking is done.
 func NewVector(n int, data []float64, indices []int) *Vector {
+	return NewVectorOf(n, data, indices)
+}
+
+// NewVectorOf returns a new VectorOf[T] of dimension n with non-zero elements
+// given by data and indices. The requirements on the arguments are the same as
+// for NewVector.
+func NewVectorOf[T Scalar](n int, data []T, indices []int) *VectorOf[T] {
 	if len(data) != len(indices) {
 		panic("sparse: slice length mismatch")
 	}
 	if n < len(data) {
 		panic("sparse: vector dimension is less than the number of entries")
 	}
-	return &Vector{
+	return &VectorOf[T]{
 		N:       n,
 		Data:    data,
 		Indices: indices,
@@ -31,18 +42,18 @@ func NewVector(n int, data []float64, indices []int) *Vector {
 }
 
 // InsertEntry appends the value v with index i to the Vector.
-func (v *Vector) InsertEntry(val float64, i int) {
+func (v *VectorOf[T]) InsertEntry(val T, i int) {
 	v.Data = append(v.Data, val)
 	v.Indices = append(v.Indices, i)
 }
 
-func (v *Vector) reuseAs(n, nnz int) {
+func (v *VectorOf[T]) reuseAs(n, nnz int) {
 	v.N = n
 	if cap(v.Data) >= nnz {
 		v.Data = v.Data[:nnz]
 		v.Indices = v.Indices[:nnz]
 	} else {
-		v.Data = make([]float64, nnz)
+		v.Data = make([]T, nnz)
 		v.Indices = make([]int, nnz)
 	}
 }